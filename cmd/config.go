@@ -130,11 +130,14 @@ func init() {
 	configCmd.Flags().StringVar(&conf.Config.KeysDir, "keysDir", "", "Keys directory (default dataDir)")
 	configCmd.Flags().StringVar(&conf.Config.DataDir, "dataDir", "", "Data directory (default cwd/genesis-data)")
 	configCmd.Flags().StringVar(&conf.Config.TempDir, "tempDir", "", "Temporary directory (default temporary directory of OS)")
+	configCmd.Flags().StringVar(&conf.Config.ContractCacheDir, "contractCacheDir", "", "Directory of the compiled contracts (default dataDir/contracts-cache)")
 	configCmd.Flags().StringVar(&conf.Config.FirstBlockPath, "firstBlock", "", "First block path (default dataDir/1block)")
 	configCmd.Flags().BoolVar(&conf.Config.TLS, "tls", false, "Enable https")
 	configCmd.Flags().StringVar(&conf.Config.TLSCert, "tls-cert", "", "Filepath to the fullchain of certificates")
 	configCmd.Flags().StringVar(&conf.Config.TLSKey, "tls-key", "", "Filepath to the private key")
 	configCmd.Flags().Int64Var(&conf.Config.MaxPageGenerationTime, "mpgt", 1000, "Max page generation time in ms")
+	configCmd.Flags().BoolVar(&conf.Config.TestMode, "testMode", false, "The node is not used in production")
+	configCmd.Flags().BoolVar(&conf.Config.ProfileFailedTx, "profileFailedTx", false, "Keep the fuel profiles of the failed transactions")
 	configCmd.Flags().StringSliceVar(&conf.Config.NodesAddr, "nodesAddr", []string{}, "List of addresses for downloading blockchain")
 	configCmd.Flags().BoolVar(&conf.Config.PrivateBlockchain, "privateBlockchain", false, "Is blockchain private")
 
@@ -149,5 +152,8 @@ func init() {
 	viper.BindPFlag("MaxPageGenerationTime", configCmd.Flags().Lookup("mpgt"))
 	viper.BindPFlag("PrivateBlockchain", configCmd.Flags().Lookup("privateBlockchain"))
 	viper.BindPFlag("TempDir", configCmd.Flags().Lookup("tempDir"))
+	viper.BindPFlag("ContractCacheDir", configCmd.Flags().Lookup("contractCacheDir"))
 	viper.BindPFlag("NodesAddr", configCmd.Flags().Lookup("nodesAddr"))
+	viper.BindPFlag("ProfileFailedTx", configCmd.Flags().Lookup("profileFailedTx"))
+	viper.BindPFlag("TestMode", configCmd.Flags().Lookup("testMode"))
 }
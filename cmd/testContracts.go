@@ -0,0 +1,67 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/GenesisKernel/go-genesis/packages/smart/smarttest"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// testContractsCmd represents the testContracts command
+var testContractsCmd = &cobra.Command{
+	Use:   "testContracts [spec.json...]",
+	Short: "Run the contract tests without the database",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.SetLevel(log.FatalLevel)
+		var failed int
+		for _, filename := range args {
+			spec, err := smarttest.LoadSpec(filename)
+			if err != nil {
+				fmt.Printf("FAIL %s: %v\n", filename, err)
+				failed++
+				continue
+			}
+			h, err := spec.Harness()
+			if err != nil {
+				fmt.Printf("FAIL %s: %v\n", filename, err)
+				failed++
+				continue
+			}
+			for i, test := range spec.Tests {
+				name := test.Name
+				if len(name) == 0 {
+					name = fmt.Sprintf("%s #%d", test.Contract, i+1)
+				}
+				if err = test.Check(h); err != nil {
+					fmt.Printf("FAIL %s %s: %v\n", filename, name, err)
+					failed++
+				} else {
+					fmt.Printf("ok   %s %s\n", filename, name)
+				}
+			}
+		}
+		if failed > 0 {
+			fmt.Printf("%d failed\n", failed)
+			os.Exit(1)
+		}
+	},
+}
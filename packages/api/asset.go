@@ -0,0 +1,145 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type assetItem struct {
+	ID       int64           `json:"id"`
+	Owner    string          `json:"owner"`
+	Approved string          `json:"approved,omitempty"`
+	Creator  string          `json:"creator"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	BinaryID int64           `json:"binary_id,omitempty"`
+	BlockID  int64           `json:"block_id"`
+	Time     int64           `json:"time"`
+}
+
+type assetsResult struct {
+	List []assetItem `json:"list"`
+}
+
+type assetHistoryItem struct {
+	Sender    string `json:"sender,omitempty"`
+	Recipient string `json:"recipient"`
+	BlockID   int64  `json:"block_id"`
+	TxHash    string `json:"tx_hash"`
+	Time      int64  `json:"time"`
+}
+
+type assetResult struct {
+	assetItem
+	Link    string             `json:"link,omitempty"`
+	History []assetHistoryItem `json:"history"`
+}
+
+func getAssetItem(asset *model.Asset) assetItem {
+	item := assetItem{ID: asset.ID, Owner: converter.AddressToString(asset.KeyID),
+		Creator: converter.AddressToString(asset.CreatorID), BinaryID: asset.BinaryID,
+		BlockID: asset.BlockID, Time: asset.Time}
+	if asset.ApprovedID != 0 {
+		item.Approved = converter.AddressToString(asset.ApprovedID)
+	}
+	if len(asset.Data) > 0 {
+		item.Data = json.RawMessage(asset.Data)
+	}
+	return item
+}
+
+// getAssets returns the assets of the wallet in the ecosystem
+func getAssets(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	keyID := converter.StringToAddress(data.params[`wallet`].(string))
+	if keyID == 0 {
+		logger.WithFields(log.Fields{"type": consts.ConversionError, "value": data.params["wallet"].(string)}).Error("converting wallet to address")
+		return errorAPI(w, `E_INVALIDWALLET`, http.StatusBadRequest, data.params[`wallet`].(string))
+	}
+	limit := data.params[`limit`].(int64)
+	if limit <= 0 {
+		limit = 25
+	}
+	assets, err := model.GetAssets(ecosystemID, keyID, data.params[`offset`].(int64), limit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting assets")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := &assetsResult{List: make([]assetItem, 0, len(assets))}
+	for i := range assets {
+		result.List = append(result.List, getAssetItem(&assets[i]))
+	}
+	data.result = result
+	return nil
+}
+
+// getAsset returns the asset with the link to its binary and the history of the owners
+func getAsset(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, prefix, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	id := converter.StrToInt64(data.params[`id`].(string))
+	asset := &model.Asset{}
+	found, err := asset.Get(id)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting asset")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if !found || asset.Ecosystem != ecosystemID {
+		return errorAPI(w, `E_ASSET`, http.StatusNotFound, id)
+	}
+	result := &assetResult{assetItem: getAssetItem(asset)}
+	if asset.BinaryID != 0 {
+		binary := &model.Binary{}
+		binary.SetTablePrefix(prefix)
+		found, err = binary.GetByID(asset.BinaryID)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting binary of asset")
+			return errorAPI(w, err, http.StatusInternalServerError)
+		}
+		if found {
+			result.Link = binary.Link()
+		}
+	}
+	history, err := model.GetAssetHistory(id)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting asset history")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result.History = make([]assetHistoryItem, 0, len(history))
+	for _, item := range history {
+		change := assetHistoryItem{Recipient: converter.AddressToString(item.RecipientID),
+			BlockID: item.BlockID, TxHash: item.TxHash, Time: item.Time}
+		if item.SenderID != 0 {
+			change.Sender = converter.AddressToString(item.SenderID)
+		}
+		result.History = append(result.History, change)
+	}
+	data.result = result
+	return nil
+}
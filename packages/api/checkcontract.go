@@ -0,0 +1,48 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type checkContractResult struct {
+	Diagnostics script.Diagnostics `json:"diagnostics"`
+}
+
+// checkContract compiles the source code without loading it into the virtual machine
+// and returns the diagnostics of the type checker
+func checkContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	root, err := smart.VMCompileBlock(data.vm, data.params[`code`].(string),
+		&script.OwnerInfo{StateID: uint32(data.ecosystemId)})
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ParseError, "error": err}).Error("compiling contract")
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	result := checkContractResult{Diagnostics: data.vm.TypeCheck(root)}
+	if result.Diagnostics == nil {
+		result.Diagnostics = make(script.Diagnostics, 0)
+	}
+	data.result = result
+	return nil
+}
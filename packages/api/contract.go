@@ -291,20 +291,20 @@ func (c *contractHandlers) contractMulti(w http.ResponseWriter, r *http.Request,
 	return nil
 }
 
-func (c *contractHandlers) contract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+// serializeContract builds the signed transaction of the prepared request
+func (c *contractHandlers) serializeContract(w http.ResponseWriter, data *apiData, logger *log.Entry) (*script.ContractInfo, []byte, error) {
 	var (
-		hash, publicKey []byte
-		toSerialize     interface{}
-		requestID       = data.ParamString("request_id")
+		publicKey []byte
+		requestID = data.ParamString("request_id")
 	)
 
 	req, ok := c.requests.GetRequest(requestID)
 	if !ok {
-		return errorAPI(w, "E_REQUESTNOTFOUND", http.StatusNotFound, requestID)
+		return nil, nil, errorAPI(w, "E_REQUESTNOTFOUND", http.StatusNotFound, requestID)
 	}
 	contract := smart.VMGetContract(data.vm, req.Contract, uint32(data.ecosystemId))
 	if contract == nil {
-		return errorAPI(w, "E_CONTRACT", http.StatusBadRequest, req.Contract)
+		return nil, nil, errorAPI(w, "E_CONTRACT", http.StatusBadRequest, req.Contract)
 	}
 
 	info := (*contract).Block.Info.(*script.ContractInfo)
@@ -323,22 +323,33 @@ func (c *contractHandlers) contract(w http.ResponseWriter, r *http.Request, data
 	var err error
 	publicKey, err = getPublicKey(signID, data.ecosystemId, pubkey, w, logger)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	signature := data.params[`signature`].([]byte)
 	if len(signature) == 0 {
 		logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("signature is empty")
-		return errorAPI(w, `E_EMPTYSIGN`, http.StatusBadRequest)
+		return nil, nil, errorAPI(w, `E_EMPTYSIGN`, http.StatusBadRequest)
+	}
+	serializedData, err := buildContract(w, data, logger, req, info, signedBy, publicKey, signature)
+	if err != nil {
+		return nil, nil, err
 	}
+	return info, serializedData, nil
+}
+
+// buildContract serializes the transaction of the prepared request with the specified signature
+func buildContract(w http.ResponseWriter, data *apiData, logger *log.Entry, req *tx.Request,
+	info *script.ContractInfo, signedBy int64, publicKey, signature []byte) ([]byte, error) {
+	var err error
 	idata := make([]byte, 0)
 	if info.Tx != nil {
 		idata, err = getData(*info.Tx, req, w, logger)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	toSerialize = tx.SmartContract{
+	toSerialize := tx.SmartContract{
 		Header: tx.Header{
 			Type:          int(info.ID),
 			Time:          converter.StrToInt64(data.params[`time`].(string)),
@@ -356,10 +367,25 @@ func (c *contractHandlers) contract(w http.ResponseWriter, r *http.Request, data
 		SignedBy:       signedBy,
 		Data:           idata,
 	}
+	// the sponsor is specified only by the routes which send the transaction
+	if sponsor, ok := data.params[`sponsor`].(int64); ok && sponsor != 0 {
+		toSerialize.Sponsor = sponsor
+		toSerialize.SponsorSignature = data.params[`sponsor_signature`].([]byte)
+	}
 	serializedData, err := msgpack.Marshal(toSerialize)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("marshalling smart contract to msgpack")
-		return errorAPI(w, err, http.StatusInternalServerError)
+		return nil, errorAPI(w, err, http.StatusInternalServerError)
+	}
+	return serializedData, nil
+}
+
+func (c *contractHandlers) contract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	var hash []byte
+
+	info, serializedData, err := c.serializeContract(w, data, logger)
+	if err != nil {
+		return err
 	}
 	if data.vde {
 		ret, err := VDEContract(serializedData, data)
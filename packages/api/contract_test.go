@@ -1032,3 +1032,48 @@ func TestContractChain(t *testing.T) {
 		t.Error(fmt.Errorf(`wrong result %s`, msg))
 	}
 }
+
+func TestSimulateContract(t *testing.T) {
+	require.NoError(t, keyLogin(1))
+
+	rnd := randName(`sim`)
+	form := url.Values{"Value": {`contract ` + rnd + ` {
+		data {
+			Par string
+		}
+		conditions {
+			if $Par == "" {
+				error "empty par"
+			}
+		}
+		action {
+			DBUpdate("parameters", 1, "value", $Par)
+			$result = $Par
+		}}`}, "ApplicationId": {`1`}, "Conditions": {`true`}}
+	require.NoError(t, postTx(`NewContract`, &form))
+
+	var before paramValue
+	require.NoError(t, sendGet(`ecosystemparam/founder_account`, nil, &before))
+
+	var ret simulateResult
+	require.NoError(t, sendPost(`simulate/`+rnd, &url.Values{"Par": {`simulated`}}, &ret))
+	require.Nil(t, ret.Message)
+	assert.Equal(t, `simulated`, ret.Result)
+	assert.True(t, ret.Fuel > 0)
+	if assert.Len(t, ret.Changes, 1) {
+		assert.Equal(t, `1_parameters`, ret.Changes[0].Table)
+		assert.Equal(t, `1`, ret.Changes[0].ID)
+		assert.Equal(t, `simulated`, ret.Changes[0].New[`value`])
+	}
+
+	ret = simulateResult{}
+	require.NoError(t, sendPost(`simulate/`+rnd, &url.Values{"Par": {``}}, &ret))
+	if assert.NotNil(t, ret.Message) {
+		assert.Equal(t, `empty par`, ret.Message.Error)
+	}
+
+	// the changes are rolled back
+	var after paramValue
+	require.NoError(t, sendGet(`ecosystemparam/founder_account`, nil, &after))
+	assert.Equal(t, before.Value, after.Value)
+}
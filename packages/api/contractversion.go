@@ -0,0 +1,70 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type contractVersionItem struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"` // the pinned name of the version
+	Hash    string `json:"hash"`
+	BlockID int64  `json:"block_id"`
+	TxHash  string `json:"tx_hash,omitempty"`
+	Value   string `json:"value"`
+	Active  bool   `json:"active"`
+}
+
+type contractVersionsResult struct {
+	Name    string                `json:"name"`
+	Version int64                 `json:"version"` // the active version
+	List    []contractVersionItem `json:"list"`
+}
+
+// getContractVersions returns the versions of the contract
+func getContractVersions(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	cntname := data.params[`name`].(string)
+	contract := smart.VMGetContract(data.vm, cntname, uint32(data.ecosystemId))
+	if contract == nil {
+		logger.WithFields(log.Fields{"type": consts.ContractError, "contract_name": cntname}).Error("contract name")
+		return errorAPI(w, `E_CONTRACT`, http.StatusBadRequest, cntname)
+	}
+	info := contract.Block.Info.(*script.ContractInfo)
+	_, name := script.ParseContract(info.Name)
+	versions, err := model.GetContractVersions(int64(info.Owner.StateID), name)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting contract versions")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := &contractVersionsResult{Name: info.Name, Version: info.Owner.Version,
+		List: make([]contractVersionItem, 0, len(versions))}
+	for _, v := range versions {
+		result.List = append(result.List, contractVersionItem{Version: v.Version,
+			Name: smart.ContractVersionName(info.Name, v.Version), Hash: v.Hash, BlockID: v.BlockID,
+			TxHash: v.TxHash, Value: v.Value, Active: v.Version == info.Owner.Version})
+	}
+	data.result = result
+	return nil
+}
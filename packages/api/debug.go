@@ -0,0 +1,181 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/crypto"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/smart"
+	"github.com/GenesisKernel/go-genesis/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxPauses = 100
+	maxDebugPauses   = 1000
+)
+
+var debugSteps = map[string]script.DebugAction{
+	``:         script.DebugContinue,
+	`continue`: script.DebugContinue,
+	`into`:     script.DebugStepInto,
+	`over`:     script.DebugStepOver,
+	`out`:      script.DebugStepOut,
+}
+
+// dryRunResult is the result of the contract which has been run without writing to the blockchain
+type dryRunResult struct {
+	Hash    string         `json:"hash"`
+	Message *txstatusError `json:"errmsg,omitempty"`
+	Result  string         `json:"result,omitempty"`
+	Fuel    int64          `json:"fuel"`
+}
+
+type debugResult struct {
+	dryRunResult
+	Pauses []*script.DebugState `json:"pauses"`
+}
+
+type profileResult struct {
+	dryRunResult
+	Profile *script.Profile `json:"profile"`
+}
+
+// dryRun runs the serialized contract and rolls back all changes of the database. The functions which
+// change the memory of the node fail in the dry run. setup is called
+// before the execution to attach the debugger or the profiler to the contract. done is called
+// after the successful execution before the rollback if it is defined.
+func dryRun(w http.ResponseWriter, data *apiData, logger *log.Entry, serializedData []byte,
+	result *dryRunResult, setup func(*smart.SmartContract), done func(*smart.SmartContract) error) error {
+	hash, err := crypto.Hash(serializedData)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("getting hash of contract data")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result.Hash = hex.EncodeToString(hash)
+
+	dbTransaction, err := model.StartTransaction()
+	if err != nil {
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	defer dbTransaction.Rollback()
+
+	sc := smart.SmartContract{VDE: data.vde, TxHash: hash, DbTransaction: dbTransaction, DryRun: true}
+	setup(&sc)
+	if !data.vde {
+		block := &model.Block{}
+		if _, err := block.GetMaxBlock(); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting max block")
+			return errorAPI(w, err, http.StatusInternalServerError)
+		}
+		sc.BlockData = &utils.BlockData{BlockID: block.ID + 1, Time: time.Now().Unix(),
+			KeyID: block.KeyID, EcosystemID: block.EcosystemID}
+	}
+	if err = InitSmartContract(&sc, serializedData); err != nil {
+		result.Message = &txstatusError{Type: "panic", Error: err.Error()}
+		return nil
+	}
+	if ret, err := sc.CallContract(smart.CallInit | smart.CallCondition | smart.CallAction); err == nil {
+		result.Result = ret
+		if done != nil {
+			if err = done(&sc); err != nil {
+				return errorAPI(w, err, http.StatusInternalServerError)
+			}
+		}
+	} else if errResult := json.Unmarshal([]byte(err.Error()), &result.Message); errResult != nil {
+		result.Message = &txstatusError{Type: "panic", Error: err.Error()}
+	}
+	result.Fuel = sc.TxFuel
+	return nil
+}
+
+// debugContract runs the prepared contract in the debug mode. The execution is paused on
+// breakpoints and steps and the state of the virtual machine is returned for every pause.
+// All changes of the database are rolled back.
+func (c *contractHandlers) debugContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	var breakpoints []script.Breakpoint
+
+	if val := data.params[`breakpoints`].(string); len(val) > 0 {
+		if err := json.Unmarshal([]byte(val), &breakpoints); err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling breakpoints")
+			return errorAPI(w, err, http.StatusBadRequest)
+		}
+	}
+	step, ok := debugSteps[data.params[`step`].(string)]
+	if !ok {
+		return errorAPI(w, `E_DEBUGSTEP`, http.StatusBadRequest, data.params[`step`].(string))
+	}
+	maxPauses := int(data.params[`max_pauses`].(int64))
+	if maxPauses <= 0 {
+		maxPauses = defaultMaxPauses
+	} else if maxPauses > maxDebugPauses {
+		maxPauses = maxDebugPauses
+	}
+
+	_, serializedData, err := c.serializeContract(w, data, logger)
+	if err != nil {
+		return err
+	}
+	result := &debugResult{Pauses: make([]*script.DebugState, 0)}
+	// The execution is paused on the first command if the stepping mode is specified
+	start := step
+	if step != script.DebugContinue {
+		start = script.DebugStepInto
+	}
+	err = dryRun(w, data, logger, serializedData, &result.dryRunResult, func(sc *smart.SmartContract) {
+		sc.Debugger = script.NewDebugger(start, breakpoints, func(state *script.DebugState) script.DebugAction {
+			result.Pauses = append(result.Pauses, state)
+			if len(result.Pauses) >= maxPauses {
+				return script.DebugStop
+			}
+			return step
+		})
+	}, nil)
+	if err != nil {
+		return err
+	}
+	data.result = result
+	return nil
+}
+
+// profileContract runs the prepared contract and returns the fuel profile of the called functions.
+// All changes of the database are rolled back.
+func (c *contractHandlers) profileContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	_, serializedData, err := c.serializeContract(w, data, logger)
+	if err != nil {
+		return err
+	}
+	result := &profileResult{}
+	profiler := script.NewProfiler()
+	err = dryRun(w, data, logger, serializedData, &result.dryRunResult, func(sc *smart.SmartContract) {
+		sc.Profiler = profiler
+	}, nil)
+	if err != nil {
+		return err
+	}
+	result.Profile = profiler.Profile()
+	data.result = result
+	return nil
+}
@@ -18,8 +18,10 @@ package api
 
 var (
 	apiErrors = map[string]string{
+		`E_ASSET`:           `Asset %d has not been found`,
 		`E_CONTRACT`:        `There is not %s contract`,
 		`E_DBNIL`:           `DB is nil`,
+		`E_DEBUGSTEP`:       `Unknown debugger step %s`,
 		`E_DELETEDKEY`:      `The key is deleted`,
 		`E_ECOSYSTEM`:       `Ecosystem %d doesn't exist`,
 		`E_EMPTYPUBLIC`:     `Public key is undefined`,
@@ -28,7 +30,9 @@ var (
 		`E_HASHNOTFOUND`:    `Hash has not been found`,
 		`E_HEAVYPAGE`:       `This page is heavy`,
 		`E_INSTALLED`:       `Apla is already installed`,
+		`E_INVALIDRANGE`:    `Range %d-%d is not valid`,
 		`E_INVALIDWALLET`:   `Wallet %s is not valid`,
+		`E_MULTISIG`:        `Multi-signature call %d has not been found`,
 		`E_NOTFOUND`:        `Page not found`,
 		`E_NOTINSTALLED`:    `Apla is not installed`,
 		`E_PARAMNOTFOUND`:   `Parameter %s has not been found`,
@@ -41,8 +45,10 @@ var (
 		`E_UNKNOWNSIGN`:     `Unknown signature`,
 		`E_STATELOGIN`:      `%s is not a membership of ecosystem %s`,
 		`E_TABLENOTFOUND`:   `Table %s has not been found`,
+		`E_TESTMODE`:        `%s is allowed only in the test mode`,
 		`E_TOKEN`:           `Token is not valid`,
 		`E_TOKENEXPIRED`:    `Token is expired by %s`,
+		`E_TOKENNOTFOUND`:   `Token %s has not been found`,
 		`E_UNAUTHORIZED`:    `Unauthorized`,
 		`E_UNDEFINEVAL`:     `Value %s is undefined`,
 		`E_UNKNOWNUID`:      `Unknown uid`,
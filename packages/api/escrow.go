@@ -0,0 +1,75 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type escrowItem struct {
+	ID           int64  `json:"id"`
+	Sender       string `json:"sender"`
+	Recipient    string `json:"recipient"`
+	TokenID      int64  `json:"token_id,omitempty"`
+	Amount       string `json:"amount"`
+	Condition    string `json:"condition,omitempty"`
+	Deadline     int64  `json:"deadline"`
+	Status       string `json:"status"`
+	BlockID      int64  `json:"block_id"`
+	SettledBlock int64  `json:"settled_block,omitempty"`
+}
+
+type escrowsResult struct {
+	List []escrowItem `json:"list"`
+}
+
+// getEscrows returns the escrows of the ecosystem where the wallet is the sender or the recipient
+func getEscrows(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	keyID := converter.StringToAddress(data.params[`wallet`].(string))
+	if keyID == 0 {
+		logger.WithFields(log.Fields{"type": consts.ConversionError, "value": data.params["wallet"].(string)}).Error("converting wallet to address")
+		return errorAPI(w, `E_INVALIDWALLET`, http.StatusBadRequest, data.params[`wallet`].(string))
+	}
+	limit := data.params[`limit`].(int64)
+	if limit <= 0 {
+		limit = 25
+	}
+	escrows, err := model.GetEscrows(ecosystemID, keyID, data.params[`offset`].(int64), limit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting escrows")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := &escrowsResult{List: make([]escrowItem, 0, len(escrows))}
+	for _, e := range escrows {
+		result.List = append(result.List, escrowItem{ID: e.ID, Sender: converter.AddressToString(e.SenderID),
+			Recipient: converter.AddressToString(e.RecipientID), TokenID: e.TokenID, Amount: e.Amount,
+			Condition: e.Condition, Deadline: e.Deadline, Status: e.Status, BlockID: e.BlockID,
+			SettledBlock: e.SettledBlock})
+	}
+	data.result = result
+	return nil
+}
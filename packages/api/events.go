@@ -0,0 +1,100 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type eventItem struct {
+	ID        int64           `json:"id"`
+	Ecosystem int64           `json:"ecosystem"`
+	BlockID   int64           `json:"block_id"`
+	TxHash    string          `json:"tx_hash"`
+	Contract  string          `json:"contract"`
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+	Time      int64           `json:"time"`
+}
+
+type eventsResult struct {
+	List []eventItem `json:"list"`
+}
+
+// getEvents returns the events of the ecosystem. The events can be filtered by the contract,
+// the name, the hash of the transaction and the range of the blocks.
+func getEvents(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	limit := data.params[`limit`].(int64)
+	if limit <= 0 {
+		limit = 25
+	}
+	filter := &model.EventFilter{
+		Ecosystem: ecosystemID,
+		Contract:  data.params[`contract`].(string),
+		Name:      data.params[`name`].(string),
+		TxHash:    data.params[`hash`].(string),
+		FromBlock: data.params[`block_from`].(int64),
+		ToBlock:   data.params[`block_to`].(int64),
+		Offset:    data.params[`offset`].(int64),
+		Limit:     limit,
+	}
+	if filter.FromBlock > 0 && filter.ToBlock > 0 && filter.FromBlock > filter.ToBlock {
+		logger.WithFields(log.Fields{"type": consts.InvalidObject, "block_from": filter.FromBlock,
+			"block_to": filter.ToBlock}).Error("wrong range of blocks")
+		return errorAPI(w, `E_INVALIDRANGE`, http.StatusBadRequest, filter.FromBlock, filter.ToBlock)
+	}
+	return eventsList(w, data, logger, filter)
+}
+
+// getTxEvents returns the events which have been emitted by the transaction
+func getTxEvents(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	return eventsList(w, data, logger, &model.EventFilter{Ecosystem: ecosystemID,
+		TxHash: data.params[`hash`].(string), Limit: -1})
+}
+
+func eventsList(w http.ResponseWriter, data *apiData, logger *log.Entry, filter *model.EventFilter) error {
+	events, err := model.GetEvents(filter)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting events")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := &eventsResult{List: make([]eventItem, 0, len(events))}
+	for _, event := range events {
+		item := eventItem{ID: event.ID, Ecosystem: event.Ecosystem, BlockID: event.BlockID,
+			TxHash: event.TxHash, Contract: event.Contract, Name: event.Name, Time: event.Time}
+		if len(event.Data) > 0 {
+			item.Data = json.RawMessage(event.Data)
+		}
+		result.List = append(result.List, item)
+	}
+	data.result = result
+	return nil
+}
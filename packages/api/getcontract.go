@@ -44,6 +44,7 @@ type getContractResult struct {
 	Address  string          `json:"address"`
 	Fields   []contractField `json:"fields"`
 	Name     string          `json:"name"`
+	Version  int64           `json:"version"`
 }
 
 func getContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
@@ -61,7 +62,8 @@ func getContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *
 		Active: info.Owner.Active, TableID: converter.Int64ToStr(info.Owner.TableID),
 		WalletID: converter.Int64ToStr(info.Owner.WalletID),
 		TokenID:  converter.Int64ToStr(info.Owner.TokenID),
-		Address:  converter.AddressToString(info.Owner.WalletID)}
+		Address:  converter.AddressToString(info.Owner.WalletID),
+		Version:  info.Owner.Version}
 
 	if info.Tx != nil {
 		for _, fitem := range *info.Tx {
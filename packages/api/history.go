@@ -27,6 +27,9 @@ import (
 
 const rollbackHistoryLimit = 100
 
+// historyDeleted is the key which marks the deleted row in the history. It can't be the name of the column.
+const historyDeleted = `$deleted`
+
 type historyResult struct {
 	List []map[string]string `json:"list"`
 }
@@ -50,6 +53,9 @@ func getHistory(w http.ResponseWriter, r *http.Request, data *apiData, logger *l
 			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollbackTx.Data from JSON")
 			return errorAPI(w, err, http.StatusInternalServerError)
 		}
+		if tx.Deleted {
+			rollback[historyDeleted] = `true`
+		}
 		rollbackList = append(rollbackList, rollback)
 	}
 	data.result = &historyResult{rollbackList}
@@ -59,6 +59,10 @@ func list(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Ent
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting rows from table")
 		return errorAPI(w, err.Error(), http.StatusInternalServerError)
 	}
+	if err = encodeBytes(strings.Trim(table, `"`), list...); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting column types")
+		return errorAPI(w, err.Error(), http.StatusInternalServerError)
+	}
 	data.result = &listResult{
 		Count: converter.Int64ToStr(count), List: list,
 	}
@@ -0,0 +1,129 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type multisigItem struct {
+	ID         int64           `json:"id"`
+	Ecosystem  int64           `json:"ecosystem"`
+	Contract   string          `json:"contract"`
+	Params     json.RawMessage `json:"params"`
+	KeyID      string          `json:"key_id"`
+	TxHash     string          `json:"tx_hash"`
+	BlockID    int64           `json:"block_id"`
+	Threshold  int64           `json:"threshold"`
+	Approvals  []string        `json:"approvals"`
+	Time       int64           `json:"time"`
+	Expiration int64           `json:"expiration"`
+	Status     string          `json:"status"`
+	Result     string          `json:"result,omitempty"`
+}
+
+type multisigResult struct {
+	List []multisigItem `json:"list"`
+}
+
+func getMultisigItem(m *model.Multisig, now int64) (*multisigItem, error) {
+	approvals, err := m.ApprovedBy()
+	if err != nil {
+		return nil, err
+	}
+	item := &multisigItem{ID: m.ID, Ecosystem: m.Ecosystem, Contract: m.Contract, KeyID: converter.Int64ToStr(m.KeyID),
+		TxHash: m.TxHash, BlockID: m.BlockID, Threshold: m.Threshold, Approvals: make([]string, len(approvals)),
+		Time: m.Time, Expiration: m.Expiration, Status: m.State(now), Result: m.Result}
+	for i, key := range approvals {
+		item.Approvals[i] = converter.Int64ToStr(key)
+	}
+	if len(m.Params) > 0 {
+		item.Params = json.RawMessage(m.Params)
+	}
+	return item, nil
+}
+
+// getMultisigList returns the calls of the multi-signature contracts of the ecosystem. The calls can be
+// filtered by the contract, the initiator and the status: pending, expired or executed.
+func getMultisigList(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	limit := data.params[`limit`].(int64)
+	if limit <= 0 {
+		limit = 25
+	}
+	now := time.Now().Unix()
+	list, err := model.GetMultisigList(&model.MultisigFilter{
+		Ecosystem: ecosystemID,
+		Contract:  data.params[`contract`].(string),
+		Status:    data.params[`status`].(string),
+		KeyID:     data.params[`key_id`].(int64),
+		Time:      now,
+		Offset:    data.params[`offset`].(int64),
+		Limit:     limit,
+	})
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting multi-signature calls")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := &multisigResult{List: make([]multisigItem, 0, len(list))}
+	for i := range list {
+		item, err := getMultisigItem(&list[i], now)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling approvals")
+			return errorAPI(w, err, http.StatusInternalServerError)
+		}
+		result.List = append(result.List, *item)
+	}
+	data.result = result
+	return nil
+}
+
+// getMultisig returns the call of the multi-signature contract with the approvals
+func getMultisig(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	id := converter.StrToInt64(data.params[`id`].(string))
+	m := &model.Multisig{}
+	found, err := m.Get(id)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting multi-signature call")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if !found || m.Ecosystem != ecosystemID {
+		return errorAPI(w, `E_MULTISIG`, http.StatusNotFound, id)
+	}
+	item, err := getMultisigItem(m, time.Now().Unix())
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling approvals")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = item
+	return nil
+}
@@ -153,6 +153,9 @@ func (h *contractHandlers) prepareContract(w http.ResponseWriter, r *http.Reques
 	if data.params[`signed_by`] != nil {
 		smartTx.SignedBy = data.params[`signed_by`].(int64)
 	}
+	if sponsor, ok := data.params[`sponsor`].(int64); ok {
+		smartTx.Sponsor = sponsor
+	}
 
 	req := h.requests.NewRequest(contract.Name)
 
@@ -53,7 +53,14 @@ func Route(route *hr.Router) {
 	get(`appparam/:appid/:name`, `?ecosystem:int64`, authWallet, appParam)
 	get(`appparams/:appid`, `?ecosystem:int64,?names:string`, authWallet, appParams)
 	get(`balance/:wallet`, `?ecosystem:int64`, authWallet, balance)
+	get(`balance/:wallet/:token`, `?ecosystem:int64`, authWallet, tokenBalance)
+	get(`tokens`, `?ecosystem:int64`, authWallet, getTokens)
+	get(`assets/:wallet`, `?ecosystem ?limit ?offset:int64`, authWallet, getAssets)
+	get(`asset/:id`, `?ecosystem:int64`, authWallet, getAsset)
+	get(`escrows/:wallet`, `?ecosystem ?limit ?offset:int64`, authWallet, getEscrows)
+	get(`sponsor/:wallet`, `?ecosystem:int64`, authWallet, getSponsor)
 	get(`contract/:name`, ``, authWallet, getContract)
+	get(`contract/:name/versions`, ``, authWallet, getContractVersions)
 	get(`contracts`, `?limit ?offset:int64`, authWallet, getContracts)
 	get(`ecosystemparam/:name`, `?ecosystem:int64`, authWallet, ecosystemParam)
 	get(`ecosystemparams`, `?ecosystem:int64,?names:string`, authWallet, ecosystemParams)
@@ -70,6 +77,10 @@ func Route(route *hr.Router) {
 	get(`txstatus/:hash`, ``, authWallet, txstatus)
 	get(`test/:name`, ``, getTest)
 	get(`history/:table/:id`, ``, authWallet, getHistory)
+	get(`events`, `?ecosystem ?block_from ?block_to ?limit ?offset:int64,?contract ?name ?hash:string`, authWallet, getEvents)
+	get(`events/:hash`, `?ecosystem:int64`, authWallet, getTxEvents)
+	get(`multisig`, `?ecosystem ?key_id ?limit ?offset:int64,?contract ?status:string`, authWallet, getMultisigList)
+	get(`multisig/:id`, `?ecosystem:int64`, authWallet, getMultisig)
 	get(`block/:id`, ``, getBlockInfo)
 	get(`maxblockid`, ``, getMaxBlockID)
 	get(`version`, ``, getVersion)
@@ -80,12 +91,16 @@ func Route(route *hr.Router) {
 	post(`content/page/:name`, `?lang:string`, authWallet, getPage)
 	post(`content/menu/:name`, `?lang:string`, authWallet, getMenu)
 	post(`content/hash/:name`, ``, getPageHash)
+	post(`checkcontract`, `code:string`, authWallet, checkContract)
 	post(`vde/create`, ``, authWallet, vdeCreate)
 	post(`login`, `?pubkey signature:hex,?key_id ?mobile:string,?ecosystem ?expire ?role_id:int64`, login)
-	post(`prepare/:name`, `?token_ecosystem:int64,?max_sum ?payover:string`, authWallet, contractHandlers.prepareContract)
+	post(`prepare/:name`, `?token_ecosystem ?sponsor:int64,?max_sum ?payover:string`, authWallet, contractHandlers.prepareContract)
 	post(`prepareMultiple`, `data:string`, authWallet, contractHandlers.prepareMultipleContract)
 	post(`txstatusMultiple`, `data:string`, authWallet, txstatusMulti)
-	post(`contract/:request_id`, `?pubkey signature:hex, time:string, ?token_ecosystem:int64,?max_sum ?payover:string`, authWallet, blockchainUpdatingState, contractHandlers.contract)
+	post(`contract/:request_id`, `?pubkey signature ?sponsor_signature:hex, time:string, ?token_ecosystem ?sponsor:int64,?max_sum ?payover:string`, authWallet, blockchainUpdatingState, contractHandlers.contract)
+	post(`debug/:request_id`, `?pubkey signature:hex, time:string, ?token_ecosystem:int64,?max_sum ?payover ?breakpoints ?step:string,?max_pauses:int64`, authWallet, blockchainUpdatingState, contractHandlers.debugContract)
+	post(`profile/:request_id`, `?pubkey signature:hex, time:string, ?token_ecosystem:int64,?max_sum ?payover:string`, authWallet, blockchainUpdatingState, contractHandlers.profileContract)
+	post(`simulate/:name`, `?token_ecosystem ?key_id ?ecosystem:int64,?max_sum ?payover:string`, blockchainUpdatingState, contractHandlers.simulateContract)
 	post(`contractMultiple/:request_id`, `data:string`, authWallet, blockchainUpdatingState, contractHandlers.contractMulti)
 	post(`refresh`, `token:string,?expire:int64`, refresh)
 	post(`test/:name`, ``, getTest)
@@ -17,7 +17,9 @@
 package api
 
 import (
+	"encoding/hex"
 	"net/http"
+	"strings"
 
 	"github.com/GenesisKernel/go-genesis/packages/consts"
 	"github.com/GenesisKernel/go-genesis/packages/converter"
@@ -42,6 +44,31 @@ func row(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entr
 		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
 	}
 
+	if err = encodeBytes(strings.Trim(table, `"`), row); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting column types")
+		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
+	}
+
 	data.result = &rowResult{Value: row}
 	return
 }
+
+// encodeBytes replaces the values of bytea columns with their hex representation
+func encodeBytes(table string, rows ...map[string]string) error {
+	types, err := model.GetAllColumnTypes(table)
+	if err != nil {
+		return err
+	}
+	for _, col := range types {
+		if col["data_type"] != `bytea` {
+			continue
+		}
+		name := col["column_name"]
+		for _, row := range rows {
+			if val, ok := row[name]; ok && val != `NULL` {
+				row[name] = hex.EncodeToString([]byte(val))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/GenesisKernel/go-genesis/packages/conf"
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type simulateChange struct {
+	Table   string            `json:"table"`
+	ID      string            `json:"id"`
+	Old     map[string]string `json:"old,omitempty"` // the values before the execution, it is empty for the new rows
+	New     map[string]string `json:"new"`
+	Deleted bool              `json:"deleted,omitempty"` // the row has been deleted, Old contains its values
+}
+
+type simulateResult struct {
+	dryRunResult
+	Changes []simulateChange `json:"changes"`
+}
+
+// simulateContract runs the contract with the parameters of the form without the signature and
+// returns the result, the fuel and the rows which would be changed. All changes are rolled back.
+// The contract can be run on behalf of any key_id only if the node is in the test mode.
+func (c *contractHandlers) simulateContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	if keyID := data.params[`key_id`].(int64); keyID != 0 {
+		if !conf.Config.TestMode {
+			logger.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": keyID}).Error("simulating on behalf of key")
+			return errorAPI(w, `E_TESTMODE`, http.StatusForbidden, `key_id`)
+		}
+		data.keyId = keyID
+		if ecosystem := data.params[`ecosystem`].(int64); ecosystem > 0 {
+			data.ecosystemId = ecosystem
+		} else if data.ecosystemId == 0 {
+			data.ecosystemId = 1
+		}
+		if data.vde {
+			if data.vm = smart.GetVM(true, data.ecosystemId); data.vm == nil {
+				return errorAPI(w, `E_VDE`, http.StatusBadRequest, data.ecosystemId)
+			}
+		}
+	} else if err := authWallet(w, r, data, logger); err != nil {
+		return err
+	}
+
+	prepareData := *data
+	if err := c.prepareContract(w, r, &prepareData, logger); err != nil {
+		return err
+	}
+	prepared := prepareData.result.(prepareResult)
+	req, ok := c.requests.GetRequest(prepared.ID)
+	if !ok {
+		return errorAPI(w, `E_REQUESTNOTFOUND`, http.StatusNotFound, prepared.ID)
+	}
+	contract := smart.VMGetContract(data.vm, req.Contract, uint32(data.ecosystemId))
+	if contract == nil {
+		return errorAPI(w, `E_CONTRACT`, http.StatusBadRequest, req.Contract)
+	}
+	data.params[`time`] = prepared.Time
+	serializedData, err := buildContract(w, data, logger, req, contract.Block.Info.(*script.ContractInfo), 0, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	result := &simulateResult{Changes: make([]simulateChange, 0)}
+	err = dryRun(w, data, logger, serializedData, &result.dryRunResult, func(sc *smart.SmartContract) {
+		sc.SkipSign = true
+		sc.Rollback = true
+	}, func(sc *smart.SmartContract) error {
+		changes, err := getChanges(sc.DbTransaction, sc.TxHash, logger)
+		result.Changes = changes
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	data.result = result
+	return nil
+}
+
+// getChanges returns the rows which have been changed by the transaction. The previous values
+// are taken from the rollback records.
+func getChanges(dbTransaction *model.DbTransaction, hash []byte, logger *log.Entry) ([]simulateChange, error) {
+	rollbacks, err := (&model.RollbackTx{}).GetTxRollbackTransactions(dbTransaction, hash)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting rollback transactions")
+		return nil, err
+	}
+	changes := make([]simulateChange, 0, len(rollbacks))
+	rows := make(map[string]bool)
+	for _, item := range rollbacks {
+		if len(item.AlterType) > 0 {
+			// the schema changes are not the changes of the rows
+			continue
+		}
+		key := item.NameTable + `.` + item.TableID
+		if rows[key] {
+			continue
+		}
+		rows[key] = true
+		change := simulateChange{Table: item.NameTable, ID: item.TableID, Deleted: item.Deleted}
+		if len(item.Data) > 0 {
+			if err = json.Unmarshal([]byte(item.Data), &change.Old); err != nil {
+				logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollback data")
+				return nil, err
+			}
+		}
+		change.New, err = model.GetOneRowTransaction(dbTransaction, `SELECT * FROM "`+item.NameTable+
+			`" WHERE id = ?`, item.TableID).String()
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": item.NameTable}).Error("getting changed row")
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type sponsorResult struct {
+	Budget    string   `json:"budget"`
+	Spent     string   `json:"spent"`
+	Contracts []string `json:"contracts"` // the empty list allows no contract
+}
+
+// getSponsor returns the budget and the allow-list of the contracts of the sponsor in the ecosystem
+func getSponsor(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	keyID := converter.StringToAddress(data.params[`wallet`].(string))
+	if keyID == 0 {
+		logger.WithFields(log.Fields{"type": consts.ConversionError, "value": data.params["wallet"].(string)}).Error("converting wallet to address")
+		return errorAPI(w, `E_INVALIDWALLET`, http.StatusBadRequest, data.params[`wallet`].(string))
+	}
+	sponsor := &model.Sponsor{Budget: `0`, Spent: `0`}
+	if _, err = sponsor.Get(ecosystemID, keyID); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting sponsor")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := &sponsorResult{Budget: sponsor.Budget, Spent: sponsor.Spent, Contracts: []string{}}
+	if len(sponsor.Contracts) > 0 {
+		result.Contracts = strings.Split(sponsor.Contracts, `,`)
+	}
+	data.result = result
+	return nil
+}
@@ -39,6 +39,7 @@ type tableResult struct {
 	Insert     string       `json:"insert"`
 	NewColumn  string       `json:"new_column"`
 	Update     string       `json:"update"`
+	Delete     string       `json:"delete,omitempty"`
 	Read       string       `json:"read,omitempty"`
 	Filter     string       `json:"filter,omitempty"`
 	Conditions string       `json:"conditions"`
@@ -86,6 +87,7 @@ func table(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.En
 			Insert:     perm[`insert`],
 			NewColumn:  perm[`new_column`],
 			Update:     perm[`update`],
+			Delete:     perm[`delete`],
 			Read:       perm[`read`],
 			Filter:     perm[`filter`],
 			Conditions: table.Conditions,
@@ -0,0 +1,106 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+type tokenItem struct {
+	ID       int64  `json:"id"`
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Decimals int64  `json:"decimals"`
+	Supply   string `json:"supply"`
+	Owner    string `json:"owner"`
+}
+
+type tokensResult struct {
+	List []tokenItem `json:"list"`
+}
+
+type tokenBalanceResult struct {
+	balanceResult
+	Symbol string `json:"symbol"`
+}
+
+// tokenMoney returns the amount of the minimal units as the number with the decimals of the token
+func tokenMoney(amount string, decimals int64) string {
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return amount
+	}
+	return value.Shift(int32(-decimals)).String()
+}
+
+// getTokens returns the tokens of the ecosystem
+func getTokens(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	tokens, err := model.GetTokens(ecosystemID)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting tokens")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := &tokensResult{List: make([]tokenItem, 0, len(tokens))}
+	for _, token := range tokens {
+		result.List = append(result.List, tokenItem{ID: token.ID, Symbol: token.Symbol, Name: token.Name,
+			Decimals: token.Decimals, Supply: token.Supply, Owner: converter.AddressToString(token.KeyID)})
+	}
+	data.result = result
+	return nil
+}
+
+// tokenBalance returns the amount of the token of the wallet the same as balance for the ecosystem token
+func tokenBalance(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystemID, _, err := checkEcosystem(w, data, logger)
+	if err != nil {
+		return err
+	}
+	keyID := converter.StringToAddress(data.params[`wallet`].(string))
+	if keyID == 0 {
+		logger.WithFields(log.Fields{"type": consts.ConversionError, "value": data.params["wallet"].(string)}).Error("converting wallet to address")
+		return errorAPI(w, `E_INVALIDWALLET`, http.StatusBadRequest, data.params[`wallet`].(string))
+	}
+	symbol := data.params[`token`].(string)
+	token := &model.Token{}
+	found, err := token.GetBySymbol(ecosystemID, symbol)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting token")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if !found {
+		return errorAPI(w, `E_TOKENNOTFOUND`, http.StatusNotFound, symbol)
+	}
+	balance := &model.TokenBalance{Amount: `0`}
+	if _, err = balance.Get(token.ID, keyID); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting token balance")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = &tokenBalanceResult{balanceResult: balanceResult{Amount: balance.Amount,
+		Money: tokenMoney(balance.Amount, token.Decimals)}, Symbol: token.Symbol}
+	return nil
+}
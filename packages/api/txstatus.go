@@ -20,23 +20,31 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/GenesisKernel/go-genesis/packages/consts"
 	"github.com/GenesisKernel/go-genesis/packages/converter"
 	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/smart"
 
 	log "github.com/sirupsen/logrus"
 )
 
 type txstatusError struct {
-	Type  string `json:"type,omitempty"`
-	Error string `json:"error,omitempty"`
+	Type  string             `json:"type,omitempty"`
+	Error string             `json:"error,omitempty"`
+	Trace []script.TraceItem `json:"trace,omitempty"`
 }
 
 type txstatusResult struct {
-	BlockID string         `json:"blockid"`
-	Message *txstatusError `json:"errmsg,omitempty"`
-	Result  string         `json:"result"`
+	BlockID string          `json:"blockid"`
+	Message *txstatusError  `json:"errmsg,omitempty"`
+	Result  string          `json:"result"`
+	Profile *script.Profile `json:"profile,omitempty"` // the fuel profile of the failed transaction
+
+	Contract string `json:"contract,omitempty"` // the executed contract
+	Version  int64  `json:"version,omitempty"`  // the version of the executed contract
 }
 
 func getTxStatus(hash string, w http.ResponseWriter, logger *log.Entry) (*txstatusResult, error) {
@@ -58,6 +66,13 @@ func getTxStatus(hash string, w http.ResponseWriter, logger *log.Entry) (*txstat
 	if ts.BlockID > 0 {
 		status.BlockID = converter.Int64ToStr(ts.BlockID)
 		status.Result = ts.Error
+		txContract := &model.TxContract{}
+		if found, err = txContract.GetByHash(strings.ToLower(hash)); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting executed contract by hash")
+			return nil, errorAPI(w, err, http.StatusInternalServerError)
+		} else if found {
+			status.Contract, status.Version = txContract.Contract, txContract.Version
+		}
 	} else if len(ts.Error) > 0 {
 		if err := json.Unmarshal([]byte(ts.Error), &status.Message); err != nil {
 			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "text": ts.Error, "error": err}).Warn("unmarshalling txstatus error")
@@ -66,6 +81,7 @@ func getTxStatus(hash string, w http.ResponseWriter, logger *log.Entry) (*txstat
 				Error: ts.Error,
 			}
 		}
+		status.Profile = smart.FailedProfile(ts.Hash)
 	}
 	return &status, nil
 }
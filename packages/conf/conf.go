@@ -85,12 +85,15 @@ type GlobalConfig struct {
 	DataDir           string // application work dir (cwd by default)
 	KeysDir           string // place for private keys files: NodePrivateKey, PrivateKey
 	TempDir           string // temporary dir
+	ContractCacheDir  string // place for the compiled contracts
 	FirstBlockPath    string
 	TLS               bool   // TLS is on/off. It is required for https
 	TLSCert           string // TLSCert is a filepath of the fullchain of certificate.
 	TLSKey            string // TLSKey is a filepath of the private key.
 
 	MaxPageGenerationTime int64 // in milliseconds
+	ProfileFailedTx       bool  // keep the fuel profiles of the failed transactions for txstatus
+	TestMode              bool  // the node is not used in production, e.g. the contracts can be simulated on behalf of any key
 
 	TCPServer HostPort
 	HTTP      HostPort
@@ -179,6 +182,10 @@ func FillRuntimePaths() error {
 		Config.TempDir = filepath.Join(os.TempDir(), consts.DefaultTempDirName)
 	}
 
+	if Config.ContractCacheDir == "" {
+		Config.ContractCacheDir = filepath.Join(Config.DataDir, consts.DefaultContractCacheDirName)
+	}
+
 	if Config.FirstBlockPath == "" {
 		Config.FirstBlockPath = filepath.Join(Config.DataDir, consts.FirstBlockFilename)
 	}
@@ -76,6 +76,10 @@ const (
 	NodeBanTime = `node_ban_time`
 	// LocalNodeBanTime is value of local ban time for bad nodes (in ms)
 	LocalNodeBanTime = `local_node_ban_time`
+	// CheckContractTypes is the flag of refusing the contracts with type errors
+	CheckContractTypes = `check_contract_types`
+	// FuelSchedule is the list of the versions of the fuel schedule of the virtual machine
+	FuelSchedule = `fuel_schedule`
 )
 
 var (
@@ -100,10 +104,21 @@ func SysUpdate(dbTransaction *model.DbTransaction) error {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting all system parameters")
 		return err
 	}
+	params := make(map[string]string, len(systemParameters))
+	for _, param := range systemParameters {
+		params[param.Name] = param.Value
+	}
+	return SetParams(params)
+}
+
+// SetParams updates the values of the system parameters. It is used by SysUpdate and
+// allows to define the system parameters without the database, for example, in the tests.
+func SetParams(params map[string]string) error {
+	var err error
 	mutex.Lock()
 	defer mutex.Unlock()
-	for _, param := range systemParameters {
-		cache[param.Name] = param.Value
+	for name, value := range params {
+		cache[name] = value
 	}
 	if len(cache[FullNodes]) > 0 {
 		if err = updateNodes(); err != nil {
@@ -408,6 +423,11 @@ func GetLocalNodeBanTime() time.Duration {
 	return time.Millisecond * time.Duration(converter.StrToInt64(SysString(LocalNodeBanTime)))
 }
 
+// IsCheckContractTypes returns true if the contracts with type errors must be refused
+func IsCheckContractTypes() bool {
+	return SysInt64(CheckContractTypes) > 0
+}
+
 // GetRemoteHosts returns array of hostnames excluding myself
 func GetRemoteHosts() []string {
 	ret := make([]string, 0)
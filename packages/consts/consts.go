@@ -21,7 +21,7 @@ import (
 )
 
 // VERSION is current version
-const VERSION = "0.1.6b13"
+const VERSION = "0.1.6b30"
 
 // BLOCK_VERSION is block version
 const BLOCK_VERSION = 1
@@ -157,3 +157,6 @@ const TxRequestExpire = 1 * time.Minute
 
 // DefaultTempDirName is default name of temporary directory
 const DefaultTempDirName = "genesis-temp"
+
+// DefaultContractCacheDirName is default name of directory of the compiled contracts
+const DefaultContractCacheDirName = "contracts-cache"
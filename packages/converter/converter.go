@@ -574,6 +574,8 @@ func InterfaceToStr(v interface{}) (string, error) {
 		str = v.(string)
 	case []byte:
 		str = string(v.([]byte))
+	case bool:
+		str = strconv.FormatBool(v.(bool))
 	default:
 		if reflect.TypeOf(v).String() == `map[string]interface {}` {
 			if out, err := json.Marshal(v); err != nil {
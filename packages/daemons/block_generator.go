@@ -98,6 +98,7 @@ func BlockGenerator(ctx context.Context, d *daemon) error {
 		logger:     d.logger,
 	}
 	dtx.RunForBlockID(prevBlock.BlockID + 1)
+	dtx.SettleEscrows(time.Now().Unix())
 
 	trs, err := processTransactions(d.logger)
 	if err != nil {
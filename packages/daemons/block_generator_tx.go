@@ -3,6 +3,7 @@ package daemons
 import (
 	"encoding/hex"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/GenesisKernel/go-genesis/packages/consts"
@@ -19,9 +20,20 @@ import (
 
 const (
 	callDelayedContract = "CallDelayedContract"
+	settleEscrow        = "SettleEscrow"
 	firstEcosystemID    = 1
+
+	// maxEscrowSettlements is the maximum count of the escrows which are settled by one block
+	maxEscrowSettlements = 100
 )
 
+// settlingEscrows keeps the hashes of the settle transactions which have been created for the escrows.
+// The new transaction isn't created while the previous one is in the queue of the transactions.
+var settlingEscrows = struct {
+	sync.Mutex
+	hashes map[int64][]byte
+}{hashes: make(map[int64][]byte)}
+
 // DelayedTx represents struct which works with delayed contracts
 type DelayedTx struct {
 	logger     *log.Entry
@@ -38,19 +50,61 @@ func (dtx *DelayedTx) RunForBlockID(blockID int64) {
 	}
 
 	for _, c := range contracts {
-		if err := dtx.createTx(c.ID, c.KeyID); err != nil {
+		if _, err := dtx.createTx(callDelayedContract, c.ID, c.KeyID); err != nil {
 			dtx.logger.WithFields(log.Fields{"error": err}).Debug("can't create transaction for delayed contract")
 		}
 	}
 }
 
-func (dtx *DelayedTx) createTx(delayedContactID, keyID int64) error {
+// SettleEscrows creates the transactions that settle the escrows which are expired at the time.
+// The transactions are run on behalf of the senders of the escrows who pay the fuel.
+func (dtx *DelayedTx) SettleEscrows(time int64) {
+	escrows, err := model.GetExpiredEscrows(time, maxEscrowSettlements)
+	if err != nil {
+		dtx.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting expired escrows")
+		return
+	}
+
+	settlingEscrows.Lock()
+	defer settlingEscrows.Unlock()
+	expired := make(map[int64][]byte, len(escrows))
+	for _, e := range escrows {
+		if hash, ok := settlingEscrows.hashes[e.ID]; ok {
+			count, err := model.GetTransactionsCount(hash)
+			if err != nil {
+				dtx.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting settle transaction of escrow")
+				expired[e.ID] = hash
+				continue
+			}
+			if count > 0 {
+				expired[e.ID] = hash
+				continue
+			}
+		}
+		hash, err := dtx.createTx(settleEscrow, e.ID, e.SenderID)
+		if err != nil {
+			dtx.logger.WithFields(log.Fields{"error": err}).Debug("can't create transaction for escrow")
+			continue
+		}
+		expired[e.ID] = hash
+	}
+	// the settled escrows are not kept
+	settlingEscrows.hashes = expired
+}
+
+// createTx creates the transaction of the contract of the first ecosystem with the single Id parameter.
+// It returns the hash of the transaction.
+func (dtx *DelayedTx) createTx(name string, id, keyID int64) ([]byte, error) {
 	vm := smart.GetVM(false, 0)
-	contract := smart.VMGetContract(vm, callDelayedContract, uint32(firstEcosystemID))
+	contract := smart.VMGetContract(vm, name, uint32(firstEcosystemID))
+	if contract == nil {
+		dtx.logger.WithFields(log.Fields{"type": consts.NotFound, "contract_name": name}).Error("unknown contract")
+		return nil, fmt.Errorf(`unknown contract %s`, name)
+	}
 	info := contract.Block.Info.(*script.ContractInfo)
 
 	params := make([]byte, 0)
-	converter.EncodeLenInt64(&params, delayedContactID)
+	converter.EncodeLenInt64(&params, id)
 
 	smartTx := tx.SmartContract{
 		Header: tx.Header{
@@ -66,30 +120,30 @@ func (dtx *DelayedTx) createTx(delayedContactID, keyID int64) error {
 
 	signature, err := crypto.Sign(
 		dtx.privateKey,
-		fmt.Sprintf("%s,%d", smartTx.ForSign(), delayedContactID),
+		fmt.Sprintf("%s,%d", smartTx.ForSign(), id),
 	)
 	if err != nil {
 		dtx.logger.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("signing by node private key")
-		return err
+		return nil, err
 	}
 	smartTx.BinSignatures = converter.EncodeLengthPlusData(signature)
 
 	if smartTx.PublicKey, err = hex.DecodeString(dtx.publicKey); err != nil {
 		dtx.logger.WithFields(log.Fields{"type": consts.ConversionError, "error": err}).Error("decoding public key from hex")
-		return err
+		return nil, err
 	}
 
 	data, err := msgpack.Marshal(smartTx)
 	if err != nil {
 		dtx.logger.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("marshalling smart contract to msgpack")
-		return err
+		return nil, err
 	}
 	data = append([]byte{128}, data...)
 
 	hash, err := crypto.Hash(data)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("calculating hash of smart contract")
-		return err
+		return nil, err
 	}
 
 	tx := &model.Transaction{
@@ -101,8 +155,8 @@ func (dtx *DelayedTx) createTx(delayedContactID, keyID int64) error {
 	}
 	if err = tx.Create(); err != nil {
 		dtx.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating new transaction")
-		return err
+		return nil, err
 	}
 
-	return nil
+	return hash, nil
 }
@@ -147,4 +147,194 @@ var (
 		DROP TABLE IF EXISTS "stop_daemons"; CREATE TABLE "stop_daemons" (
 		"stop_time" int NOT NULL DEFAULT '0'
 		);`
+
+	migrationEvents = `DROP TABLE IF EXISTS "events"; CREATE TABLE "events" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"ecosystem" bigint NOT NULL DEFAULT '1',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"tx_hash" varchar(64) NOT NULL DEFAULT '',
+		"contract" varchar(255) NOT NULL DEFAULT '',
+		"name" varchar(255) NOT NULL DEFAULT '',
+		"data" jsonb,
+		"time" bigint NOT NULL DEFAULT '0'
+		);
+		ALTER TABLE ONLY "events" ADD CONSTRAINT events_pkey PRIMARY KEY (id);
+		CREATE INDEX "events_index_name" ON "events" (ecosystem, contract, name);
+		CREATE INDEX "events_index_block" ON "events" (block_id);`
+
+	migrationRollbackDeleted = `ALTER TABLE "rollback_tx" ADD COLUMN "deleted" boolean NOT NULL DEFAULT false;`
+
+	migrationRollbackAlter = `ALTER TABLE "rollback_tx" ADD COLUMN "alter_type" varchar(32) NOT NULL DEFAULT '';`
+
+	migrationMultisig = `DROP TABLE IF EXISTS "multisig"; CREATE TABLE "multisig" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"ecosystem" bigint NOT NULL DEFAULT '1',
+		"contract" varchar(255) NOT NULL DEFAULT '',
+		"params" jsonb,
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"tx_hash" varchar(64) NOT NULL DEFAULT '',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"threshold" bigint NOT NULL DEFAULT '0',
+		"approvals" jsonb,
+		"time" bigint NOT NULL DEFAULT '0',
+		"expiration" bigint NOT NULL DEFAULT '0',
+		"status" varchar(32) NOT NULL DEFAULT '',
+		"result" text NOT NULL DEFAULT ''
+		);
+		ALTER TABLE ONLY "multisig" ADD CONSTRAINT multisig_pkey PRIMARY KEY (id);
+		CREATE INDEX "multisig_index_status" ON "multisig" (ecosystem, status);
+		CREATE INDEX "multisig_index_hash" ON "multisig" (tx_hash);`
+
+	migrationTokens = `DROP TABLE IF EXISTS "tokens"; CREATE TABLE "tokens" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"ecosystem" bigint NOT NULL DEFAULT '1',
+		"symbol" varchar(32) NOT NULL DEFAULT '',
+		"name" varchar(255) NOT NULL DEFAULT '',
+		"decimals" bigint NOT NULL DEFAULT '0',
+		"supply" decimal(30) NOT NULL DEFAULT '0',
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"block_id" bigint NOT NULL DEFAULT '0'
+		);
+		ALTER TABLE ONLY "tokens" ADD CONSTRAINT tokens_pkey PRIMARY KEY (id);
+		CREATE UNIQUE INDEX "tokens_index_symbol" ON "tokens" (ecosystem, symbol);
+
+		DROP TABLE IF EXISTS "token_balances"; CREATE TABLE "token_balances" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"token_id" bigint NOT NULL DEFAULT '0',
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"amount" decimal(30) NOT NULL DEFAULT '0' CHECK (amount >= 0)
+		);
+		ALTER TABLE ONLY "token_balances" ADD CONSTRAINT token_balances_pkey PRIMARY KEY (id);
+		CREATE UNIQUE INDEX "token_balances_index_key" ON "token_balances" (token_id, key_id);
+
+		DROP TABLE IF EXISTS "token_allowances"; CREATE TABLE "token_allowances" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"token_id" bigint NOT NULL DEFAULT '0',
+		"owner_id" bigint NOT NULL DEFAULT '0',
+		"spender_id" bigint NOT NULL DEFAULT '0',
+		"amount" decimal(30) NOT NULL DEFAULT '0' CHECK (amount >= 0)
+		);
+		ALTER TABLE ONLY "token_allowances" ADD CONSTRAINT token_allowances_pkey PRIMARY KEY (id);
+		CREATE UNIQUE INDEX "token_allowances_index_key" ON "token_allowances" (token_id, owner_id, spender_id);
+
+		DO $$
+		DECLARE
+			tbl record;
+		BEGIN
+			FOR tbl IN SELECT table_name FROM information_schema.tables WHERE table_name ~ '^[0-9]+_history$' LOOP
+				EXECUTE format('ALTER TABLE %I ADD COLUMN "token_id" bigint NOT NULL DEFAULT ''0''', tbl.table_name);
+			END LOOP;
+		END $$;`
+
+	migrationAssets = `DROP TABLE IF EXISTS "assets"; CREATE TABLE "assets" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"ecosystem" bigint NOT NULL DEFAULT '1',
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"approved_id" bigint NOT NULL DEFAULT '0',
+		"creator_id" bigint NOT NULL DEFAULT '0',
+		"data" jsonb,
+		"binary_id" bigint NOT NULL DEFAULT '0',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"time" bigint NOT NULL DEFAULT '0'
+		);
+		ALTER TABLE ONLY "assets" ADD CONSTRAINT assets_pkey PRIMARY KEY (id);
+		CREATE INDEX "assets_index_owner" ON "assets" (ecosystem, key_id);
+
+		DROP TABLE IF EXISTS "asset_history"; CREATE TABLE "asset_history" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"asset_id" bigint NOT NULL DEFAULT '0',
+		"sender_id" bigint NOT NULL DEFAULT '0',
+		"recipient_id" bigint NOT NULL DEFAULT '0',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"tx_hash" varchar(64) NOT NULL DEFAULT '',
+		"time" bigint NOT NULL DEFAULT '0'
+		);
+		ALTER TABLE ONLY "asset_history" ADD CONSTRAINT asset_history_pkey PRIMARY KEY (id);
+		CREATE INDEX "asset_history_index_asset" ON "asset_history" (asset_id);`
+
+	migrationEscrows = `DROP TABLE IF EXISTS "escrows"; CREATE TABLE "escrows" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"ecosystem" bigint NOT NULL DEFAULT '1',
+		"sender_id" bigint NOT NULL DEFAULT '0',
+		"recipient_id" bigint NOT NULL DEFAULT '0',
+		"token_id" bigint NOT NULL DEFAULT '0',
+		"amount" decimal(30) NOT NULL DEFAULT '0' CHECK (amount >= 0),
+		"condition" text NOT NULL DEFAULT '',
+		"deadline" bigint NOT NULL DEFAULT '0',
+		"status" varchar(32) NOT NULL DEFAULT '',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"settled_block" bigint NOT NULL DEFAULT '0'
+		);
+		ALTER TABLE ONLY "escrows" ADD CONSTRAINT escrows_pkey PRIMARY KEY (id);
+		CREATE INDEX "escrows_index_deadline" ON "escrows" (status, deadline);
+		CREATE INDEX "escrows_index_sender" ON "escrows" (ecosystem, sender_id);
+		CREATE INDEX "escrows_index_recipient" ON "escrows" (ecosystem, recipient_id);`
+
+	migrationContractVersions = `DROP TABLE IF EXISTS "contract_versions"; CREATE TABLE "contract_versions" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"ecosystem" bigint NOT NULL DEFAULT '1',
+		"contract_id" bigint NOT NULL DEFAULT '0',
+		"name" varchar(255) NOT NULL DEFAULT '',
+		"version" bigint NOT NULL DEFAULT '0',
+		"value" text NOT NULL DEFAULT '',
+		"hash" varchar(64) NOT NULL DEFAULT '',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"tx_hash" varchar(64) NOT NULL DEFAULT ''
+		);
+		ALTER TABLE ONLY "contract_versions" ADD CONSTRAINT contract_versions_pkey PRIMARY KEY (id);
+		CREATE UNIQUE INDEX "contract_versions_index_name" ON "contract_versions" (ecosystem, name, version);
+
+		DROP TABLE IF EXISTS "tx_contracts"; CREATE TABLE "tx_contracts" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"tx_hash" varchar(64) NOT NULL DEFAULT '',
+		"ecosystem" bigint NOT NULL DEFAULT '1',
+		"contract" varchar(255) NOT NULL DEFAULT '',
+		"version" bigint NOT NULL DEFAULT '0',
+		"block_id" bigint NOT NULL DEFAULT '0'
+		);
+		ALTER TABLE ONLY "tx_contracts" ADD CONSTRAINT tx_contracts_pkey PRIMARY KEY (id);
+		CREATE INDEX "tx_contracts_index_hash" ON "tx_contracts" (tx_hash);
+
+		DO $$
+		DECLARE
+			tbl record;
+		BEGIN
+			FOR tbl IN SELECT table_name FROM information_schema.tables WHERE table_name ~ '^[0-9]+_contracts$' LOOP
+				EXECUTE format('ALTER TABLE %I ADD COLUMN "version" bigint NOT NULL DEFAULT ''0''', tbl.table_name);
+			END LOOP;
+		END $$;`
+
+	migrationSponsors = `DROP TABLE IF EXISTS "sponsors"; CREATE TABLE "sponsors" (
+		"id" bigint NOT NULL DEFAULT '0',
+		"ecosystem" bigint NOT NULL DEFAULT '1',
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"budget" decimal(30) NOT NULL DEFAULT '0' CHECK (budget >= 0),
+		"spent" decimal(30) NOT NULL DEFAULT '0',
+		"contracts" text NOT NULL DEFAULT ''
+		);
+		ALTER TABLE ONLY "sponsors" ADD CONSTRAINT sponsors_pkey PRIMARY KEY (id);
+		CREATE UNIQUE INDEX "sponsors_index_key" ON "sponsors" (ecosystem, key_id);`
+
+	migrationSystemParameters = `DO $$ BEGIN
+		IF to_regclass('"1_system_parameters"') IS NOT NULL THEN
+			INSERT INTO "1_system_parameters" ("id", "name", "value", "conditions")
+			SELECT (SELECT max(id) + 1 FROM "1_system_parameters"), 'check_contract_types', '0', 'true'
+			WHERE NOT EXISTS (SELECT id FROM "1_system_parameters" WHERE name = 'check_contract_types');
+			INSERT INTO "1_system_parameters" ("id", "name", "value", "conditions")
+			SELECT (SELECT max(id) + 1 FROM "1_system_parameters"), 'fuel_schedule', '[]', 'true'
+			WHERE NOT EXISTS (SELECT id FROM "1_system_parameters" WHERE name = 'fuel_schedule');
+		END IF;
+	END $$;`
+
+	// migrationDropSystemContracts removes the system contracts which have been inserted by the migrations
+	// outside of the blocks. They don't have the records of rollback_tx.
+	migrationDropSystemContracts = `DO $$ BEGIN
+		IF to_regclass('"1_contracts"') IS NOT NULL THEN
+			DELETE FROM "1_contracts" c WHERE c.name IN ('EditColumnName', 'DelColumn', 'EditColumnType',
+				'EditTableName', 'ApproveMultisig', 'NewToken', 'TransferToken', 'ApproveToken', 'TransferTokenFrom',
+				'NewAsset', 'TransferAsset', 'ApproveAsset', 'LockEscrow', 'ReleaseEscrow', 'CancelEscrow',
+				'SettleEscrow', 'SetSponsor') AND NOT EXISTS (SELECT id FROM "rollback_tx" r
+				WHERE r.table_name = '1_contracts' AND r.table_id = c.id::varchar);
+		END IF;
+	END $$;`
 )
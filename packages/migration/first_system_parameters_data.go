@@ -66,5 +66,7 @@ INSERT INTO "1_system_parameters" ("id","name", "value", "conditions") VALUES
 	('63','block_reward','1000','true'),
 	('64','incorrect_blocks_per_day','10','true'),
 	('65','node_ban_time','86400000','true'),
-	('66','local_node_ban_time','1800000','true');
+	('66','local_node_ban_time','1800000','true'),
+	('67','check_contract_types','0','true'),
+	('68','fuel_schedule','[]','true');
 `
@@ -13,6 +13,37 @@ var migrations = []*migration{
 
 	// Initial schema
 	&migration{"0.1.6b9", migrationInitialSchema},
+
+	// Events of contracts
+	&migration{"0.1.6b14", migrationEvents},
+
+	// Rollback of the deleted rows
+	&migration{"0.1.6b15", migrationRollbackDeleted},
+	&migration{"0.1.6b16", migrationRollbackAlter},
+
+	// Pending calls of multi-signature contracts
+	&migration{"0.1.6b17", migrationMultisig},
+
+	// Tokens of ecosystems
+	&migration{"0.1.6b18", migrationTokens},
+
+	// Registry of non-fungible assets
+	&migration{"0.1.6b19", migrationAssets},
+
+	// Escrows and time-locked transfers
+	&migration{"0.1.6b20", migrationEscrows},
+
+	// Versions of contracts
+	&migration{"0.1.6b21", migrationContractVersions},
+
+	// Sponsors of transactions
+	&migration{"0.1.6b22", migrationSponsors},
+
+	// System parameters of the type checking and the fuel schedule
+	&migration{"0.1.6b29", migrationSystemParameters},
+
+	// The system contracts are created by the transaction so the rows of the previous migrations are removed
+	&migration{"0.1.6b30", migrationDropSystemContracts},
 }
 
 type migration struct {
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The kinds of the schema changes in rollback_tx
+const (
+	AlterRenameColumn = "rename_column"
+	AlterDropColumn   = "drop_column"
+	AlterColumnType   = "column_type"
+	AlterRenameTable  = "rename_table"
+)
+
+// ColumnBackup keeps the definition and the values of the column to restore it
+type ColumnBackup struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	Default string            `json:"default,omitempty"`
+	NotNull bool              `json:"not_null,omitempty"`
+	Checks  map[string]string `json:"checks,omitempty"`
+	Indexes []string          `json:"indexes,omitempty"`
+	Values  map[string]string `json:"values,omitempty"` // the text values of not NULL columns by id
+}
+
+// BackupColumn returns the definition, the constraints, the indexes and the values of the column
+func BackupColumn(transaction *DbTransaction, tblname, column string) (*ColumnBackup, error) {
+	regclass := `"` + tblname + `"`
+	def, err := GetOneRowTransaction(transaction, `SELECT format_type(a.atttypid, a.atttypmod) AS type,
+		a.attnotnull AS not_null, coalesce(pg_get_expr(d.adbin, d.adrelid), '') AS def
+		FROM pg_attribute a LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = ?::regclass AND a.attname = ? AND NOT a.attisdropped`, regclass, column).String()
+	if err != nil {
+		return nil, err
+	}
+	if len(def) == 0 {
+		return nil, fmt.Errorf(`column %s has not been found`, column)
+	}
+	backup := &ColumnBackup{Name: column, Type: def[`type`], Default: def[`def`], NotNull: def[`not_null`] == `true`,
+		Checks: make(map[string]string), Values: make(map[string]string)}
+	checks, err := GetAllTransaction(transaction, `SELECT c.conname AS name, pg_get_constraintdef(c.oid) AS def
+		FROM pg_constraint c, pg_attribute a WHERE c.conrelid = ?::regclass AND c.contype = 'c'
+		AND a.attrelid = c.conrelid AND a.attname = ? AND a.attnum = ANY(c.conkey)`, -1, regclass, column)
+	if err != nil {
+		return nil, err
+	}
+	for _, check := range checks {
+		backup.Checks[check[`name`]] = check[`def`]
+	}
+	indexes, err := GetAllTransaction(transaction, `SELECT DISTINCT pg_get_indexdef(ix.indexrelid) AS def
+		FROM pg_index ix, pg_attribute a WHERE ix.indrelid = ?::regclass AND NOT ix.indisprimary
+		AND a.attrelid = ix.indrelid AND a.attname = ? AND a.attnum = ANY(ix.indkey)`, -1, regclass, column)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		backup.Indexes = append(backup.Indexes, index[`def`])
+	}
+	values, err := GetAllTransaction(transaction, `SELECT id, "`+column+`"::text AS value FROM "`+tblname+
+		`" WHERE "`+column+`" IS NOT NULL`, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		backup.Values[value[`id`]] = value[`value`]
+	}
+	return backup, nil
+}
+
+// RestoreColumn adds the column which has been saved by BackupColumn
+func RestoreColumn(transaction *DbTransaction, tblname string, backup *ColumnBackup) error {
+	db := GetDB(transaction)
+	alter := `ALTER TABLE "` + tblname + `" `
+	column := `"` + backup.Name + `"`
+	if err := db.Exec(alter + `ADD COLUMN ` + column + ` ` + backup.Type).Error; err != nil {
+		return err
+	}
+	if len(backup.Values) > 0 {
+		ids := make([]string, 0, len(backup.Values))
+		for id := range backup.Values {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		values := make([]string, len(ids))
+		for i, id := range ids {
+			values[i] = fmt.Sprintf(`('%s', '%s')`, strings.Replace(id, `'`, `''`, -1),
+				strings.Replace(backup.Values[id], `'`, `''`, -1))
+		}
+		if err := db.Exec(`UPDATE "` + tblname + `" SET ` + column + ` = v.value::` + backup.Type +
+			` FROM (VALUES ` + strings.Join(values, `, `) + `) AS v(id, value) WHERE "` + tblname +
+			`".id::text = v.id`).Error; err != nil {
+			return err
+		}
+	}
+	if len(backup.Default) > 0 {
+		if err := db.Exec(alter + `ALTER COLUMN ` + column + ` SET DEFAULT ` + backup.Default).Error; err != nil {
+			return err
+		}
+	}
+	if backup.NotNull {
+		if err := db.Exec(alter + `ALTER COLUMN ` + column + ` SET NOT NULL`).Error; err != nil {
+			return err
+		}
+	}
+	for name, check := range backup.Checks {
+		if err := db.Exec(alter + `ADD CONSTRAINT "` + name + `" ` + check).Error; err != nil {
+			return err
+		}
+	}
+	return CreateIndexes(transaction, backup.Indexes)
+}
+
+// CreateIndexes creates the indexes by their definitions
+func CreateIndexes(transaction *DbTransaction, indexes []string) error {
+	for _, index := range indexes {
+		if err := GetDB(transaction).Exec(index).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package model
+
+// The names of the tables of the non-fungible assets
+const (
+	AssetTableName        = "assets"
+	AssetHistoryTableName = "asset_history"
+)
+
+// Asset is the unique asset which is owned by the key. The asset is described by the metadata
+// and can refer to the file of the binaries table of the ecosystem.
+type Asset struct {
+	ID         int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem  int64  `gorm:"not null" json:"ecosystem"`
+	KeyID      int64  `gorm:"not null" json:"key_id"`
+	ApprovedID int64  `gorm:"not null" json:"approved_id"`
+	CreatorID  int64  `gorm:"not null" json:"creator_id"`
+	Data       string `gorm:"type:jsonb(PostgreSQL)" json:"data"`
+	BinaryID   int64  `gorm:"not null" json:"binary_id"`
+	BlockID    int64  `gorm:"not null" json:"block_id"`
+	Time       int64  `gorm:"not null" json:"time"`
+}
+
+// TableName returns name of table
+func (Asset) TableName() string {
+	return AssetTableName
+}
+
+// Get is retrieving model from database
+func (m *Asset) Get(id int64) (bool, error) {
+	return isFound(DBConn.Where("id = ?", id).First(m))
+}
+
+// GetAssets returns the assets of the owner in the ecosystem
+func GetAssets(ecosystem, keyID, offset, limit int64) ([]Asset, error) {
+	var assets []Asset
+	err := DBConn.Where("ecosystem = ? AND key_id = ?", ecosystem, keyID).Order("id asc").
+		Offset(offset).Limit(limit).Find(&assets).Error
+	return assets, err
+}
+
+// AssetHistory is the change of the owner of the asset. The sender is zero for the created asset.
+type AssetHistory struct {
+	ID          int64  `gorm:"primary_key;not null" json:"id"`
+	AssetID     int64  `gorm:"not null" json:"asset_id"`
+	SenderID    int64  `gorm:"not null" json:"sender_id"`
+	RecipientID int64  `gorm:"not null" json:"recipient_id"`
+	BlockID     int64  `gorm:"not null" json:"block_id"`
+	TxHash      string `gorm:"not null;size:64" json:"tx_hash"`
+	Time        int64  `gorm:"not null" json:"time"`
+}
+
+// TableName returns name of table
+func (AssetHistory) TableName() string {
+	return AssetHistoryTableName
+}
+
+// GetAssetHistory returns the owners of the asset in the order of the transfers
+func GetAssetHistory(assetID int64) ([]AssetHistory, error) {
+	var history []AssetHistory
+	err := DBConn.Where("asset_id = ?", assetID).Order("id asc").Find(&history).Error
+	return history, err
+}
@@ -0,0 +1,58 @@
+package model
+
+// The names of the tables of the contract versions
+const (
+	ContractVersionTableName = "contract_versions"
+	TxContractTableName      = "tx_contracts"
+)
+
+// ContractVersion is the immutable source of the contract. The version of the contract which
+// has been created before the versioning has the zero BlockID.
+type ContractVersion struct {
+	ID         int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem  int64  `gorm:"not null" json:"ecosystem"`
+	ContractID int64  `gorm:"not null" json:"contract_id"`
+	Name       string `gorm:"not null;size:255" json:"name"`
+	Version    int64  `gorm:"not null" json:"version"`
+	Value      string `gorm:"not null" json:"value"`
+	Hash       string `gorm:"not null;size:64" json:"hash"`
+	BlockID    int64  `gorm:"not null" json:"block_id"`
+	TxHash     string `gorm:"not null;size:64" json:"tx_hash"`
+}
+
+// TableName returns name of table
+func (ContractVersion) TableName() string {
+	return ContractVersionTableName
+}
+
+// Get is retrieving the version of the contract
+func (m *ContractVersion) Get(ecosystem int64, name string, version int64) (bool, error) {
+	return isFound(DBConn.Where("ecosystem = ? AND name = ? AND version = ?", ecosystem, name, version).First(m))
+}
+
+// GetContractVersions returns the versions of the contract
+func GetContractVersions(ecosystem int64, name string) ([]ContractVersion, error) {
+	var versions []ContractVersion
+	err := DBConn.Where("ecosystem = ? AND name = ?", ecosystem, name).Order("version asc").Find(&versions).Error
+	return versions, err
+}
+
+// TxContract is the version of the contract which has been executed by the transaction
+type TxContract struct {
+	ID        int64  `gorm:"primary_key;not null" json:"id"`
+	TxHash    string `gorm:"not null;size:64" json:"tx_hash"`
+	Ecosystem int64  `gorm:"not null" json:"ecosystem"`
+	Contract  string `gorm:"not null;size:255" json:"contract"`
+	Version   int64  `gorm:"not null" json:"version"`
+	BlockID   int64  `gorm:"not null" json:"block_id"`
+}
+
+// TableName returns name of table
+func (TxContract) TableName() string {
+	return TxContractTableName
+}
+
+// GetByHash is retrieving the executed contract of the transaction
+func (m *TxContract) GetByHash(hash string) (bool, error) {
+	return isFound(DBConn.Where("tx_hash = ?", hash).First(m))
+}
@@ -109,6 +109,21 @@ func (tr *DbTransaction) ReleaseSavepoint(idTx int) error {
 	return tr.Connection().Exec(fmt.Sprintf("RELEASE SAVEPOINT \"tx-%d\";", idTx)).Error
 }
 
+// TrySavepoint creates PostgreSQL savepoint of the try block of the contract
+func (tr *DbTransaction) TrySavepoint(id int) error {
+	return tr.Connection().Exec(fmt.Sprintf("SAVEPOINT \"try-%d\";", id)).Error
+}
+
+// RollbackTrySavepoint rollbacks PostgreSQL savepoint of the try block of the contract
+func (tr *DbTransaction) RollbackTrySavepoint(id int) error {
+	return tr.Connection().Exec(fmt.Sprintf("ROLLBACK TO SAVEPOINT \"try-%d\";", id)).Error
+}
+
+// ReleaseTrySavepoint releases PostgreSQL savepoint of the try block of the contract
+func (tr *DbTransaction) ReleaseTrySavepoint(id int) error {
+	return tr.Connection().Exec(fmt.Sprintf("RELEASE SAVEPOINT \"try-%d\";", id)).Error
+}
+
 // GetDB is returning gorm.DB
 func GetDB(tr *DbTransaction) *gorm.DB {
 	if tr != nil && tr.conn != nil {
@@ -168,11 +183,21 @@ func Update(transaction *DbTransaction, tblname, set, where string) error {
 	return GetDB(transaction).Exec(`UPDATE "` + strings.Trim(tblname, `"`) + `" SET ` + set + " " + where).Error
 }
 
+// Insert inserts the row with the SQL lists of the fields and the values
+func Insert(transaction *DbTransaction, tblname, fields, values string) error {
+	return GetDB(transaction).Exec(`INSERT INTO "` + strings.Trim(tblname, `"`) + `" (` + fields + `) VALUES (` + values + `)`).Error
+}
+
 // Delete is deleting table rows
 func Delete(tblname, where string) error {
 	return DBConn.Exec(`DELETE FROM "` + tblname + `" ` + where).Error
 }
 
+// DeleteTx deletes the rows of the table in the transaction
+func DeleteTx(transaction *DbTransaction, tblname, where string, args ...interface{}) error {
+	return GetDB(transaction).Exec(`DELETE FROM "`+tblname+`" `+where, args...).Error
+}
+
 // GetColumnCount is counting rows in table
 func GetColumnCount(tableName string) (int64, error) {
 	var count int64
@@ -219,8 +244,18 @@ func AlterTableAddColumn(transaction *DbTransaction, tableName, columnName, colu
 }
 
 // AlterTableDropColumn is dropping column from table
-func AlterTableDropColumn(tableName, columnName string) error {
-	return DBConn.Exec(`ALTER TABLE "` + tableName + `" DROP COLUMN "` + columnName + `"`).Error
+func AlterTableDropColumn(transaction *DbTransaction, tableName, columnName string) error {
+	return GetDB(transaction).Exec(`ALTER TABLE "` + tableName + `" DROP COLUMN "` + columnName + `"`).Error
+}
+
+// AlterTableRenameColumn is renaming column of table
+func AlterTableRenameColumn(transaction *DbTransaction, tableName, columnName, newName string) error {
+	return GetDB(transaction).Exec(`ALTER TABLE "` + tableName + `" RENAME COLUMN "` + columnName + `" TO "` + newName + `"`).Error
+}
+
+// AlterTableRename is renaming table
+func AlterTableRename(transaction *DbTransaction, tableName, newName string) error {
+	return GetDB(transaction).Exec(`ALTER TABLE "` + tableName + `" RENAME TO "` + newName + `"`).Error
 }
 
 // CreateIndex is creating index on table column
@@ -228,9 +263,37 @@ func CreateIndex(transaction *DbTransaction, indexName, tableName, onColumn stri
 	return GetDB(transaction).Exec(`CREATE INDEX "` + indexName + `_index" ON "` + tableName + `" (` + onColumn + `)`).Error
 }
 
+// CreateUniqueIndex is creating unique index on table columns
+func CreateUniqueIndex(transaction *DbTransaction, indexName, tableName, onColumns string) error {
+	return GetDB(transaction).Exec(`CREATE UNIQUE INDEX "` + indexName + `_unique" ON "` + tableName + `" (` + onColumns + `)`).Error
+}
+
+// DropIndexes drops the indexes of the table except the primary key. If the column is specified
+// then only the indexes containing the column are dropped.
+func DropIndexes(transaction *DbTransaction, tblname, column string) error {
+	query := `select distinct i.relname as index_name from pg_class t, pg_class i, pg_index ix, pg_attribute a
+	 where t.oid = ix.indrelid and i.oid = ix.indexrelid and a.attrelid = t.oid and a.attnum = ANY(ix.indkey)
+		 and t.relkind = 'r' and not ix.indisprimary and t.relname = ?`
+	params := []interface{}{tblname}
+	if len(column) > 0 {
+		query += ` and a.attname = ?`
+		params = append(params, column)
+	}
+	indexes, err := GetAllTransaction(transaction, query, -1, params...)
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err = GetDB(transaction).Exec(`DROP INDEX IF EXISTS "` + index[`index_name`] + `"`).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetColumnDataTypeCharMaxLength is returns max length of table column
 func GetColumnDataTypeCharMaxLength(tableName, columnName string) (map[string]string, error) {
-	return GetOneRow(`select data_type,character_maximum_length from
+	return GetOneRow(`select data_type,character_maximum_length,udt_name,numeric_precision,numeric_scale from
 			 information_schema.columns where table_name = ? AND column_name = ?`,
 		tableName, columnName).String()
 }
@@ -243,6 +306,20 @@ func GetAllColumnTypes(tblname string) ([]map[string]string, error) {
 		ORDER BY ordinal_position ASC`, -1, tblname)
 }
 
+// GetByteaColumns returns the names of the bytea columns of the table
+func GetByteaColumns(transaction *DbTransaction, tblname string) (map[string]bool, error) {
+	list, err := GetAllTransaction(transaction, `SELECT column_name FROM information_schema.columns
+		WHERE table_name = ? AND data_type = 'bytea'`, -1, tblname)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool, len(list))
+	for _, item := range list {
+		columns[item["column_name"]] = true
+	}
+	return columns, nil
+}
+
 // GetColumnType is returns type of column
 func GetColumnType(tblname, column string) (itype string, err error) {
 	coltype, err := GetColumnDataTypeCharMaxLength(tblname, column)
@@ -260,9 +337,27 @@ func GetColumnType(tblname, column string) (itype string, err error) {
 		case strings.HasPrefix(dataType, `timestamp`):
 			itype = "datetime"
 		case strings.HasPrefix(dataType, `numeric`):
-			itype = "money"
+			precision, scale := coltype["numeric_precision"], coltype["numeric_scale"]
+			if precision == `NULL` || (precision == `30` && scale == `0`) {
+				itype = "money"
+			} else {
+				itype = fmt.Sprintf(`decimal(%s,%s)`, precision, scale)
+			}
 		case strings.HasPrefix(dataType, `double`):
 			itype = "double"
+		case dataType == `boolean`:
+			itype = "bool"
+		case dataType == `bytea`:
+			itype = "bytes"
+		case dataType == `ARRAY`:
+			switch coltype["udt_name"] {
+			case `_int8`:
+				itype = "int_array"
+			case `_text`:
+				itype = "text_array"
+			default:
+				itype = dataType
+			}
 		default:
 			itype = dataType
 		}
@@ -0,0 +1,54 @@
+package model
+
+// EscrowTableName is the name of the table of the escrows
+const EscrowTableName = "escrows"
+
+// The statuses of the escrows
+const (
+	EscrowLocked   = "locked"
+	EscrowReleased = "released"
+	EscrowRefunded = "refunded"
+)
+
+// Escrow is the amount which has been locked by the sender until the deadline. The amount is released
+// to the recipient if the condition is true or refunded to the sender otherwise. TokenID is zero for
+// the amount of the keys table.
+type Escrow struct {
+	ID           int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem    int64  `gorm:"not null" json:"ecosystem"`
+	SenderID     int64  `gorm:"not null" json:"sender_id"`
+	RecipientID  int64  `gorm:"not null" json:"recipient_id"`
+	TokenID      int64  `gorm:"not null" json:"token_id"`
+	Amount       string `gorm:"not null" json:"amount"`
+	Condition    string `gorm:"not null" json:"condition"`
+	Deadline     int64  `gorm:"not null" json:"deadline"`
+	Status       string `gorm:"not null;size:32" json:"status"`
+	BlockID      int64  `gorm:"not null" json:"block_id"`
+	SettledBlock int64  `gorm:"not null" json:"settled_block"`
+}
+
+// TableName returns name of table
+func (Escrow) TableName() string {
+	return EscrowTableName
+}
+
+// Get is retrieving model from database
+func (m *Escrow) Get(id int64) (bool, error) {
+	return isFound(DBConn.Where("id = ?", id).First(m))
+}
+
+// GetExpiredEscrows returns the locked escrows whose deadline has been reached at the time
+func GetExpiredEscrows(time int64, limit int) ([]Escrow, error) {
+	var escrows []Escrow
+	err := DBConn.Where("status = ? AND deadline <= ?", EscrowLocked, time).Order("deadline asc, id asc").
+		Limit(limit).Find(&escrows).Error
+	return escrows, err
+}
+
+// GetEscrows returns the escrows of the ecosystem where the key is the sender or the recipient
+func GetEscrows(ecosystem, keyID, offset, limit int64) ([]Escrow, error) {
+	var escrows []Escrow
+	err := DBConn.Where("ecosystem = ? AND (sender_id = ? OR recipient_id = ?)", ecosystem, keyID, keyID).
+		Order("id desc").Offset(offset).Limit(limit).Find(&escrows).Error
+	return escrows, err
+}
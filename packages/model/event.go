@@ -0,0 +1,72 @@
+package model
+
+import (
+	"strings"
+)
+
+// EventTableName is the name of the table of the contract events
+const EventTableName = "events"
+
+// Event is the event which has been emitted by the contract
+type Event struct {
+	ID        int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem int64  `gorm:"not null" json:"ecosystem"`
+	BlockID   int64  `gorm:"not null" json:"block_id"`
+	TxHash    string `gorm:"not null;size:64" json:"tx_hash"`
+	Contract  string `gorm:"not null;size:255" json:"contract"`
+	Name      string `gorm:"not null;size:255" json:"name"`
+	Data      string `gorm:"type:jsonb(PostgreSQL)" json:"data"`
+	Time      int64  `gorm:"not null" json:"time"`
+}
+
+// TableName returns name of table
+func (Event) TableName() string {
+	return EventTableName
+}
+
+// EventFilter is the filter of the events. The empty fields are not used.
+type EventFilter struct {
+	Ecosystem int64
+	Contract  string
+	Name      string
+	TxHash    string
+	FromBlock int64
+	ToBlock   int64
+	Offset    int64
+	Limit     int64
+}
+
+func getEventsFilter(filter *EventFilter) (where string, params []interface{}) {
+	conds := []string{`ecosystem = ?`}
+	params = append(params, filter.Ecosystem)
+	if len(filter.Contract) > 0 {
+		conds = append(conds, `contract = ?`)
+		params = append(params, filter.Contract)
+	}
+	if len(filter.Name) > 0 {
+		conds = append(conds, `name = ?`)
+		params = append(params, filter.Name)
+	}
+	if len(filter.TxHash) > 0 {
+		conds = append(conds, `tx_hash = ?`)
+		params = append(params, strings.ToLower(filter.TxHash))
+	}
+	if filter.FromBlock > 0 {
+		conds = append(conds, `block_id >= ?`)
+		params = append(params, filter.FromBlock)
+	}
+	if filter.ToBlock > 0 {
+		conds = append(conds, `block_id <= ?`)
+		params = append(params, filter.ToBlock)
+	}
+	return strings.Join(conds, ` AND `), params
+}
+
+// GetEvents returns the events which match the filter in the order of the emission
+func GetEvents(filter *EventFilter) ([]Event, error) {
+	var events []Event
+	where, params := getEventsFilter(filter)
+	err := DBConn.Where(where, params...).Order("id asc").Offset(filter.Offset).
+		Limit(filter.Limit).Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEventsFilter(t *testing.T) {
+	testTable := []struct {
+		Filter EventFilter
+		Where  string
+		Params []interface{}
+	}{
+		{
+			Filter: EventFilter{Ecosystem: 1},
+			Where:  `ecosystem = ?`,
+			Params: []interface{}{int64(1)},
+		},
+		{
+			Filter: EventFilter{Ecosystem: 2, Contract: `@2Pay`, Name: `paid`, TxHash: `AB01`, FromBlock: 10, ToBlock: 20},
+			Where:  `ecosystem = ? AND contract = ? AND name = ? AND tx_hash = ? AND block_id >= ? AND block_id <= ?`,
+			Params: []interface{}{int64(2), `@2Pay`, `paid`, `ab01`, int64(10), int64(20)},
+		},
+		{
+			Filter: EventFilter{Ecosystem: 1, Name: `paid`, ToBlock: 5},
+			Where:  `ecosystem = ? AND name = ? AND block_id <= ?`,
+			Params: []interface{}{int64(1), `paid`, int64(5)},
+		},
+		{
+			Filter: EventFilter{Ecosystem: 1, TxHash: `Ff`, FromBlock: 3, Limit: 10, Offset: 20},
+			Where:  `ecosystem = ? AND tx_hash = ? AND block_id >= ?`,
+			Params: []interface{}{int64(1), `ff`, int64(3)},
+		},
+	}
+
+	for i, item := range testTable {
+		where, params := getEventsFilter(&item.Filter)
+		assert.Equal(t, item.Where, where, "on %d step wrong filter %s", i, where)
+		assert.Equal(t, item.Params, params, "on %d step wrong params", i)
+	}
+}
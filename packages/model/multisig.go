@@ -0,0 +1,109 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MultisigTableName is the name of the table of the pending calls of multi-signature contracts
+const MultisigTableName = "multisig"
+
+// The statuses of the multi-signature calls
+const (
+	MultisigPending  = "pending"
+	MultisigExecuted = "executed"
+	MultisigExpired  = "expired"
+)
+
+// Multisig is the call of the multi-signature contract which waits for the approvals
+type Multisig struct {
+	ID         int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem  int64  `gorm:"not null" json:"ecosystem"`
+	Contract   string `gorm:"not null;size:255" json:"contract"`
+	Params     string `gorm:"type:jsonb(PostgreSQL)" json:"params"`
+	KeyID      int64  `gorm:"not null" json:"key_id"`
+	TxHash     string `gorm:"not null;size:64" json:"tx_hash"`
+	BlockID    int64  `gorm:"not null" json:"block_id"`
+	Threshold  int64  `gorm:"not null" json:"threshold"`
+	Approvals  string `gorm:"type:jsonb(PostgreSQL)" json:"approvals"`
+	Time       int64  `gorm:"not null" json:"time"`
+	Expiration int64  `gorm:"not null" json:"expiration"`
+	Status     string `gorm:"not null;size:32" json:"status"`
+	Result     string `gorm:"not null" json:"result"`
+}
+
+// TableName returns name of table
+func (Multisig) TableName() string {
+	return MultisigTableName
+}
+
+// Get is retrieving model from database
+func (m *Multisig) Get(id int64) (bool, error) {
+	return isFound(DBConn.Where("id = ?", id).First(m))
+}
+
+// State returns the status of the call at the time. The pending call is expired if the time
+// is greater than its expiration.
+func (m *Multisig) State(time int64) string {
+	if m.Status == MultisigPending && time > m.Expiration {
+		return MultisigExpired
+	}
+	return m.Status
+}
+
+// ApprovedBy returns the keys which have approved the call
+func (m *Multisig) ApprovedBy() ([]int64, error) {
+	var keys []int64
+	if len(m.Approvals) > 0 {
+		if err := json.Unmarshal([]byte(m.Approvals), &keys); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// MultisigFilter is the filter of the multi-signature calls. The empty fields are not used.
+type MultisigFilter struct {
+	Ecosystem int64
+	Contract  string
+	Status    string
+	KeyID     int64
+	Time      int64 // the current time which defines the expired calls
+	Offset    int64
+	Limit     int64
+}
+
+func getMultisigFilter(filter *MultisigFilter) (where string, params []interface{}) {
+	conds := []string{`ecosystem = ?`}
+	params = append(params, filter.Ecosystem)
+	if len(filter.Contract) > 0 {
+		conds = append(conds, `contract = ?`)
+		params = append(params, filter.Contract)
+	}
+	if filter.KeyID != 0 {
+		conds = append(conds, `key_id = ?`)
+		params = append(params, filter.KeyID)
+	}
+	switch filter.Status {
+	case ``:
+	case MultisigPending:
+		conds = append(conds, `status = ? AND expiration >= ?`)
+		params = append(params, MultisigPending, filter.Time)
+	case MultisigExpired:
+		conds = append(conds, `status = ? AND expiration < ?`)
+		params = append(params, MultisigPending, filter.Time)
+	default:
+		conds = append(conds, `status = ?`)
+		params = append(params, filter.Status)
+	}
+	return strings.Join(conds, ` AND `), params
+}
+
+// GetMultisigList returns the multi-signature calls which match the filter, the last calls are the first
+func GetMultisigList(filter *MultisigFilter) ([]Multisig, error) {
+	var list []Multisig
+	where, params := getMultisigFilter(filter)
+	err := DBConn.Where(where, params...).Order("id desc").Offset(filter.Offset).
+		Limit(filter.Limit).Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMultisigFilter(t *testing.T) {
+	where, params := getMultisigFilter(&MultisigFilter{Ecosystem: 1, Status: MultisigExpired, Time: 100})
+	assert.Equal(t, `ecosystem = ? AND status = ? AND expiration < ?`, where)
+	assert.Equal(t, []interface{}{int64(1), MultisigPending, int64(100)}, params)
+
+	where, params = getMultisigFilter(&MultisigFilter{Ecosystem: 2, Contract: `@2Pay`, KeyID: 5,
+		Status: MultisigExecuted})
+	assert.Equal(t, `ecosystem = ? AND contract = ? AND key_id = ? AND status = ?`, where)
+	assert.Equal(t, []interface{}{int64(2), `@2Pay`, int64(5), MultisigExecuted}, params)
+
+	m := &Multisig{Status: MultisigPending, Expiration: 100, Approvals: `[1,2]`}
+	assert.Equal(t, MultisigPending, m.State(100))
+	assert.Equal(t, MultisigExpired, m.State(101))
+	keys, err := m.ApprovedBy()
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, keys)
+}
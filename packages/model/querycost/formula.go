@@ -19,6 +19,7 @@ const (
 	Set  = "set"
 	From = "from"
 	Into = "into"
+	Join = "join"
 
 	Quote  = `"`
 	Lparen = "("
@@ -29,11 +30,13 @@ const (
 	UpdateCost = 1
 	InsertCost = 1
 	DeleteCost = 1
+	JoinCost   = 1
 
 	SelectRowCoeff = 0.0001
 	InsertRowCoeff = 0.0001
 	DeleteRowCoeff = 0.0001
 	UpdateRowCoeff = 0.0001
+	JoinRowCoeff   = 0.0001
 )
 
 var FromStatementMissingError = errors.New("FROM statement missing")
@@ -67,8 +70,27 @@ func (d *DBCountQueryRowCounter) RowCount(transaction *model.DbTransaction, tabl
 	return count, err
 }
 
+// baseCosts are the default base costs of the query types
+var baseCosts = map[string]int64{Select: SelectCost, Insert: InsertCost, Update: UpdateCost,
+	Delete: DeleteCost, Join: JoinCost}
+
 type FormulaQueryCoster struct {
 	rowCounter TableRowCounter
+	costs      map[string]int64 // the base costs of the query types which replace the default ones
+}
+
+// NewFormulaQueryCoster returns the formula coster with the base costs of the query types
+// which replace the default costs of the omitted types
+func NewFormulaQueryCoster(costs map[string]int64) QueryCoster {
+	return &FormulaQueryCoster{rowCounter: &DBCountQueryRowCounter{}, costs: costs}
+}
+
+// baseCost returns the difference between the base cost of the query type and the default one
+func (f *FormulaQueryCoster) baseCost(name string) int64 {
+	if cost, ok := f.costs[name]; ok {
+		return cost - baseCosts[name]
+	}
+	return 0
 }
 
 type QueryType interface {
@@ -91,6 +113,23 @@ func (s SelectQueryType) CalculateCost(rowCount int64) int64 {
 	return SelectCost + int64(SelectRowCoeff*float64(rowCount))
 }
 
+// GetJoinTables returns the names of the joined tables
+func (s SelectQueryType) GetJoinTables() []string {
+	tables := make([]string, 0)
+	queryFields := strings.Fields(string(s))
+	for i, field := range queryFields {
+		if field == Join && i < len(queryFields)-1 {
+			tables = append(tables, strings.Trim(queryFields[i+1], Quote))
+		}
+	}
+	return tables
+}
+
+// JoinTableCost returns the cost of the joining of the table with rowCount rows
+func JoinTableCost(rowCount int64) int64 {
+	return JoinCost + int64(JoinRowCoeff*float64(rowCount))
+}
+
 type UpdateQueryType string
 
 func (s UpdateQueryType) GetTableName() (string, error) {
@@ -150,16 +189,19 @@ func (s DeleteQueryType) CalculateCost(rowCount int64) int64 {
 
 func (f *FormulaQueryCoster) QueryCost(transaction *model.DbTransaction, query string, args ...interface{}) (int64, error) {
 	cleanedQuery := strings.TrimSpace(strings.ToLower(query))
-	var queryType QueryType
+	var (
+		queryType QueryType
+		name      string
+	)
 	switch {
 	case strings.HasPrefix(cleanedQuery, Select):
-		queryType = SelectQueryType(cleanedQuery)
+		queryType, name = SelectQueryType(cleanedQuery), Select
 	case strings.HasPrefix(cleanedQuery, Insert):
-		queryType = InsertQueryType(cleanedQuery)
+		queryType, name = InsertQueryType(cleanedQuery), Insert
 	case strings.HasPrefix(cleanedQuery, Update):
-		queryType = UpdateQueryType(cleanedQuery)
+		queryType, name = UpdateQueryType(cleanedQuery), Update
 	case strings.HasPrefix(cleanedQuery, Delete):
-		queryType = DeleteQueryType(cleanedQuery)
+		queryType, name = DeleteQueryType(cleanedQuery), Delete
 	default:
 		log.WithFields(log.Fields{"type": consts.ParseError, "query": query}).Error("parsing sql query")
 		return 0, UnknownQueryTypeError
@@ -173,5 +215,14 @@ func (f *FormulaQueryCoster) QueryCost(transaction *model.DbTransaction, query s
 	if err != nil {
 		return 0, err
 	}
-	return queryType.CalculateCost(rowCount), nil
+	cost := queryType.CalculateCost(rowCount) + f.baseCost(name)
+	if selectQuery, ok := queryType.(SelectQueryType); ok {
+		for _, joinTable := range selectQuery.GetJoinTables() {
+			if rowCount, err = f.rowCounter.RowCount(transaction, joinTable); err != nil {
+				return 0, err
+			}
+			cost += JoinTableCost(rowCount) + f.baseCost(Join)
+		}
+	}
+	return cost, nil
 }
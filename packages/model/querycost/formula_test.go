@@ -27,7 +27,7 @@ type QueryCostByFormulaTestSuite struct {
 }
 
 func (s *QueryCostByFormulaTestSuite) SetupTest() {
-	s.queryCoster = &FormulaQueryCoster{&TestTableRowCounter{}}
+	s.queryCoster = &FormulaQueryCoster{rowCounter: &TestTableRowCounter{}}
 }
 
 func (s *QueryCostByFormulaTestSuite) TestQueryCostUnknownQueryType() {
@@ -108,6 +108,20 @@ func (s *QueryCostByFormulaTestSuite) TestQueryCostSelect() {
 	assert.Equal(s.T(), cost, SelectQueryType("").CalculateCost(tableRowCount))
 }
 
+func (s *QueryCostByFormulaTestSuite) TestGetJoinTablesFromSelect() {
+	assert.Equal(s.T(), []string{}, SelectQueryType("select a from keys").GetJoinTables())
+	assert.Equal(s.T(), []string{"1_keys"}, SelectQueryType(`select a from "1_deposits" as "deposits" `+
+		`inner join "1_keys" as "keys" on deposits.key_id = keys.id`).GetJoinTables())
+}
+
+func (s *QueryCostByFormulaTestSuite) TestQueryCostSelectJoin() {
+	cost, err := s.queryCoster.QueryCost(nil, `SELECT * FROM small AS a INNER JOIN small AS b ON a.id = b.id`)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), cost, SelectQueryType("").CalculateCost(tableRowCount)+JoinTableCost(tableRowCount))
+	_, err = s.queryCoster.QueryCost(nil, `SELECT * FROM small INNER JOIN unknown ON small.id = unknown.id`)
+	assert.Error(s.T(), err)
+}
+
 func (s *QueryCostByFormulaTestSuite) TestQueryCostUpdate() {
 	cost, err := s.queryCoster.QueryCost(nil, "UPDATE small SET a = ?", 3)
 	assert.Nil(s.T(), err)
@@ -131,6 +145,16 @@ func (s *QueryCostByFormulaTestSuite) TestQueryCostInsertWrongTable() {
 	assert.Error(s.T(), err)
 }
 
+func (s *QueryCostByFormulaTestSuite) TestQueryCostBaseCosts() {
+	coster := &FormulaQueryCoster{rowCounter: &TestTableRowCounter{}, costs: map[string]int64{Select: 10, Join: 5}}
+	cost, err := coster.QueryCost(nil, `SELECT * FROM small AS a INNER JOIN small AS b ON a.id = b.id`)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), cost, SelectQueryType("").CalculateCost(tableRowCount)+JoinTableCost(tableRowCount)+13)
+	cost, err = coster.QueryCost(nil, "DELETE FROM small")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), cost, DeleteQueryType("").CalculateCost(tableRowCount))
+}
+
 func TestQueryCostFormula(t *testing.T) {
 	suite.Run(t, new(QueryCostByFormulaTestSuite))
 }
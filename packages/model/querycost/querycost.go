@@ -37,7 +37,7 @@ func GetQueryCoster(tp QueryCosterType) QueryCoster {
 	case ExplainAnalyzeQueryCosterType:
 		return &ExplainAnalyzeQueryCoster{}
 	case FormulaQueryCosterType:
-		return &FormulaQueryCoster{&DBCountQueryRowCounter{}}
+		return &FormulaQueryCoster{rowCounter: &DBCountQueryRowCounter{}}
 	}
 	return nil
 }
@@ -8,6 +8,8 @@ type RollbackTx struct {
 	NameTable string `gorm:"not null;size:255;column:table_name" json:"table_name"`
 	TableID   string `gorm:"not null;size:255" json:"table_id"`
 	Data      string `gorm:"not null;type:jsonb(PostgreSQL)" json:"data"`
+	Deleted   bool   `gorm:"not null" json:"deleted,omitempty"`            // Data is the deleted row
+	AlterType string `gorm:"not null;size:32" json:"alter_type,omitempty"` // the kind of the schema change, Data has its parameters
 }
 
 // TableName returns name of table
@@ -27,6 +29,13 @@ func (rt *RollbackTx) GetBlockRollbackTransactions(dbTransaction *DbTransaction,
 	return rollbackTransactions, err
 }
 
+// GetTxRollbackTransactions returns records of rollback of the transaction in the order of the changes
+func (rt *RollbackTx) GetTxRollbackTransactions(dbTransaction *DbTransaction, transactionHash []byte) ([]RollbackTx, error) {
+	var rollbackTransactions []RollbackTx
+	err := GetDB(dbTransaction).Where("tx_hash = ?", transactionHash).Order("id asc").Find(&rollbackTransactions).Error
+	return rollbackTransactions, err
+}
+
 // GetRollbackTxsByTableIDAndTableName returns records of rollback by table name and id
 func (rt *RollbackTx) GetRollbackTxsByTableIDAndTableName(tableID, tableName string, limit int) (*[]RollbackTx, error) {
 	rollbackTx := new([]RollbackTx)
@@ -0,0 +1,26 @@
+package model
+
+// SponsorTableName is the name of the table of the sponsors
+const SponsorTableName = "sponsors"
+
+// Sponsor is the key which pays the fuel of the transactions of other keys. Ecosystem is the token
+// ecosystem of the paid fuel, Budget is the amount of its tokens which can still be spent, Contracts is the comma-separated list of
+// the sponsored contracts, the empty list allows no contract.
+type Sponsor struct {
+	ID        int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem int64  `gorm:"not null" json:"ecosystem"`
+	KeyID     int64  `gorm:"not null" json:"key_id"`
+	Budget    string `gorm:"not null" json:"budget"`
+	Spent     string `gorm:"not null" json:"spent"`
+	Contracts string `gorm:"not null" json:"contracts"`
+}
+
+// TableName returns name of table
+func (Sponsor) TableName() string {
+	return SponsorTableName
+}
+
+// Get is retrieving the sponsor of the token ecosystem
+func (m *Sponsor) Get(ecosystem, keyID int64) (bool, error) {
+	return isFound(DBConn.Where("ecosystem = ? AND key_id = ?", ecosystem, keyID).First(m))
+}
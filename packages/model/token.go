@@ -0,0 +1,56 @@
+package model
+
+// The names of the tables of the ecosystem tokens
+const (
+	TokenTableName          = "tokens"
+	TokenBalanceTableName   = "token_balances"
+	TokenAllowanceTableName = "token_allowances"
+)
+
+// Token is the fungible token of the ecosystem. The amounts of the token are kept in the minimal
+// units, Decimals is the count of the digits after the point.
+type Token struct {
+	ID        int64  `gorm:"primary_key;not null" json:"id"`
+	Ecosystem int64  `gorm:"not null" json:"ecosystem"`
+	Symbol    string `gorm:"not null;size:32" json:"symbol"`
+	Name      string `gorm:"not null;size:255" json:"name"`
+	Decimals  int64  `gorm:"not null" json:"decimals"`
+	Supply    string `gorm:"not null" json:"supply"`
+	KeyID     int64  `gorm:"not null" json:"key_id"`
+	BlockID   int64  `gorm:"not null" json:"block_id"`
+}
+
+// TableName returns name of table
+func (Token) TableName() string {
+	return TokenTableName
+}
+
+// GetBySymbol is retrieving the token of the ecosystem by the symbol
+func (m *Token) GetBySymbol(ecosystem int64, symbol string) (bool, error) {
+	return isFound(DBConn.Where("ecosystem = ? AND symbol = ?", ecosystem, symbol).First(m))
+}
+
+// GetTokens returns the tokens of the ecosystem
+func GetTokens(ecosystem int64) ([]Token, error) {
+	var tokens []Token
+	err := DBConn.Where("ecosystem = ?", ecosystem).Order("id asc").Find(&tokens).Error
+	return tokens, err
+}
+
+// TokenBalance is the amount of the token which belongs to the key
+type TokenBalance struct {
+	ID      int64  `gorm:"primary_key;not null"`
+	TokenID int64  `gorm:"not null"`
+	KeyID   int64  `gorm:"not null"`
+	Amount  string `gorm:"not null"`
+}
+
+// TableName returns name of table
+func (TokenBalance) TableName() string {
+	return TokenBalanceTableName
+}
+
+// Get is retrieving model from database
+func (m *TokenBalance) Get(tokenID, keyID int64) (bool, error) {
+	return isFound(DBConn.Where("token_id = ? AND key_id = ?", tokenID, keyID).First(m))
+}
@@ -28,10 +28,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/GenesisKernel/go-genesis/packages/conf"
 	"github.com/GenesisKernel/go-genesis/packages/consts"
 	"github.com/GenesisKernel/go-genesis/packages/converter"
 	"github.com/GenesisKernel/go-genesis/packages/crypto"
 	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
 	"github.com/GenesisKernel/go-genesis/packages/smart"
 	"github.com/GenesisKernel/go-genesis/packages/utils"
 	"github.com/GenesisKernel/go-genesis/packages/utils/tx"
@@ -517,7 +519,13 @@ func (p *Parser) CallContract(flags int) (resultContract string, err error) {
 		PublicKeys:    p.PublicKeys,
 		DbTransaction: p.DbTransaction,
 	}
+	if conf.Config.ProfileFailedTx {
+		sc.Profiler = script.NewProfiler()
+	}
 	resultContract, err = sc.CallContract(flags)
+	if err != nil && sc.Profiler != nil {
+		smart.SaveFailedProfile(p.TxHash, sc.Profiler.Profile())
+	}
 	p.SysUpdate = sc.SysUpdate
 	return
 }
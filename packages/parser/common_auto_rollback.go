@@ -18,6 +18,7 @@ package parser
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/GenesisKernel/go-genesis/packages/consts"
@@ -62,6 +63,71 @@ func (p *Parser) deleteInsertedDBRow(tx map[string]string, where string) error {
 	return nil
 }
 
+func (p *Parser) restoreDeletedDBRow(tx map[string]string) error {
+	logger := p.GetLogger()
+	var row map[string]string
+	if err := json.Unmarshal([]byte(tx["data"]), &row); err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollback.Data from json")
+		return p.ErrInfo(err)
+	}
+	fields := make([]string, 0, len(row))
+	values := make([]string, 0, len(row))
+	for k, v := range row {
+		fields = append(fields, `"`+k+`"`)
+		if v == "NULL" {
+			values = append(values, `NULL`)
+		} else if converter.IsByteColumn(tx["table_name"], k) && len(v) != 0 {
+			values = append(values, `decode('`+v+`','HEX')`)
+		} else {
+			values = append(values, `'`+strings.Replace(v, `'`, `''`, -1)+`'`)
+		}
+	}
+	if err := model.Insert(p.DbTransaction, tx["table_name"], strings.Join(fields, `,`), strings.Join(values, `,`)); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tx["table_name"]}).Error("restoring deleted row")
+		return p.ErrInfo(err)
+	}
+	return nil
+}
+
+// rollbackAlter reverts the schema change of the table
+func (p *Parser) rollbackAlter(tx map[string]string) error {
+	logger := p.GetLogger()
+	table := tx["table_name"]
+	var err error
+	switch tx["alter_type"] {
+	case model.AlterRenameColumn, model.AlterRenameTable:
+		var names map[string]string
+		if err = json.Unmarshal([]byte(tx["data"]), &names); err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollback.Data from json")
+			return p.ErrInfo(err)
+		}
+		if tx["alter_type"] == model.AlterRenameTable {
+			err = model.AlterTableRename(p.DbTransaction, table, names["name"])
+		} else {
+			err = model.AlterTableRenameColumn(p.DbTransaction, table, names["new_name"], names["name"])
+		}
+	case model.AlterDropColumn, model.AlterColumnType:
+		var backup model.ColumnBackup
+		if err = json.Unmarshal([]byte(tx["data"]), &backup); err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollback.Data from json")
+			return p.ErrInfo(err)
+		}
+		if tx["alter_type"] == model.AlterColumnType {
+			err = model.AlterTableDropColumn(p.DbTransaction, table, backup.Name)
+		}
+		if err == nil {
+			err = model.RestoreColumn(p.DbTransaction, table, &backup)
+		}
+	default:
+		err = fmt.Errorf(`unknown schema change %s`, tx["alter_type"])
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("rolling back schema change")
+		return p.ErrInfo(err)
+	}
+	return nil
+}
+
 func (p *Parser) autoRollback() error {
 	logger := p.GetLogger()
 	rollbackTx := &model.RollbackTx{}
@@ -72,7 +138,15 @@ func (p *Parser) autoRollback() error {
 	}
 	for _, tx := range txs {
 		where := " WHERE id='" + tx["table_id"] + `'`
-		if len(tx["data"]) > 0 {
+		if len(tx["alter_type"]) > 0 {
+			if err := p.rollbackAlter(tx); err != nil {
+				return err
+			}
+		} else if tx["deleted"] == "true" {
+			if err := p.restoreDeletedDBRow(tx); err != nil {
+				return err
+			}
+		} else if len(tx["data"]) > 0 {
 			if err := p.restoreUpdatedDBRowToPreviousData(tx, where); err != nil {
 				return err
 			}
@@ -0,0 +1,352 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	// DiagError is the level of the problem which certainly breaks the execution
+	DiagError = `error`
+	// DiagWarning is the level of the suspicious code
+	DiagWarning = `warning`
+
+	// DiagAssign is the kind of the mismatched assignment
+	DiagAssign = `assign`
+	// DiagParam is the kind of the wrong argument type of the function
+	DiagParam = `param`
+	// DiagUnreachable is the kind of the unreachable code
+	DiagUnreachable = `unreachable`
+)
+
+// Diagnostic is the problem in the source code which has been found by the type checker
+type Diagnostic struct {
+	Level   string `json:"level"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Line    uint32 `json:"line"`
+	Column  uint32 `json:"column"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf(`%s %s [Ln:%d Col:%d]`, d.Name, d.Message, d.Line, d.Column)
+}
+
+// Diagnostics is the list of the problems of the source code
+type Diagnostics []Diagnostic
+
+// Errors returns the diagnostics with the error level
+func (diags Diagnostics) Errors() (ret Diagnostics) {
+	for _, item := range diags {
+		if item.Level == DiagError {
+			ret = append(ret, item)
+		}
+	}
+	return
+}
+
+func (diags Diagnostics) Error() string {
+	list := make([]string, len(diags))
+	for i, item := range diags {
+		list[i] = item.String()
+	}
+	return strings.Join(list, `; `)
+}
+
+// typeItem is the item of the stack of types. count is the value of int constant,
+// it is used for the count of the variadic parameters. value is the value of the constant.
+type typeItem struct {
+	vtype reflect.Type
+	count int
+	value interface{}
+}
+
+type typeChecker struct {
+	vm     *VM
+	diags  Diagnostics
+	stack  []typeItem
+	unwrap bool
+}
+
+var (
+	typeBool   = reflect.TypeOf(true)
+	typeInt    = reflect.TypeOf(int64(0))
+	typeFloat  = reflect.TypeOf(float64(0))
+	typeString = reflect.TypeOf(``)
+)
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return `unknown`
+	}
+	for name, item := range types {
+		if item == t {
+			return name
+		}
+	}
+	return t.String()
+}
+
+// isAssignable returns true if the value of src type can be used as dst type.
+// Unknown types are always assignable.
+func isAssignable(dst, src reflect.Type, assign bool) bool {
+	if dst == nil || src == nil || dst == src || dst.Kind() == reflect.Interface ||
+		src.Kind() == reflect.Interface {
+		return true
+	}
+	if dst.Kind() == src.Kind() && (dst.Kind() == reflect.Map || dst.Kind() == reflect.Slice) {
+		return true
+	}
+	if assign {
+		switch dst.String() {
+		case Decimal:
+			return src == typeInt || src == typeFloat || src == typeString
+		case `float64`:
+			return src == typeInt
+		}
+	}
+	return src.AssignableTo(dst)
+}
+
+func (tc *typeChecker) push(vtype reflect.Type) {
+	tc.stack = append(tc.stack, typeItem{vtype: vtype, count: -1})
+}
+
+// pop returns the top item of the stack. It returns unknown type if the stack is empty.
+func (tc *typeChecker) pop() typeItem {
+	if len(tc.stack) == 0 {
+		return typeItem{count: -1}
+	}
+	ret := tc.stack[len(tc.stack)-1]
+	tc.stack = tc.stack[:len(tc.stack)-1]
+	return ret
+}
+
+// top returns the item with the specified offset from the top of the stack
+func (tc *typeChecker) top(off int) typeItem {
+	if off >= len(tc.stack) {
+		return typeItem{count: -1}
+	}
+	return tc.stack[len(tc.stack)-1-off]
+}
+
+func (tc *typeChecker) add(block *Block, offset int, level, kind, msg string) {
+	pos := block.Position(offset)
+	tc.diags = append(tc.diags, Diagnostic{Level: level, Kind: kind, Name: funcName(funcBlock(block)),
+		Line: pos.Line, Column: pos.Column, Message: msg})
+}
+
+// checkParams checks the types of the count parameters on the top of the stack and removes them
+func (tc *typeChecker) checkParams(block *Block, offset int, name string, params []reflect.Type, count int) {
+	for i := 0; i < count; i++ {
+		item := tc.top(count - 1 - i)
+		if i < len(params) && !isAssignable(params[i], item.vtype, false) {
+			tc.add(block, offset, DiagError, DiagParam, fmt.Sprintf(`parameter %d of %s must be %s instead of %s`,
+				i+1, name, typeName(params[i]), typeName(item.vtype)))
+		} else if val, ok := item.value.(string); ok && tc.vm.ParamCheck != nil {
+			if err := tc.vm.ParamCheck(name, i, val); err != nil {
+				tc.add(block, offset, DiagError, DiagParam, fmt.Sprintf(`parameter %d of %s: %s`, i+1, name, err))
+			}
+		}
+	}
+	for ; count > 0; count-- {
+		tc.pop()
+	}
+}
+
+func (tc *typeChecker) call(block *Block, offset int, cmd *ByteCode) {
+	var (
+		params, results []reflect.Type
+		name            string
+		count           int
+	)
+	obj := cmd.Value.(*ObjInfo)
+	switch obj.Type {
+	case ObjExtFunc:
+		finfo := obj.Value.(ExtFuncInfo)
+		name = finfo.Name
+		for i, par := range finfo.Params {
+			if len(finfo.Auto[i]) == 0 {
+				params = append(params, par)
+			}
+		}
+		for i, res := range finfo.Results {
+			if i == 0 && tc.vm.FuncCallsDB != nil {
+				if _, ok := tc.vm.FuncCallsDB[name]; ok {
+					continue
+				}
+			}
+			if res.String() != `error` {
+				results = append(results, res)
+			}
+		}
+	case ObjFunc:
+		finfo := obj.Value.(*Block).Info.(*FuncInfo)
+		if finfo.Names != nil {
+			// the functions with tail parameters are not checked
+			tc.stack = tc.stack[:0]
+			for _, res := range finfo.Results {
+				tc.push(res)
+			}
+			return
+		}
+		name = funcName(obj.Value.(*Block))
+		params = finfo.Params
+		results = finfo.Results
+	}
+	if cmd.Cmd == cmdCallVari {
+		count = tc.pop().count
+		if count < 0 || tc.unwrap {
+			tc.stack = tc.stack[:0]
+			count = 0
+		}
+		if len(params) > 0 {
+			params = params[:len(params)-1]
+		}
+	} else {
+		count = len(params)
+	}
+	tc.unwrap = false
+	tc.checkParams(block, offset, name, params, count)
+	for _, res := range results {
+		tc.push(res)
+	}
+}
+
+func (tc *typeChecker) assign(block *Block, offset int, vars []*VarInfo) {
+	for i, item := range vars {
+		src := tc.top(len(vars) - 1 - i).vtype
+		if item.Owner == nil {
+			continue
+		}
+		dst := item.Owner.Vars[item.Obj.Value.(int)]
+		if !isAssignable(dst, src, true) {
+			var name string
+			for key, obj := range item.Owner.Objects {
+				if obj == item.Obj {
+					name = key
+					break
+				}
+			}
+			tc.add(block, offset, DiagError, DiagAssign, fmt.Sprintf(`cannot assign %s to %s variable %s`,
+				typeName(src), typeName(dst), name))
+		}
+	}
+}
+
+func (tc *typeChecker) checkBlock(block *Block) {
+	var (
+		assign      []*VarInfo
+		unreachable bool
+	)
+
+	tc.stack = tc.stack[:0]
+	tc.unwrap = false
+	for i, cmd := range block.Code {
+		switch cmd.Cmd {
+		case cmdReturn, cmdError, cmdBreak, cmdContinue:
+			// the generated continue in the end of while block is skipped
+			if !unreachable && i+1 < len(block.Code) &&
+				!(i+2 == len(block.Code) && block.Code[i+1].Cmd == cmdContinue) {
+				tc.add(block, i+1, DiagWarning, DiagUnreachable, `unreachable code`)
+				unreachable = true
+			}
+		}
+		switch cmd.Cmd {
+		case cmdPush:
+			item := typeItem{vtype: reflect.TypeOf(cmd.Value), count: -1, value: cmd.Value}
+			if val, ok := cmd.Value.(int); ok {
+				item.count = val
+			}
+			tc.stack = append(tc.stack, item)
+		case cmdPushStr:
+			tc.push(typeString)
+		case cmdVar:
+			ivar := cmd.Value.(*VarInfo)
+			tc.push(ivar.Owner.Vars[ivar.Obj.Value.(int)])
+		case cmdExtend:
+			tc.push(nil)
+		case cmdCallExtend, cmdSetIndex, cmdFuncName:
+			// the count of the used parameters is unknown
+			tc.stack = tc.stack[:0]
+			tc.push(nil)
+		case cmdUnwrapArr:
+			tc.unwrap = true
+		case cmdCall, cmdCallVari:
+			tc.call(block, i, cmd)
+		case cmdAssignVar:
+			assign = cmd.Value.([]*VarInfo)
+		case cmdAssign:
+			tc.assign(block, i, assign)
+		case cmdIndex:
+			tc.pop()
+			tc.pop()
+			tc.push(nil)
+		case cmdWhile, cmdFor:
+			tc.pop()
+		case cmdNot:
+			tc.pop()
+			tc.push(typeBool)
+		case cmdSign:
+		default:
+			if cmd.Cmd>>8 == 2 {
+				right, left := tc.pop().vtype, tc.pop().vtype
+				switch cmd.Cmd {
+				case cmdAdd, cmdSub, cmdMul, cmdDiv:
+					if left == right {
+						tc.push(left)
+					} else {
+						tc.push(nil)
+					}
+				default:
+					tc.push(typeBool)
+				}
+			}
+		}
+		switch cmd.Cmd {
+		case cmdIf, cmdElse, cmdWhile, cmdFor, cmdTry, cmdCatch:
+			stack := tc.stack
+			tc.stack = nil
+			tc.checkBlock(cmd.Value.(*Block))
+			tc.stack = stack
+		}
+	}
+}
+
+func (tc *typeChecker) checkTree(block *Block) {
+	for _, item := range block.Children {
+		if item.Type == ObjFunc {
+			tc.checkBlock(item)
+		}
+		if item.Type == ObjFunc || item.Type == ObjContract {
+			tc.checkTree(item)
+		}
+	}
+}
+
+// TypeCheck checks the types of the compiled block before it is loaded into the virtual machine.
+// It returns the list of mismatched assignments, wrong parameters of the functions and unreachable code.
+// The values of the unknown types such as extend variables are not checked.
+func (vm *VM) TypeCheck(root *Block) Diagnostics {
+	tc := typeChecker{vm: vm}
+	tc.checkTree(root)
+	return tc.diags
+}
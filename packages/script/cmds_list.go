@@ -40,6 +40,9 @@ const (
 	cmdFuncName              // set func name Func(...).Name(...)
 	cmdUnwrapArr             // unwrap array to stack
 	cmdError                 // error command
+	cmdFor                   // for ... in
+	cmdTry                   // try
+	cmdCatch                 // catch
 )
 
 // the commands for operations in expressions are listed below
@@ -89,6 +89,17 @@ const (
 	stateConstsAssign
 	stateConstsValue
 	stateFields
+	stateFor
+	stateForIn
+	stateCatch
+	stateStruct
+	stateStructBlock
+	stateStructFields
+	stateLibrary
+	stateLibraryBlock
+	stateLibraryBody
+	stateImport
+	stateLibraryImport
 	stateEval
 
 	// The list of state flags
@@ -117,6 +128,7 @@ const (
 	errVarType               // must be type
 	errAssign                // must be '='
 	errStrNum                // must be number or string
+	errMustIn                // must be 'in'
 )
 
 const (
@@ -147,6 +159,16 @@ const (
 	cfContinue
 	cfBreak
 	cfCmdError
+	cfForVar
+	cfFor
+	cfTry
+	cfCatch
+	cfCatchVar
+	cfStructName
+	cfStructField
+	cfStructType
+	cfStructEnd
+	cfImport
 
 //	cfEval
 )
@@ -184,6 +206,16 @@ var (
 		fContinue,
 		fBreak,
 		fCmdError,
+		fForVar,
+		fFor,
+		fTry,
+		fCatch,
+		fCatchVar,
+		fStructName,
+		fStructField,
+		fStructType,
+		fStructEnd,
+		fImport,
 	}
 
 	// 'states' describes a finite machine with states on the base of which a bytecode will be generated
@@ -192,6 +224,8 @@ var (
 			lexNewLine:                      {stateRoot, 0},
 			lexKeyword | (keyContract << 8): {stateContract | statePush, 0},
 			lexKeyword | (keyFunc << 8):     {stateFunc | statePush, 0},
+			lexKeyword | (keyStruct << 8):   {stateStruct | statePush, 0},
+			lexKeyword | (keyLibrary << 8):  {stateLibrary | statePush, 0},
 			0: {errUnknownCmd, cfError},
 		},
 		{ // stateBody
@@ -202,13 +236,18 @@ var (
 			lexKeyword | (keyBreak << 8):    {stateBody, cfBreak},
 			lexKeyword | (keyIf << 8):       {stateEval | statePush | stateToBlock | stateMustEval, cfIf},
 			lexKeyword | (keyWhile << 8):    {stateEval | statePush | stateToBlock | stateLabel | stateMustEval, cfWhile},
+			lexKeyword | (keyFor << 8):      {stateFor | statePush, 0},
+			lexKeyword | (keyTry << 8):      {stateBlock | statePush, cfTry},
+			lexKeyword | (keyCatch << 8):    {stateCatch | statePush, cfCatch},
 			lexKeyword | (keyElse << 8):     {stateBlock | statePush, cfElse},
 			lexKeyword | (keyVar << 8):      {stateVar, 0},
 			lexKeyword | (keyTX << 8):       {stateTX, cfTX},
 			lexKeyword | (keySettings << 8): {stateSettings, cfSettings},
+			lexKeyword | (keyImport << 8):   {stateImport, 0},
 			lexKeyword | (keyError << 8):    {stateEval, cfCmdError},
 			lexKeyword | (keyWarning << 8):  {stateEval, cfCmdError},
 			lexKeyword | (keyInfo << 8):     {stateEval, cfCmdError},
+			lexKeyword | (keyThrow << 8):    {stateEval, cfCmdError},
 			lexIdent:                        {stateAssignEval | stateFork, 0},
 			lexExtend:                       {stateAssignEval | stateFork, 0},
 			isRCurly:                        {statePop, 0},
@@ -283,6 +322,7 @@ var (
 		{ // stateAssignEval
 			isLPar:   {stateEval | stateToFork | stateToBody, 0},
 			isLBrack: {stateEval | stateToFork | stateToBody, 0},
+			isDot:    {stateEval | stateToFork | stateToBody, 0},
 			0:        {stateAssign | stateToFork | stateStay, 0},
 		},
 		{ // stateAssign
@@ -327,6 +367,62 @@ var (
 			isRCurly:   {stateToBody, 0},
 			0:          {errMustRCurly, cfError},
 		},
+		{ // stateFor
+			lexIdent: {stateForIn, cfForVar},
+			0:        {errMustName, cfError},
+		},
+		{ // stateForIn
+			isComma:                   {stateFor, 0},
+			lexKeyword | (keyIn << 8): {stateEval | stateToBlock | stateMustEval, cfFor},
+			0:                         {errMustIn, cfError},
+		},
+		{ // stateCatch
+			lexIdent: {stateBlock, cfCatchVar},
+			0:        {stateBlock | stateStay, 0},
+		},
+		{ // stateStruct
+			lexNewLine: {stateStruct, 0},
+			lexIdent:   {stateStructBlock, cfStructName},
+			0:          {errMustName, cfError},
+		},
+		{ // stateStructBlock
+			lexNewLine: {stateStructBlock, 0},
+			isLCurly:   {stateStructFields, 0},
+			0:          {errMustLCurly, cfError},
+		},
+		{ // stateStructFields
+			lexNewLine: {stateStructFields, 0},
+			isComma:    {stateStructFields, 0},
+			lexIdent:   {stateStructFields, cfStructField},
+			lexType:    {stateStructFields, cfStructType},
+			isRCurly:   {statePop, cfStructEnd},
+			0:          {errMustRCurly, cfError},
+		},
+		{ // stateLibrary
+			lexNewLine: {stateLibrary, 0},
+			lexIdent:   {stateLibraryBlock, cfNameBlock},
+			0:          {errMustName, cfError},
+		},
+		{ // stateLibraryBlock
+			lexNewLine: {stateLibraryBlock, 0},
+			isLCurly:   {stateLibraryBody, 0},
+			0:          {errMustLCurly, cfError},
+		},
+		{ // stateLibraryBody
+			lexNewLine:                    {stateLibraryBody, 0},
+			lexKeyword | (keyFunc << 8):   {stateFunc | statePush, 0},
+			lexKeyword | (keyImport << 8): {stateLibraryImport, 0},
+			isRCurly:                      {statePop, 0},
+			0:                             {errUnknownCmd, cfError},
+		},
+		{ // stateImport
+			lexIdent: {stateBody, cfImport},
+			0:        {errMustName, cfError},
+		},
+		{ // stateLibraryImport
+			lexIdent: {stateLibraryBody, cfImport},
+			0:        {errMustName, cfError},
+		},
 	}
 )
 
@@ -341,6 +437,7 @@ func fError(buf *[]*Block, state int, lexem *Lexem) error {
 		`must be type`,             // errVarType
 		`must be '='`,              // errAssign
 		`must be number or string`, // errStrNum
+		`must be 'in'`,             // errMustIn
 	}
 	fmt.Printf("%s %x %v [Ln:%d Col:%d]\r\n", errors[state], lexem.Type, lexem.Value, lexem.Line, lexem.Column)
 	logger := lexem.GetLogger()
@@ -354,7 +451,8 @@ func fError(buf *[]*Block, state int, lexem *Lexem) error {
 
 func fFuncResult(buf *[]*Block, state int, lexem *Lexem) error {
 	fblock := (*buf)[len(*buf)-1].Info.(*FuncInfo)
-	(*fblock).Results = append((*fblock).Results, lexem.Value.(reflect.Type))
+	vtype, _ := lexemType(lexem)
+	(*fblock).Results = append((*fblock).Results, vtype)
 	return nil
 }
 
@@ -396,12 +494,13 @@ func fFparam(buf *[]*Block, state int, lexem *Lexem) error {
 
 func fFtype(buf *[]*Block, state int, lexem *Lexem) error {
 	block := (*buf)[len(*buf)-1]
+	vtype, sblock := lexemType(lexem)
 	if block.Type == ObjFunc && state == stateFParam {
 		fblock := block.Info.(*FuncInfo)
 		if fblock.Names == nil {
 			for pkey, param := range fblock.Params {
 				if param == reflect.TypeOf(nil) {
-					fblock.Params[pkey] = lexem.Value.(reflect.Type)
+					fblock.Params[pkey] = vtype
 				}
 			}
 		} else {
@@ -409,7 +508,7 @@ func fFtype(buf *[]*Block, state int, lexem *Lexem) error {
 				if key[0] == '_' {
 					for pkey, param := range (*fblock.Names)[key[1:]].Params {
 						if param == reflect.TypeOf(nil) {
-							(*fblock.Names)[key[1:]].Params[pkey] = lexem.Value.(reflect.Type)
+							(*fblock.Names)[key[1:]].Params[pkey] = vtype
 						}
 					}
 					break
@@ -419,7 +518,13 @@ func fFtype(buf *[]*Block, state int, lexem *Lexem) error {
 	}
 	for vkey, ivar := range block.Vars {
 		if ivar == reflect.TypeOf(nil) {
-			block.Vars[vkey] = lexem.Value.(reflect.Type)
+			block.Vars[vkey] = vtype
+			if sblock != nil {
+				if block.VarStructs == nil {
+					block.VarStructs = make(map[int]*Block)
+				}
+				block.VarStructs[vkey] = sblock
+			}
 		}
 	}
 	return nil
@@ -498,6 +603,67 @@ func fWhile(buf *[]*Block, state int, lexem *Lexem) error {
 	return nil
 }
 
+// fForVar declares the loop variable in the body of for ... in loop
+func fForVar(buf *[]*Block, state int, lexem *Lexem) error {
+	block := (*buf)[len(*buf)-1]
+	if block.Objects == nil {
+		block.Objects = make(map[string]*ObjInfo)
+		block.Info = &ParamsInfo{}
+	}
+	name := lexem.Value.(string)
+	if _, ok := block.Objects[name]; ok || block.Info.(*ParamsInfo).Count == 2 {
+		logger := lexem.GetLogger()
+		logger.WithFields(log.Fields{"type": consts.ParseError, "lex_value": name}).Error("wrong loop variables")
+		return fmt.Errorf(`wrong loop variable %s [Ln:%d Col:%d]`, name, lexem.Line, lexem.Column)
+	}
+	block.Objects[name] = &ObjInfo{Type: ObjVar, Value: len(block.Vars)}
+	block.Vars = append(block.Vars, reflect.TypeOf((*interface{})(nil)).Elem())
+	block.Info.(*ParamsInfo).Count++
+	return nil
+}
+
+// fFor moves the compiled expression of for ... in loop from the body to the parent block
+func fFor(buf *[]*Block, state int, lexem *Lexem) error {
+	block := (*buf)[len(*buf)-1]
+	parent := (*buf)[len(*buf)-2]
+	for _, cmd := range block.Code {
+		if cmd.Cmd == cmdVar && cmd.Value.(*VarInfo).Owner == block {
+			logger := lexem.GetLogger()
+			logger.WithFields(log.Fields{"type": consts.ParseError}).Error("loop variable in the range expression")
+			return fmt.Errorf(`loop variable is used in the range expression [Ln:%d Col:%d]`, lexem.Line, lexem.Column)
+		}
+	}
+	parent.Code = append(parent.Code, block.Code...)
+	parent.Code = append(parent.Code, &ByteCode{cmdFor, block})
+	block.Code = nil
+	return nil
+}
+
+func fTry(buf *[]*Block, state int, lexem *Lexem) error {
+	(*(*buf)[len(*buf)-2]).Code = append((*(*buf)[len(*buf)-2]).Code, &ByteCode{cmdTry, (*buf)[len(*buf)-1]})
+	return nil
+}
+
+func fCatch(buf *[]*Block, state int, lexem *Lexem) error {
+	code := (*(*buf)[len(*buf)-2]).Code
+	if len(code) == 0 || code[len(code)-1].Cmd != cmdTry {
+		logger := lexem.GetLogger()
+		logger.WithFields(log.Fields{"type": consts.ParseError}).Error("there is not try before")
+		return fmt.Errorf(`there is not try before %v [Ln:%d Col:%d]`, lexem.Type, lexem.Line, lexem.Column)
+	}
+	(*(*buf)[len(*buf)-2]).Code = append(code, &ByteCode{cmdCatch, (*buf)[len(*buf)-1]})
+	return nil
+}
+
+// fCatchVar declares the variable of the catch block which gets the caught error
+func fCatchVar(buf *[]*Block, state int, lexem *Lexem) error {
+	block := (*buf)[len(*buf)-1]
+	block.Objects = map[string]*ObjInfo{lexem.Value.(string): {Type: ObjVar, Value: 0}}
+	block.Vars = []reflect.Type{reflect.TypeOf(map[string]interface{}{})}
+	block.Info = &ParamsInfo{Count: 1}
+	return nil
+}
+
 func fContinue(buf *[]*Block, state int, lexem *Lexem) error {
 	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdContinue, 0})
 	return nil
@@ -614,6 +780,93 @@ func fFieldTag(buf *[]*Block, state int, lexem *Lexem) error {
 	return nil
 }
 
+func fStructName(buf *[]*Block, state int, lexem *Lexem) error {
+	prev := (*buf)[len(*buf)-2]
+	block := (*buf)[len(*buf)-1]
+	name := lexem.Value.(string)
+	block.Type = ObjStruct
+	block.Info = &StructInfo{Name: name}
+	prev.Objects[name] = &ObjInfo{Type: ObjStruct, Value: block}
+	return nil
+}
+
+func fStructField(buf *[]*Block, state int, lexem *Lexem) error {
+	info := (*buf)[len(*buf)-1].Info.(*StructInfo)
+	name := lexem.Value.(string)
+	if info.Field(name) != nil {
+		logger := lexem.GetLogger()
+		logger.WithFields(log.Fields{"type": consts.ParseError, "lex_value": name}).Error("duplicate field of struct")
+		return fmt.Errorf(`duplicate field %s of struct %s [Ln:%d Col:%d]`, name, info.Name, lexem.Line, lexem.Column)
+	}
+	info.Fields = append(info.Fields, &StructField{Name: name})
+	return nil
+}
+
+func fStructType(buf *[]*Block, state int, lexem *Lexem) error {
+	block := (*buf)[len(*buf)-1]
+	vtype, sblock := lexemType(lexem)
+	if sblock == block {
+		logger := lexem.GetLogger()
+		logger.WithFields(log.Fields{"type": consts.ParseError}).Error("recursive struct")
+		return fmt.Errorf(`struct %s cannot contain itself [Ln:%d Col:%d]`, block.Info.(*StructInfo).Name,
+			lexem.Line, lexem.Column)
+	}
+	for _, field := range block.Info.(*StructInfo).Fields {
+		if field.Type == nil {
+			field.Type, field.Struct = vtype, sblock
+		}
+	}
+	return nil
+}
+
+// fStructEnd checks that all fields of the struct have types
+func fStructEnd(buf *[]*Block, state int, lexem *Lexem) error {
+	parent := (*buf)[len(*buf)-1]
+	for _, field := range parent.Children[len(parent.Children)-1].Info.(*StructInfo).Fields {
+		if field.Type == nil {
+			return fError(buf, errVarType, lexem)
+		}
+	}
+	return nil
+}
+
+// fImport adds the library to the imports of the contract. The functions of the imported
+// libraries can be called without the name of the library.
+func fImport(buf *[]*Block, state int, lexem *Lexem) error {
+	logger := lexem.GetLogger()
+	block := (*buf)[len(*buf)-1]
+	if block.Type != ObjContract {
+		logger.WithFields(log.Fields{"type": consts.ParseError}).Error("import outside of contract")
+		return fmt.Errorf(`import must be in contract or library [Ln:%d Col:%d]`, lexem.Line, lexem.Column)
+	}
+	lib, ok := lexem.Value.(*Block)
+	if !ok || !lib.Info.(*ContractInfo).Library || lib == block {
+		logger.WithFields(log.Fields{"type": consts.ParseError, "lex_value": lexem.Value}).Error("unknown library")
+		return fmt.Errorf(`unknown library %v [Ln:%d Col:%d]`, lexem.Value, lexem.Line, lexem.Column)
+	}
+	info := block.Info.(*ContractInfo)
+	libName := lib.Info.(*ContractInfo).Name
+	for _, item := range info.imports {
+		if item == lib {
+			logger.WithFields(log.Fields{"type": consts.ParseError, "lex_value": libName}).Error("duplicate import")
+			return fmt.Errorf(`library %s has been already imported [Ln:%d Col:%d]`, libName, lexem.Line, lexem.Column)
+		}
+		for name, obj := range lib.Objects {
+			if prev, ok := item.Objects[name]; ok && obj.Type == ObjFunc && prev.Type == ObjFunc {
+				logger.WithFields(log.Fields{"type": consts.ParseError, "lex_value": name}).Error("function is imported twice")
+				return fmt.Errorf(`function %s is imported from %s and %s [Ln:%d Col:%d]`, name,
+					item.Info.(*ContractInfo).Name, libName, lexem.Line, lexem.Column)
+			}
+		}
+	}
+	info.imports = append(info.imports, lib)
+	if info.Used == nil {
+		info.Used = make(map[string]bool)
+	}
+	info.Used[libName] = true
+	return nil
+}
+
 func fElse(buf *[]*Block, state int, lexem *Lexem) error {
 	code := (*(*buf)[len(*buf)-2]).Code
 	if code[len(code)-1].Cmd != cmdIf {
@@ -645,11 +898,11 @@ func fNameBlock(buf *[]*Block, state int, lexem *Lexem) error {
 	fblock := (*buf)[len(*buf)-1]
 	name := lexem.Value.(string)
 	switch state {
-	case stateBlock:
+	case stateBlock, stateLibraryBlock:
 		itype = ObjContract
 		name = StateName((*buf)[0].Info.(uint32), name)
 		fblock.Info = &ContractInfo{ID: uint32(len(prev.Children) - 1), Name: name,
-			Owner: (*buf)[0].Owner}
+			Owner: (*buf)[0].Owner, Library: state == stateLibraryBlock}
 	default:
 		itype = ObjFunc
 		fblock.Info = &FuncInfo{}
@@ -681,6 +934,10 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 			ok       bool
 		)
 		lexem := lexems[i]
+		if lexem.Type == lexIdent {
+			lexem = vm.structLexem(lexems, i, curState, &blockstack)
+			lexem = vm.libraryLexem(lexem, curState, &blockstack)
+		}
 		if newState, ok = states[curState][int(lexem.Type)]; !ok {
 			newState = states[curState][0]
 		}
@@ -699,6 +956,7 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 			i--
 			continue
 		}
+		start := lexem
 		if nextState == stateEval {
 			if newState.NewState&stateLabel > 0 {
 				(*blockstack[len(blockstack)-1]).Code = append((*blockstack[len(blockstack)-1]).Code, &ByteCode{cmdLabel, 0})
@@ -738,6 +996,7 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 					(*prev).Code = append((*prev).Code, &ByteCode{cmdContinue, 0})
 				}
 			}
+			blockstack[len(blockstack)-1].setPositions(start)
 			blockstack = blockstack[:len(blockstack)-1]
 		}
 		if (newState.NewState & stateToBlock) > 0 {
@@ -751,14 +1010,30 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 				return nil, err
 			}
 		}
+		for _, block := range blockstack {
+			block.setPositions(start)
+		}
 		curState = nextState
 	}
 	if len(stack) > 0 {
 		return nil, fError(&blockstack, errMustRCurly, lexems[len(lexems)-1])
 	}
+	if vm.Optimize {
+		vm.optimize(root)
+	}
 	return root, nil
 }
 
+// setPositions binds the commands which have been appended to the block to the position of lexem
+func (block *Block) setPositions(lexem *Lexem) {
+	if len(block.Positions) > len(block.Code) {
+		block.Positions = block.Positions[:len(block.Code)]
+	}
+	for len(block.Positions) < len(block.Code) {
+		block.Positions = append(block.Positions, Position{Line: lexem.Line, Column: lexem.Column})
+	}
+}
+
 // FlushBlock loads the compiled Block into the virtual machine
 func (vm *VM) FlushBlock(root *Block) {
 	shift := len(vm.Children)
@@ -767,6 +1042,9 @@ func (vm *VM) FlushBlock(root *Block) {
 			switch item.Type {
 			case ObjContract:
 				root.Objects[key].Value.(*Block).Info.(*ContractInfo).ID = cur.Value.(*Block).Info.(*ContractInfo).ID + flushMark
+				if item.Value.(*Block).Info.(*ContractInfo).Library {
+					relinkLibrary(cur.Value.(*Block), item.Value.(*Block))
+				}
 			case ObjFunc:
 				root.Objects[key].Value.(*Block).Info.(*FuncInfo).ID = cur.Value.(*Block).Info.(*FuncInfo).ID + flushMark
 				vm.Objects[key].Value = root.Objects[key].Value
@@ -799,6 +1077,17 @@ func (vm *VM) FlushBlock(root *Block) {
 	}
 }
 
+// relinkLibrary keeps the objects of the functions of the previous version of the library because
+// the contracts which import the library refer to them
+func relinkLibrary(prev, lib *Block) {
+	for name, obj := range lib.Objects {
+		if cur, ok := prev.Objects[name]; ok && cur.Type == ObjFunc && obj.Type == ObjFunc {
+			cur.Value = obj.Value
+			lib.Objects[name] = cur
+		}
+	}
+}
+
 // FlushExtern switches off the extern mode of the compilation
 func (vm *VM) FlushExtern() {
 	vm.Extern = false
@@ -814,6 +1103,78 @@ func (vm *VM) Compile(input []rune, owner *OwnerInfo) error {
 	return err
 }
 
+// structLexem replaces the name of the struct with the type lexem if the type is expected
+func (vm *VM) structLexem(lexems Lexems, i int, state int, block *[]*Block) *Lexem {
+	lexem := lexems[i]
+	switch state {
+	case stateVarType, stateFParamTYPE, stateFResult:
+	case stateStructFields:
+		if i == 0 || lexems[i-1].Type != lexIdent {
+			return lexem
+		}
+	default:
+		return lexem
+	}
+	if obj, _ := vm.findObj(lexem.Value.(string), block); obj != nil && obj.Type == ObjStruct {
+		return &Lexem{Type: lexType, Value: obj.Value.(*Block), Line: lexem.Line, Column: lexem.Column}
+	}
+	return lexem
+}
+
+// libraryLexem replaces the name of the imported library with its block. The libraries of
+// the compiled source are looked up before the libraries of the virtual machine.
+func (vm *VM) libraryLexem(lexem *Lexem, state int, block *[]*Block) *Lexem {
+	if lexem.Type != lexIdent || (state != stateImport && state != stateLibraryImport) {
+		return lexem
+	}
+	name := lexem.Value.(string)
+	obj, ok := (*block)[0].Objects[StateName((*block)[0].Info.(uint32), name)]
+	if !ok {
+		obj = vm.getObjByNameExt(name, (*block)[0].Info.(uint32))
+	}
+	if obj != nil && obj.Type == ObjContract {
+		return &Lexem{Type: lexIdent, Value: obj.Value.(*Block), Line: lexem.Line, Column: lexem.Column}
+	}
+	return lexem
+}
+
+// compileFields compiles the access to the fields of the struct variable like name.field.field.
+// It returns IndexInfo if the value is assigned to the field.
+func compileFields(lexems *Lexems, ind *int, obj *ObjInfo, owner *Block) (ByteCodes, *IndexInfo, error) {
+	i := *ind
+	lexem := (*lexems)[i]
+	logger := lexem.GetLogger()
+	sblock := owner.VarStructs[obj.Value.(int)]
+	bytecode := ByteCodes{{cmdVar, &VarInfo{obj, owner}}}
+	for ; i < len(*lexems)-1 && (*lexems)[i+1].Type == isDot; i += 2 {
+		if sblock == nil {
+			logger.WithFields(log.Fields{"lex_value": lexem.Value, "type": consts.ParseError}).Error("not struct")
+			return nil, nil, fmt.Errorf(`%v is not struct [Ln:%d Col:%d]`, lexem.Value, lexem.Line, lexem.Column)
+		}
+		info := sblock.Info.(*StructInfo)
+		if i+2 >= len(*lexems) || (*lexems)[i+2].Type != lexIdent {
+			logger.WithFields(log.Fields{"type": consts.ParseError}).Error("must be the name of the field")
+			return nil, nil, fmt.Errorf(`must be the name of the field [Ln:%d Col:%d]`, lexem.Line, lexem.Column)
+		}
+		lexem = (*lexems)[i+2]
+		field := info.Field(lexem.Value.(string))
+		if field == nil {
+			logger.WithFields(log.Fields{"lex_value": lexem.Value, "type": consts.ParseError}).Error("unknown field")
+			return nil, nil, fmt.Errorf(`unknown field %v of struct %s [Ln:%d Col:%d]`, lexem.Value, info.Name,
+				lexem.Line, lexem.Column)
+		}
+		bytecode = append(bytecode, &ByteCode{cmdPush, field.Name})
+		if i+3 < len(*lexems) && (*lexems)[i+3].Type == isEq {
+			*ind = i + 3
+			return bytecode, &IndexInfo{obj.Value.(int), owner, ``}, nil
+		}
+		bytecode = append(bytecode, &ByteCode{cmdIndex, &IndexInfo{obj.Value.(int), owner, ``}})
+		sblock = field.Struct
+	}
+	*ind = i
+	return bytecode, nil, nil
+}
+
 func findVar(name string, block *[]*Block) (ret *ObjInfo, owner *Block) {
 	var ok bool
 	i := len(*block) - 1
@@ -826,6 +1187,21 @@ func findVar(name string, block *[]*Block) (ret *ObjInfo, owner *Block) {
 	return nil, nil
 }
 
+// findImport looks for the function in the libraries which have been imported by the contracts of the block stack
+func findImport(name string, block *[]*Block) (*ObjInfo, *Block) {
+	for i := len(*block) - 1; i >= 0; i-- {
+		if (*block)[i].Type != ObjContract {
+			continue
+		}
+		for _, lib := range (*block)[i].Info.(*ContractInfo).imports {
+			if obj, ok := lib.Objects[name]; ok && obj.Type == ObjFunc {
+				return obj, lib
+			}
+		}
+	}
+	return nil, nil
+}
+
 func (vm *VM) findObj(name string, block *[]*Block) (ret *ObjInfo, owner *Block) {
 	sname := StateName((*block)[0].Info.(uint32), name)
 	ret, owner = findVar(name, block)
@@ -836,6 +1212,9 @@ func (vm *VM) findObj(name string, block *[]*Block) (ret *ObjInfo, owner *Block)
 			return
 		}
 	}
+	if ret, owner = findImport(name, block); ret != nil {
+		return
+	}
 	if ret = vm.getObjByName(name); ret == nil && len(sname) > 0 {
 		ret = vm.getObjByName(sname)
 	}
@@ -1066,10 +1445,22 @@ main:
 			}
 		case lexIdent:
 			objInfo, tobj := vm.findObj(lexem.Value.(string), block)
-			if objInfo == nil && (!vm.Extern || i > *ind || i >= len(*lexems)-2 || (*lexems)[i+1].Type != isLPar) {
+			if (objInfo == nil && (!vm.Extern || i > *ind || i >= len(*lexems)-2 || (*lexems)[i+1].Type != isLPar)) ||
+				(objInfo != nil && objInfo.Type == ObjStruct) {
 				logger.WithFields(log.Fields{"lex_value": lexem.Value.(string), "type": consts.ParseError}).Error("unknown identifier")
 				return fmt.Errorf(`unknown identifier %s`, lexem.Value.(string))
 			}
+			if objInfo != nil && objInfo.Type == ObjVar && i < len(*lexems)-1 && (*lexems)[i+1].Type == isDot {
+				fields, info, err := compileFields(lexems, &i, objInfo, tobj)
+				if err != nil {
+					return err
+				}
+				bytecode = append(bytecode, fields...)
+				if info != nil {
+					setIndex, indexInfo = true, info
+				}
+				continue main
+			}
 			if i < len(*lexems)-2 {
 				if (*lexems)[i+1].Type == isLPar {
 					var isContract bool
@@ -1082,6 +1473,10 @@ main:
 						return fmt.Errorf(`unknown function %s`, lexem.Value.(string))
 					}
 					if objInfo.Type == ObjContract {
+						if objInfo.Value != nil && objInfo.Value.(*Block).Info.(*ContractInfo).Library {
+							logger.WithFields(log.Fields{"lex_value": lexem.Value.(string), "type": consts.ParseError}).Error("library is called as contract")
+							return fmt.Errorf(eLibraryCall, lexem.Value.(string))
+						}
 						objInfo, tobj = vm.findObj(`ExecContract`, block)
 						isContract = true
 					}
@@ -1175,7 +1570,7 @@ func ContractsList(value string) ([]string, error) {
 			level++
 		case isRCurly:
 			level--
-		case lexKeyword | (keyContract << 8), lexKeyword | (keyFunc << 8):
+		case lexKeyword | (keyContract << 8), lexKeyword | (keyFunc << 8), lexKeyword | (keyLibrary << 8):
 			if level == 0 && i+1 < len(lexems) && lexems[i+1].Type == lexIdent {
 				names = append(names, lexems[i+1].Value.(string))
 			}
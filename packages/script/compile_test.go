@@ -415,6 +415,172 @@ func TestVMCompile(t *testing.T) {
 				i = (i - "10")/"2"*"3"
 				return Sprintf("%T %[1]v", .21 + i)
 			  }`, `result`, `float64 138.21`},
+		{`func first(list array) string {
+				for item in list {
+					if str(item) != "" {
+						return str(item)
+					}
+				}
+				return "empty"
+			}
+			func result string {
+				var ret string
+				var my map
+				my["b"] = 2
+				my["a"] = 1
+				my["c"] = 3
+				for key in my {
+					ret = ret + key
+				}
+				for key, value in my {
+					if key == "b" {
+						continue
+					}
+					ret = ret + Sprintf(" %s=%d", key, value)
+				}
+				for i, item in GetArray() {
+					if i == 2 {
+						break
+					}
+					ret = ret + Sprintf(" %d:%s", i, str(item))
+				}
+				var empty array
+				for item in empty {
+					ret = ret + "never"
+				}
+				return ret + " " + first(empty) + " " + first(GetArray())
+			}`, `result`, `abc a=1 c=3 0:map[par0:Parameter 0 par1:Parameter 1] 1:The second string empty map[par0:Parameter 0 par1:Parameter 1]`},
+		{`func result string {
+				for item in 10 {
+				}
+				return "ok"
+			}`, `result`, `Type int64 doesn't support iteration`},
+		{`func fail(msg string) string {
+				warning msg
+				return "unreachable"
+			}
+			func result string {
+				var ret string
+				var i int
+				try {
+					ret = fail("first")
+				} catch err {
+					ret = err["type"] + ":" + err["error"]
+				}
+				try {
+					i = 10 / 0
+				} catch err {
+					ret = ret + " " + err["type"] + ":" + err["error"]
+				}
+				try {
+					ret = ret + " ok"
+				} catch {
+					ret = ret + " never"
+				}
+				try {
+					try {
+						error "inner"
+					} catch err {
+						throw err
+					}
+				} catch e {
+					ret = ret + " " + e["type"] + ":" + e["error"]
+				}
+				while i < 3 {
+					i = i + 1
+					try {
+						if i == 2 {
+							break
+						}
+					} catch {
+					}
+				}
+				return Sprintf("%s %d", ret, i)
+			}`, `result`, `warning:first panic:divided by zero ok error:inner 2`},
+		{`contract failed {
+				data {
+					Value int
+				}
+				action {
+					$result = "failed"
+					error Sprintf("bad value %d", $Value)
+				}
+			}
+			func result string {
+				var ret string
+				$mine = "kept"
+				try {
+					ret = failed("Value", 7)
+				} catch err {
+					ret = err["error"]
+				}
+				return ret + " " + $mine
+			}`, `result`, `bad value 7 kept`},
+		{`func result string {
+				try {
+					throw "thrown"
+				} catch err {
+				}
+				throw "uncaught"
+			}`, `result`, `{"type":"error","error":"uncaught"}`},
+		{`func import(in string) string {
+				return "imported " + in
+			}
+			func result string {
+				var for, try, catch, struct, library, throw string
+				for = "1"
+				try = "2"
+				catch = "3"
+				struct = "4"
+				library = "5"
+				throw = "6"
+				return import(for + try + catch + struct + library + throw)
+			}`, `result`, `imported 123456`},
+		{`struct Address {
+				City string
+				Zip int
+			}
+			struct Person {
+				Name string, Age int
+				Home Address
+				Tags array
+			}
+			func older(p Person) Person {
+				p.Age = p.Age + 1
+				return p
+			}
+			func result() string {
+				var p, q Person
+				var m map
+				var age int
+				p.Name = "Alice"
+				p.Home.City = "Paris"
+				q = older(p)
+				age = p.Age
+				m["Name"] = "Bob"
+				m["Age"] = 30.0
+				p = m
+				return Sprintf("%s %d %s %d %s %d %d|%s|", q.Name, q.Age, q.Home.City, age, p.Name, p.Age,
+					p.Home.Zip, p.Home.City)
+			}`, `result`, `Alice 1 Paris 0 Bob 30 0||`},
+		{`struct Point {
+				X int
+			}
+			func result() string {
+				var p Point
+				p.Y = 1
+				return "ok"
+			}`, `result`, `unknown field Y of struct Point [Ln:6 Col:8]`},
+		{`struct Point3 {
+				X int
+			}
+			func result() string {
+				var p Point3
+				var m map
+				m["Z"] = 1
+				p = m
+				return "ok"
+			}`, `result`, `unknown field Z of struct Point3`},
 		{`func money_test string {
 				var my2, m1 money
 				my2 = 100
@@ -502,3 +668,326 @@ func TestContractList(t *testing.T) {
 		}
 	}
 }
+
+func TestTypeCheck(t *testing.T) {
+	vm := NewVM()
+	vm.Extern = true
+	vm.Extend(&ExtendData{map[string]interface{}{"Sprintf": fmt.Sprintf, "str": str}, nil})
+	vm.ParamCheck = func(name string, index int, value string) error {
+		if name == `Sprintf` && index == 0 && strings.Contains(value, `%q`) {
+			return fmt.Errorf(`format %s is not allowed`, value)
+		}
+		return nil
+	}
+
+	root, err := vm.CompileBlock([]rune(`func mul(a b int) int {
+			return a * b
+		}
+		func result() string {
+			var s string
+			var i int
+			var m money
+			var list array
+			m = 10
+			i = mul(2, 3)
+			s = mul(i, 2)
+			s = Sprintf(i, list)
+			s = Sprintf("%d %v", i, list)
+			while i < 10 {
+				i = i + 1
+				if i == 5 {
+					break
+				}
+				continue
+			}
+			return s
+			s = str(i)
+			s = Sprintf("%q", s)
+		}`), &OwnerInfo{StateID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := vm.TypeCheck(root)
+	want := []string{
+		`result cannot assign int to string variable s [Ln:11 Col:7]`,
+		`result parameter 1 of Sprintf must be string instead of int [Ln:12 Col:7]`,
+		`result unreachable code [Ln:22 Col:5]`,
+		`result parameter 1 of Sprintf: format %q is not allowed [Ln:23 Col:7]`,
+	}
+	if len(diags) != len(want) {
+		t.Fatalf(`wrong diagnostics %v`, diags)
+	}
+	for i, item := range diags {
+		if item.String() != want[i] {
+			t.Errorf(`%s != %s`, item.String(), want[i])
+		}
+	}
+	if len(diags.Errors()) != 3 {
+		t.Errorf(`wrong count of errors %d`, len(diags.Errors()))
+	}
+}
+
+func TestBytecode(t *testing.T) {
+	test := []TestVM{
+		{`contract sets {
+			data {
+				Name string "optional"
+			}
+			settings {
+				rate = 100
+				name = "Name parameter"
+			}
+			action {
+				$result = Settings("@1sets","name")
+			}
+		}
+		struct Item {
+			Name string
+			Price money
+		}
+		func find().Where(pattern string, params ...) string {
+			return Sprintf(pattern, params ...)
+		}
+		func result() string {
+			var list array
+			var my map
+			var ret string
+			var it Item
+			it.Name = "item"
+			list[1] = 7.5
+			my["key"] = "value"
+			for i, item in list {
+				ret = ret + Sprintf("%d=%v ", i, item)
+			}
+			try {
+				error "failed"
+			} catch err {
+				ret = ret + err["error"]
+			}
+			$ext = my["key"]
+			return Sprintf("%s %s %s %v %s %v", ret, find().Where("%d+%s", 10, $ext), sets(), -2 < 1,
+				it.Name, it.Price)
+		}`, `result`, `0=<nil> 1=7.5 failed 10+value Name parameter true item 0`},
+	}
+	newVM := func() *VM {
+		vm := NewVM()
+		vm.Extern = true
+		vm.Extend(&ExtendData{map[string]interface{}{"Sprintf": fmt.Sprintf}, nil})
+		return vm
+	}
+	for _, item := range test {
+		owner := &OwnerInfo{StateID: 1, Active: true, TableID: 1}
+		vm := newVM()
+		root, err := vm.CompileBlock([]rune(item.Input), owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := vm.EncodeBlock(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm = newVM()
+		if root, err = vm.DecodeBlock(data, owner); err != nil {
+			t.Fatal(err)
+		}
+		vm.FlushBlock(root)
+		out, err := vm.Call(item.Func, nil, &map[string]interface{}{`rt_state`: uint32(1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out[0].(string) != item.Output {
+			t.Errorf(`error bytecode %s != %s`, out[0].(string), item.Output)
+		}
+		if _, err = vm.DecodeBlock(data[:len(data)-1], owner); err == nil {
+			t.Errorf(`broken byte-code has been decoded`)
+		}
+		if _, err = NewVM().DecodeBlock(data, owner); err == nil {
+			t.Errorf(`byte-code with unknown functions has been decoded`)
+		}
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	test := []TestVM{
+		{`func result() string {
+			var period int
+			period = 1000 * 60 * 60 + -(2 - 1)
+			return Sprintf("%d %s %v", period, "a" + "b", !(3 > 2.5))
+		}`, `result`, `3599999 ab false`},
+		{`func result() string {
+			var ret string
+			if 1 > 2 {
+				ret = "if"
+			} else {
+				ret = "else"
+			}
+			if 0 {
+				ret = ret + " never"
+			}
+			if "yes" {
+				ret = ret + " always"
+			} else {
+				ret = ret + " never"
+			}
+			while false {
+				ret = ret + " loop"
+			}
+			return ret
+			ret = "unreachable"
+		}`, `result`, `else always`},
+		{`func result() string {
+			var i int
+			while true {
+				i = i + 1
+				if i > 10 * 2 {
+					break
+					i = 0
+				}
+			}
+			return Sprintf("%d", i)
+		}`, `result`, `21`},
+		{`func result() string {
+			var i int
+			var ok bool
+			var ret string
+			while !(i >= 3) {
+				i = i + 1
+			}
+			ok = !!(i == 3)
+			if !ok {
+				ret = "if"
+			} else {
+				ret = "else"
+			}
+			return Sprintf("%d %v %v %s", i, !!!ok, !!(i < 3 || ok), ret)
+		}`, `result`, `3 false true else`},
+	}
+	run := func(input, name string, optimize bool) (string, int64) {
+		vm := NewVM()
+		vm.Extern = true
+		vm.Optimize = optimize
+		vm.Extend(&ExtendData{map[string]interface{}{"Sprintf": fmt.Sprintf}, nil})
+		if err := vm.Compile([]rune(input), &OwnerInfo{StateID: 1, Active: true, TableID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		rt := vm.RunInit(CostDefault)
+		out, err := rt.Run(vm.Objects[name].Value.(*Block), nil, &map[string]interface{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out[0].(string), rt.Cost()
+	}
+	for _, item := range test {
+		out, cost := run(item.Input, item.Func, false)
+		optOut, optCost := run(item.Input, item.Func, true)
+		if out != item.Output || optOut != item.Output {
+			t.Errorf(`error optimize %s %s != %s`, out, optOut, item.Output)
+		}
+		if optCost <= cost {
+			t.Errorf(`optimized code is not cheaper %d <= %d`, CostDefault-optCost, CostDefault-cost)
+		}
+	}
+	// the runtime errors are not folded
+	vm := NewVM()
+	vm.Optimize = true
+	if err := vm.Compile([]rune(`func result() int { return 1 / 0 }`), &OwnerInfo{StateID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vm.RunInit(CostDefault).Run(vm.Objects[`result`].Value.(*Block), nil,
+		&map[string]interface{}{}); err == nil {
+		t.Errorf(`division by zero has been folded`)
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	vm := NewVM()
+	vm.Extern = true
+	vm.Extend(&ExtendData{map[string]interface{}{"Sprintf": fmt.Sprintf}, nil})
+	owner := &OwnerInfo{StateID: 1, Active: true, TableID: 1}
+	library := `library Math {
+			func Sum(a b int) int {
+				return a + b + %d
+			}
+			func Double(a int) int {
+				return Sum(a, a)
+			}
+		}`
+	if err := vm.Compile([]rune(fmt.Sprintf(library, 0)), owner); err != nil {
+		t.Fatal(err)
+	}
+	root, err := vm.CompileBlock([]rune(`contract Calc {
+			import @1Math
+			action {
+				$result = Sprintf("%d", Double(Sum(2, 3)))
+			}
+		}
+		func result() string {
+			return Calc()
+		}`), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := vm.EncodeBlock(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm.FlushBlock(root)
+	if !vm.Objects[`@1Calc`].Value.(*Block).Info.(*ContractInfo).Used[`@1Math`] {
+		t.Errorf(`the import is not in the used contracts`)
+	}
+	call := func(want string) {
+		out, err := vm.Call(`result`, nil, &map[string]interface{}{`rt_state`: uint32(1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out[0].(string) != want {
+			t.Errorf(`wrong result %s != %s`, out[0].(string), want)
+		}
+	}
+	call(`10`)
+	// the contracts use the new version of the library
+	if err = vm.Compile([]rune(fmt.Sprintf(library, 1)), owner); err != nil {
+		t.Fatal(err)
+	}
+	call(`13`)
+	if root, err = vm.DecodeBlock(data, owner); err != nil {
+		t.Fatal(err)
+	}
+	vm.FlushBlock(root)
+	call(`13`)
+
+	errs := []TestVM{
+		{`contract Bad {
+			import Unknown
+		}`, ``, `unknown library Unknown [Ln:2 Col:12]`},
+		{`func bad() {
+			import Math
+		}`, ``, `import must be in contract or library [Ln:2 Col:12]`},
+		{`library Math2 {
+			func Sum(a b int) int {
+				return a + b
+			}
+		}
+		contract Bad {
+			import Math
+			import Math2
+		}`, ``, `function Sum is imported from @1Math and @1Math2 [Ln:8 Col:12]`},
+		{`contract Bad {
+			action {
+				Math()
+			}
+		}`, ``, `library Math cannot be called as contract`},
+		{`library Bad {
+			var i int
+		}`, ``, `unknown command 908 9 [Ln:2 Col:5]`},
+	}
+	for _, item := range errs {
+		if _, err = vm.CompileBlock([]rune(item.Input), owner); err == nil || err.Error() != item.Output {
+			t.Errorf(`wrong error %v != %s`, err, item.Output)
+		}
+	}
+	if _, err = ExecContract(vm.RunInit(CostDefault), `@1Math`, ``); err == nil ||
+		err.Error() != `library @1Math cannot be called as contract` {
+		t.Errorf(`wrong error %v`, err)
+	}
+}
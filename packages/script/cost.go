@@ -0,0 +1,191 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CostSchedule is the table of the fuel prices of the virtual machine. The omitted prices
+// are equal to the default ones.
+type CostSchedule struct {
+	Command  int64            `json:"command"`           // the cost of the command, the variable and the iteration
+	Call     int64            `json:"call"`              // the cost of the function calling
+	Contract int64            `json:"contract"`          // the cost of the contract calling
+	Extend   int64            `json:"extend"`            // the cost of the extend variable or function
+	Memory   int64            `json:"memory"`            // the cost of each 1024 bytes of the used memory
+	Opcodes  map[string]int64 `json:"opcodes,omitempty"` // the costs of the commands by their names
+	Funcs    map[string]int64 `json:"funcs,omitempty"`   // the costs of the extended functions
+	Queries  map[string]int64 `json:"queries,omitempty"` // the base costs of the database queries by their types
+
+	opcodes map[uint16]int64
+}
+
+// QueryTypes are the types of the database queries which costs can be changed by the fuel schedule
+var QueryTypes = []string{`select`, `insert`, `update`, `delete`, `join`}
+
+// CostVersion is the fuel schedule which is in force since the block
+type CostVersion struct {
+	Block    int64         `json:"block"`
+	Schedule *CostSchedule `json:"schedule"`
+}
+
+// CostSchedules is the list of the fuel schedules sorted by the blocks
+type CostSchedules []CostVersion
+
+var defaultCosts = &CostSchedule{
+	Command:  1,
+	Call:     CostCall,
+	Contract: CostContract,
+	Extend:   CostExtend,
+}
+
+// NewCostSchedule parses the fuel schedule in JSON format
+func NewCostSchedule(data []byte) (*CostSchedule, error) {
+	costs := *defaultCosts
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&costs); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling fuel schedule")
+		return nil, err
+	}
+	if costs.Command <= 0 || costs.Call < 0 || costs.Contract < 0 || costs.Extend < 0 || costs.Memory < 0 {
+		log.WithFields(log.Fields{"type": consts.InvalidObject}).Error("wrong price of fuel schedule")
+		return nil, fmt.Errorf(`wrong price of fuel schedule`)
+	}
+	costs.opcodes = make(map[uint16]int64)
+	for name, cost := range costs.Opcodes {
+		var found bool
+		for cmd, cmdName := range cmdNames {
+			if cmdName == strings.ToLower(name) {
+				costs.opcodes[cmd] = cost
+				found = true
+				break
+			}
+		}
+		if !found || cost <= 0 {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "opcode": name}).Error("wrong opcode of fuel schedule")
+			return nil, fmt.Errorf(`wrong opcode %s of fuel schedule`, name)
+		}
+	}
+	for name, cost := range costs.Funcs {
+		if cost < 0 {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "func": name}).Error("wrong function price of fuel schedule")
+			return nil, fmt.Errorf(`wrong price of %s function`, name)
+		}
+	}
+	for name, cost := range costs.Queries {
+		var found bool
+		for _, item := range QueryTypes {
+			if item == name {
+				found = true
+				break
+			}
+		}
+		if !found || cost < 0 {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "query": name}).Error("wrong query of fuel schedule")
+			return nil, fmt.Errorf(`wrong query %s of fuel schedule`, name)
+		}
+	}
+	return &costs, nil
+}
+
+// ParseCostSchedules parses the list of the versions of the fuel schedule like
+// [{"block": 1000, "schedule": {"call": 60}}]. The blocks must be in ascending order.
+func ParseCostSchedules(data string) (CostSchedules, error) {
+	var list []struct {
+		Block    int64           `json:"block"`
+		Schedule json.RawMessage `json:"schedule"`
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling fuel schedules")
+		return nil, err
+	}
+	ret := make(CostSchedules, 0, len(list))
+	for i, item := range list {
+		if item.Block <= 0 || (i > 0 && item.Block <= list[i-1].Block) {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "block": item.Block}).Error("wrong block of fuel schedule")
+			return nil, fmt.Errorf(`wrong block %d of fuel schedule`, item.Block)
+		}
+		costs, err := NewCostSchedule(item.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, CostVersion{Block: item.Block, Schedule: costs})
+	}
+	return ret, nil
+}
+
+// Get returns the fuel schedule which is in force at the block. It returns nil if
+// the default schedule is used.
+func (list CostSchedules) Get(blockID int64) *CostSchedule {
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Block <= blockID {
+			return list[i].Schedule
+		}
+	}
+	return nil
+}
+
+// opcode returns the cost of the command
+func (costs *CostSchedule) opcode(cmd uint16) int64 {
+	if cost, ok := costs.opcodes[cmd]; ok {
+		return cost
+	}
+	return costs.Command
+}
+
+// SetCostSchedule sets the fuel schedule of the execution. The default schedule is used if costs is nil.
+func (rt *RunTime) SetCostSchedule(costs *CostSchedule) {
+	if costs == nil {
+		costs = defaultCosts
+	}
+	rt.costs = costs
+}
+
+// extFuncCost returns the cost of the extended function. It returns -1 if the function has not
+// the own cost and false if the extended functions are free.
+func (rt *RunTime) extFuncCost(name string) (int64, bool) {
+	if cost, ok := rt.costs.Funcs[name]; ok {
+		return cost, true
+	}
+	if rt.vm.ExtCost == nil {
+		return 0, false
+	}
+	return rt.vm.ExtCost(name), true
+}
+
+// chargeMemory charges the growth of the used memory
+func (rt *RunTime) chargeMemory() {
+	if rt.costs.Memory == 0 {
+		return
+	}
+	if size := rt.mem >> 10; size > rt.memPaid {
+		rt.cost -= (size - rt.memPaid) * rt.costs.Memory
+		rt.memPaid = size
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"reflect"
+)
+
+// DebugAction is the action of the debugger after the pause
+type DebugAction int
+
+const (
+	// DebugContinue runs the byte-code until the next breakpoint
+	DebugContinue DebugAction = iota
+	// DebugStepInto pauses on the next command including the commands of the called functions
+	DebugStepInto
+	// DebugStepOver pauses on the next command of the current function
+	DebugStepOver
+	// DebugStepOut pauses on the next command of the calling function
+	DebugStepOut
+	// DebugStop terminates the execution
+	DebugStop
+)
+
+var cmdNames = map[uint16]string{
+	cmdPush:       `push`,
+	cmdVar:        `var`,
+	cmdExtend:     `extend`,
+	cmdCallExtend: `callextend`,
+	cmdPushStr:    `pushstr`,
+	cmdCall:       `call`,
+	cmdCallVari:   `callvari`,
+	cmdReturn:     `return`,
+	cmdIf:         `if`,
+	cmdElse:       `else`,
+	cmdAssignVar:  `assignvar`,
+	cmdAssign:     `assign`,
+	cmdLabel:      `label`,
+	cmdContinue:   `continue`,
+	cmdWhile:      `while`,
+	cmdBreak:      `break`,
+	cmdIndex:      `index`,
+	cmdSetIndex:   `setindex`,
+	cmdFuncName:   `funcname`,
+	cmdUnwrapArr:  `unwraparr`,
+	cmdError:      `error`,
+	cmdFor:        `for`,
+	cmdTry:        `try`,
+	cmdCatch:      `catch`,
+	cmdNot:        `not`,
+	cmdSign:       `sign`,
+	cmdAdd:        `add`,
+	cmdSub:        `sub`,
+	cmdMul:        `mul`,
+	cmdDiv:        `div`,
+	cmdAnd:        `and`,
+	cmdOr:         `or`,
+	cmdEqual:      `equal`,
+	cmdNotEq:      `noteq`,
+	cmdLess:       `less`,
+	cmdNotLess:    `notless`,
+	cmdGreat:      `great`,
+	cmdNotGreat:   `notgreat`,
+}
+
+// Breakpoint is the place where the execution is paused. If Line is defined then
+// the execution is paused on the first command of this source line. Otherwise Offset is
+// the index of the command in the byte-code of the function. If Name is empty the breakpoint
+// is applied to any contract or function.
+type Breakpoint struct {
+	Name   string `json:"name,omitempty"`
+	Line   uint32 `json:"line,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+}
+
+// DebugState is the state of the virtual machine at the pause
+type DebugState struct {
+	Name   string                 `json:"name"`
+	Offset int                    `json:"offset"`
+	Line   uint32                 `json:"line"`
+	Column uint32                 `json:"column"`
+	Cmd    string                 `json:"cmd"`
+	Depth  int                    `json:"depth"`
+	Stack  []interface{}          `json:"stack"`
+	Vars   map[string]interface{} `json:"vars"`
+	Extend map[string]interface{} `json:"extend"`
+	Cost   int64                  `json:"cost"`
+}
+
+// Debugger pauses the execution of the byte-code on breakpoints and steps
+type Debugger struct {
+	Breakpoints []Breakpoint
+	// Pause is called on every pause and returns the next action of the debugger
+	Pause func(*DebugState) DebugAction
+
+	action DebugAction
+	depth  int
+	names  map[*Block]string
+}
+
+// NewDebugger creates a new debugger. action defines whether the execution is paused
+// on the first command or on the first breakpoint.
+func NewDebugger(action DebugAction, breakpoints []Breakpoint, pause func(*DebugState) DebugAction) *Debugger {
+	return &Debugger{
+		Breakpoints: breakpoints,
+		Pause:       pause,
+		action:      action,
+		names:       make(map[*Block]string),
+	}
+}
+
+// SetDebugger attaches the debugger to the runtime
+func (rt *RunTime) SetDebugger(debugger *Debugger) {
+	rt.debug = debugger
+}
+
+// frameDepth returns the count of the called functions and contracts
+func (rt *RunTime) frameDepth() int {
+	depth := rt.debugDepth
+	for _, item := range rt.blocks {
+		if item.Block.Type == ObjFunc {
+			depth++
+		}
+	}
+	return depth
+}
+
+// blockName returns the name of the function which contains the block
+func (d *Debugger) blockName(block *Block) string {
+	block = funcBlock(block)
+	if name, ok := d.names[block]; ok {
+		return name
+	}
+	name := funcName(block)
+	d.names[block] = name
+	return name
+}
+
+func (d *Debugger) isBreakpoint(block *Block, offset int, line uint32, newLine bool) bool {
+	for _, bp := range d.Breakpoints {
+		if len(bp.Name) > 0 && bp.Name != d.blockName(block) {
+			continue
+		}
+		if bp.Line > 0 {
+			if newLine && bp.Line == line {
+				return true
+			}
+		} else if block.Type == ObjFunc && bp.Offset == offset {
+			return true
+		}
+	}
+	return false
+}
+
+// trace is called before the execution of each command when the debugger is attached
+func (d *Debugger) trace(rt *RunTime, block *Block, offset int) error {
+	depth := rt.frameDepth()
+	pos := block.Position(offset)
+	// the command is the first one of the source line in this block
+	newLine := offset == 0 || block.Position(offset-1).Line != pos.Line
+
+	var pause bool
+	switch d.action {
+	case DebugStepInto:
+		pause = true
+	case DebugStepOver:
+		pause = depth <= d.depth
+	case DebugStepOut:
+		pause = depth < d.depth
+	}
+	if !pause && !d.isBreakpoint(block, offset, pos.Line, newLine) {
+		return nil
+	}
+	d.depth = depth
+	if d.Pause == nil {
+		d.action = DebugContinue
+		return nil
+	}
+	d.action = d.Pause(rt.debugState(d.blockName(block), block, offset, depth))
+	if d.action == DebugStop {
+		return errDebugStop
+	}
+	return nil
+}
+
+// isDebugValue returns true if the value can be shown by the debugger
+func isDebugValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Func, reflect.Ptr, reflect.Chan, reflect.UnsafePointer, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+func (rt *RunTime) debugState(name string, block *Block, offset int, depth int) *DebugState {
+	pos := block.Position(offset)
+	state := &DebugState{
+		Name:   name,
+		Offset: offset,
+		Line:   pos.Line,
+		Column: pos.Column,
+		Cmd:    cmdNames[block.Code[offset].Cmd],
+		Depth:  depth,
+		Stack:  make([]interface{}, len(rt.stack)),
+		Vars:   make(map[string]interface{}),
+		Extend: make(map[string]interface{}),
+		Cost:   rt.cost,
+	}
+	copy(state.Stack, rt.stack)
+	// The variables of the current function are collected from the outer block to the inner one
+	first := len(rt.blocks) - 1
+	for ; first > 0 && rt.blocks[first].Block.Type != ObjFunc; first-- {
+	}
+	for i := first; i >= 0 && i < len(rt.blocks); i++ {
+		for key, item := range rt.blocks[i].Block.Objects {
+			if item.Type != ObjVar {
+				continue
+			}
+			if off := rt.blocks[i].Offset + item.Value.(int); off < len(rt.vars) {
+				state.Vars[key] = rt.vars[off]
+			}
+		}
+	}
+	if rt.extend != nil {
+		for key, item := range *rt.extend {
+			if isDebugValue(item) {
+				state.Extend[key] = item
+			}
+		}
+	}
+	return state
+}
@@ -24,6 +24,8 @@ const (
 	eTypeParam       = `parameter %d has wrong type`
 	eUndefinedParam  = `%s is not defined`
 	eUnknownContract = `unknown contract %s`
+	eLibraryCall     = `library %s cannot be called as contract`
+	eMultisigCall    = `contract %s requires the approvals of the signers`
 	eWrongParams     = `function %s must have %d parameters`
 	eArrIndex        = `index of array cannot be type %s`
 	eMapIndex        = `index of map cannot be type %s`
@@ -37,4 +39,5 @@ var (
 	errMaxArrayIndex   = errors.New(`The index is out of range`)
 	errMaxMapCount     = errors.New(`The maxumim length of map`)
 	errRecursion       = errors.New(`The contract can't call itself recursively`)
+	errDebugStop       = errors.New(`The execution has been stopped by the debugger`)
 )
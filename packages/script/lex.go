@@ -106,12 +106,21 @@ const (
 	keyCond
 	keyTail
 	keyError
+	keyFor
+	keyIn
+	keyTry
+	keyCatch
+	keyThrow
+	keyStruct
+	keyLibrary
+	keyImport
 )
 
 const (
 	msgWarning = `warning`
 	msgError   = `error`
 	msgInfo    = `info`
+	msgPanic   = `panic`
 )
 
 var (
@@ -120,7 +129,9 @@ var (
 		`if`: keyIf, `else`: keyElse, msgError: keyError, msgWarning: keyWarning, msgInfo: keyInfo,
 		`while`: keyWhile, `data`: keyTX, `settings`: keySettings, `nil`: keyNil, `action`: keyAction, `conditions`: keyCond,
 		`true`: keyTrue, `false`: keyFalse, `break`: keyBreak, `continue`: keyContinue,
-		`var`: keyVar, `...`: keyTail}
+		`var`: keyVar, `...`: keyTail, `for`: keyFor, `in`: keyIn,
+		`try`: keyTry, `catch`: keyCatch, `throw`: keyThrow, `struct`: keyStruct,
+		`library`: keyLibrary, `import`: keyImport}
 	// list of available types
 	// The list of types which save the corresponding 'reflect' type
 	types = map[string]reflect.Type{`bool`: reflect.TypeOf(true), `bytes`: reflect.TypeOf([]byte{}),
@@ -295,5 +306,75 @@ func lexParser(input []rune) (Lexems, error) {
 			off++
 		}
 	}
-	return lexems, nil
+	return contextKeywords(lexems), nil
+}
+
+// laterKeywords are the keywords which have been added after the release. They are keywords only
+// in their own places so the existing contracts can use them as the names.
+var laterKeywords = map[uint32]string{keyFor: `for`, keyIn: `in`, keyTry: `try`, keyCatch: `catch`,
+	keyThrow: `throw`, keyStruct: `struct`, keyLibrary: `library`, keyImport: `import`}
+
+func (l *Lexem) isKeyword(key uint32) bool {
+	return l != nil && l.Type == lexKeyword|(key<<8)
+}
+
+// contextKeywords replaces the later keywords with the identifiers if they are not followed or
+// preceded by the lexems of their statements
+func contextKeywords(lexems Lexems) Lexems {
+	// next returns the next lexem, the new lines are skipped if newLine is true
+	next := func(i int, newLine bool) *Lexem {
+		for i++; i < len(lexems); i++ {
+			if !newLine || lexems[i].Type != lexNewLine {
+				return lexems[i]
+			}
+		}
+		return nil
+	}
+	prev := func(i int) *Lexem {
+		for i--; i >= 0; i-- {
+			if lexems[i].Type != lexNewLine {
+				return lexems[i]
+			}
+		}
+		return nil
+	}
+	is := func(lexem *Lexem, types ...uint32) bool {
+		for _, item := range types {
+			if lexem != nil && lexem.Type == item {
+				return true
+			}
+		}
+		return false
+	}
+	for i, lexem := range lexems {
+		if lexem.Type&0xff != lexKeyword {
+			continue
+		}
+		key := lexem.Type >> 8
+		name, ok := laterKeywords[key]
+		if !ok {
+			continue
+		}
+		var keyword bool
+		switch key {
+		case keyFor, keyImport:
+			keyword = is(next(i, false), lexIdent)
+		case keyStruct, keyLibrary:
+			keyword = is(next(i, true), lexIdent)
+		case keyIn:
+			// for value in or for index, value in
+			keyword = i > 1 && is(lexems[i-1], lexIdent) && (lexems[i-2].isKeyword(keyFor) ||
+				(i > 3 && is(lexems[i-2], isComma) && is(lexems[i-3], lexIdent) && lexems[i-4].isKeyword(keyFor)))
+		case keyTry:
+			keyword = is(next(i, true), isLCurly)
+		case keyCatch:
+			keyword = is(prev(i), isRCurly) && is(next(i, true), lexIdent, isLCurly)
+		case keyThrow:
+			keyword = is(next(i, false), lexString, lexIdent, lexNumber, lexExtend)
+		}
+		if !keyword {
+			lexems[i] = &Lexem{Type: lexIdent, Value: name, Line: lexem.Line, Column: lexem.Column}
+		}
+	}
+	return lexems
 }
@@ -0,0 +1,158 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"reflect"
+)
+
+// isConst returns true if the command pushes the literal value
+func isConst(cmd *ByteCode) bool {
+	if cmd.Cmd != cmdPush {
+		return false
+	}
+	switch cmd.Value.(type) {
+	case int64, float64, bool, string:
+		return true
+	}
+	return false
+}
+
+// evalConst calculates the operation with the constant operands by the virtual machine
+// so the result is the same as at the runtime. It returns false if the operation fails,
+// in this case the error is left till the runtime.
+func (vm *VM) evalConst(operands ByteCodes, cmd *ByteCode) (interface{}, bool) {
+	code := make(ByteCodes, 0, len(operands)+2)
+	code = append(code, operands...)
+	code = append(code, cmd, &ByteCode{cmdReturn, 0})
+	block := &Block{Type: ObjFunc, Info: &FuncInfo{Results: []reflect.Type{nil}}, Code: code}
+	ret, err := vm.RunInit(CostDefault).Run(block, nil, &map[string]interface{}{})
+	if err != nil || len(ret) != 1 {
+		return nil, false
+	}
+	result := &ByteCode{cmdPush, ret[0]}
+	if !isConst(result) {
+		return nil, false
+	}
+	return ret[0], true
+}
+
+// negations are the pairs of the comparisons which return the opposite results
+var negations = map[uint16]uint16{cmdEqual: cmdNotEq, cmdNotEq: cmdEqual, cmdLess: cmdNotLess,
+	cmdNotLess: cmdLess, cmdGreat: cmdNotGreat, cmdNotGreat: cmdGreat}
+
+// isBool returns true if the command always pushes the boolean value
+func isBool(cmd *ByteCode) bool {
+	if _, ok := negations[cmd.Cmd]; ok {
+		return true
+	}
+	return cmd.Cmd == cmdNot || cmd.Cmd == cmdAnd || cmd.Cmd == cmdOr
+}
+
+// optimize rewrites the byte-code of the block and its children. It folds the operations
+// with the constant operands, removes the branches with the constant conditions which are never
+// executed and the commands after return, error, break and continue. The peephole rules merge
+// the negation with the previous comparison or negation and swap the branches of if-else
+// instead of the negation of the condition.
+func (vm *VM) optimize(block *Block) {
+	for _, item := range block.Children {
+		vm.optimize(item)
+	}
+	code := make(ByteCodes, 0, len(block.Code))
+	positions := make([]Position, 0, len(block.Code))
+	last := func(off int) *ByteCode {
+		if off >= len(code) {
+			return &ByteCode{}
+		}
+		return code[len(code)-1-off]
+	}
+	drop := func(count int) {
+		code = code[:len(code)-count]
+		positions = positions[:len(positions)-count]
+	}
+	for i := 0; i < len(block.Code); i++ {
+		cmd, pos := block.Code[i], block.Position(i)
+		switch cmd.Cmd {
+		case cmdIf:
+			hasElse := i+1 < len(block.Code) && block.Code[i+1].Cmd == cmdElse
+			if last(0).Cmd == cmdNot && hasElse {
+				// if !cond {A} else {B} is if cond {B} else {A}
+				drop(1)
+				code = append(code, &ByteCode{cmdIf, block.Code[i+1].Value}, &ByteCode{cmdElse, cmd.Value})
+				positions = append(positions, pos, block.Position(i+1))
+				i++
+				continue
+			}
+			if !isConst(last(0)) {
+				break
+			}
+			if valueToBool(last(0).Value) {
+				if hasElse {
+					i++
+				}
+			} else {
+				if !hasElse {
+					drop(1)
+				}
+				continue
+			}
+		case cmdWhile:
+			if isConst(last(0)) && !valueToBool(last(0).Value) && last(1).Cmd == cmdLabel {
+				drop(2)
+				continue
+			}
+		case cmdNot:
+			if negation, ok := negations[last(0).Cmd]; ok {
+				code[len(code)-1] = &ByteCode{negation, last(0).Value}
+				continue
+			}
+			if last(0).Cmd == cmdNot && isBool(last(1)) {
+				drop(1)
+				continue
+			}
+			// the negation of the constant is folded
+			fallthrough
+		default:
+			if count := int(cmd.Cmd >> 8); count > 0 && count <= len(code) {
+				operands := code[len(code)-count:]
+				folded := true
+				for _, item := range operands {
+					folded = folded && isConst(item)
+				}
+				if !folded {
+					break
+				}
+				if val, ok := vm.evalConst(operands, cmd); ok {
+					pos = positions[len(positions)-count]
+					drop(count)
+					cmd = &ByteCode{cmdPush, val}
+				}
+			}
+		}
+		code = append(code, cmd)
+		positions = append(positions, pos)
+		switch cmd.Cmd {
+		case cmdReturn, cmdError, cmdBreak, cmdContinue:
+			// the following commands of the block are never executed
+			i = len(block.Code)
+		}
+	}
+	block.Code = code
+	if len(block.Positions) > 0 {
+		block.Positions = positions
+	}
+}
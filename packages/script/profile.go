@@ -0,0 +1,153 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProfileItem is the fuel statistics of the function, the contract method or the extended function
+type ProfileItem struct {
+	Name  string `json:"name"`
+	Calls int64  `json:"calls"`
+	Self  int64  `json:"self"`       // the cost of the own commands
+	Total int64  `json:"cumulative"` // the cost including the called functions
+	Query int64  `json:"query"`      // the cost of the database queries
+}
+
+// Profile is the fuel profile of the execution. Folded contains the stacks of the calls
+// in the folded format of the flame graphs like "@1Contract.action;DBFind;DBSelect 120".
+type Profile struct {
+	Items  []ProfileItem `json:"items"`
+	Folded []string      `json:"folded"`
+}
+
+type profileFrame struct {
+	name     string
+	stack    string // the names of the calling frames separated by ;
+	cost     int64  // the remain cost at the start of the frame
+	children int64  // the cost of the called frames
+	query    int64
+}
+
+// Profiler attributes the spent fuel to the called functions. The cost is calculated
+// as the difference of the remain cost of the runtime at the start and at the end of the call.
+type Profiler struct {
+	frames []*profileFrame
+	items  map[string]*ProfileItem
+	folded map[string]int64
+	names  map[*Block]string
+}
+
+// NewProfiler creates a new profiler
+func NewProfiler() *Profiler {
+	return &Profiler{
+		items:  make(map[string]*ProfileItem),
+		folded: make(map[string]int64),
+		names:  make(map[*Block]string),
+	}
+}
+
+// SetProfiler attaches the profiler to the runtime
+func (rt *RunTime) SetProfiler(profiler *Profiler) {
+	rt.profile = profiler
+}
+
+func (p *Profiler) blockName(block *Block) string {
+	if name, ok := p.names[block]; ok {
+		return name
+	}
+	name := funcName(block)
+	p.names[block] = name
+	return name
+}
+
+// enter starts the frame of the call and returns the depth which must be passed to leave
+func (p *Profiler) enter(name string, cost int64) int {
+	frame := &profileFrame{name: name, stack: name, cost: cost}
+	if len(p.frames) > 0 {
+		frame.stack = p.frames[len(p.frames)-1].stack + `;` + name
+	}
+	p.frames = append(p.frames, frame)
+	return len(p.frames) - 1
+}
+
+// leave finishes the frame started at depth. The frames which have not been finished
+// because of the errors are finished too.
+func (p *Profiler) leave(depth int, cost int64) {
+	for len(p.frames) > depth {
+		frame := p.frames[len(p.frames)-1]
+		p.frames = p.frames[:len(p.frames)-1]
+		total := frame.cost - cost
+		item := p.items[frame.name]
+		if item == nil {
+			item = &ProfileItem{Name: frame.name}
+			p.items[frame.name] = item
+		}
+		item.Calls++
+		item.Self += total - frame.children
+		item.Query += frame.query
+		// the cost of the recursive calls is included in the outer call
+		recursive := false
+		for _, parent := range p.frames {
+			if parent.name == frame.name {
+				recursive = true
+				break
+			}
+		}
+		if !recursive {
+			item.Total += total
+		}
+		p.folded[frame.stack] += total - frame.children
+		if len(p.frames) > 0 {
+			p.frames[len(p.frames)-1].children += total
+		}
+	}
+}
+
+// query adds the cost of the database query to the current frame
+func (p *Profiler) query(cost int64) {
+	if len(p.frames) > 0 {
+		p.frames[len(p.frames)-1].query += cost
+	}
+}
+
+// Profile returns the collected statistics sorted by the cumulative cost
+func (p *Profiler) Profile() *Profile {
+	ret := &Profile{Items: make([]ProfileItem, 0, len(p.items)), Folded: make([]string, 0, len(p.folded))}
+	for _, item := range p.items {
+		ret.Items = append(ret.Items, *item)
+	}
+	sort.Slice(ret.Items, func(i, j int) bool {
+		if ret.Items[i].Total != ret.Items[j].Total {
+			return ret.Items[i].Total > ret.Items[j].Total
+		}
+		return ret.Items[i].Name < ret.Items[j].Name
+	})
+	for stack, cost := range p.folded {
+		ret.Folded = append(ret.Folded, fmt.Sprintf(`%s %d`, stack, cost))
+	}
+	sort.Strings(ret.Folded)
+	return ret
+}
+
+// String returns the profile in the folded format of the flame graphs
+func (profile *Profile) String() string {
+	return strings.Join(profile.Folded, "\n")
+}
@@ -0,0 +1,797 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// The compiled block can be serialized into the binary format and loaded into the virtual
+// machine without the compilation. The blocks are stored as the flat list where the pointers
+// to the blocks are replaced with their indexes. The objects of the virtual machine such as
+// extended functions are stored by name and they are looked up again when the block is loaded.
+
+const (
+	// BytecodeVersion is the version of the binary format of the byte-code. It is increased when
+	// the compiler or the optimizer changes the byte-code so the cached byte-code is compiled again.
+	BytecodeVersion = 4
+
+	bytecodeMagic = `GBC`
+)
+
+// The kinds of the values of byte-code commands
+const (
+	valNil = iota
+	valBool
+	valInt
+	valInt64
+	valUint16
+	valUint32
+	valFloat
+	valString
+	valDecimal
+	valBlock
+	valObj
+	valVar
+	valVars
+	valIndex
+	valFuncName
+)
+
+// The kinds of Block.Info
+const (
+	infoNil = iota
+	infoState
+	infoContract
+	infoFunc
+	infoParams
+	infoStruct
+)
+
+// refData refers to the object of the block. Block is -1 for the objects of the virtual machine
+// and extend variables.
+type refData struct {
+	Block int32
+	Name  string
+}
+
+type valueData struct {
+	Kind  uint8
+	Int   int64
+	Float float64
+	Str   string
+	Refs  []refData
+}
+
+type codeData struct {
+	Cmd   uint16
+	Value valueData
+}
+
+type objData struct {
+	Name  string
+	Type  int
+	Value int32 // the offset of the variable or the index of the block
+}
+
+type fieldData struct {
+	Name string
+	Type string
+	Tags string
+}
+
+type structFieldData struct {
+	Name   string
+	Type   string
+	Struct *refData
+}
+
+type funcNameData struct {
+	Name     string
+	Params   []string
+	Offset   []int
+	Variadic bool
+}
+
+type infoData struct {
+	Kind     uint8
+	ID       uint32
+	Name     string
+	Used     []string
+	Library  bool
+	Tx       []fieldData
+	HasTx    bool
+	Settings map[string]valueData
+	Params   []string
+	Results  []string
+	Names    []funcNameData
+	HasNames bool
+	Variadic bool
+	Count    int
+	Fields   []structFieldData
+}
+
+type blockData struct {
+	Objects   []objData
+	Type      int
+	Parent    int32
+	Info      infoData
+	Vars      []string
+	Structs   map[int32]refData
+	Code      []codeData
+	Positions []Position
+	Children  []int32
+}
+
+type bytecodeData struct {
+	Blocks []blockData
+}
+
+var typeInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// BytecodeKey returns the key of the compiled source code. It depends on the version
+// of the byte-code, the version of the node, the optimization and the ecosystem of the owner.
+func (vm *VM) BytecodeKey(src string, owner *OwnerInfo) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d %s %t %d\n%s", BytecodeVersion, consts.VERSION,
+		vm.Optimize, owner.StateID, src)))
+	return hex.EncodeToString(hash[:])
+}
+
+func typeToStr(t reflect.Type) (string, error) {
+	if t == nil {
+		return ``, nil
+	}
+	if t == typeInterface {
+		return `interface`, nil
+	}
+	for name, item := range types {
+		if item == t {
+			return name, nil
+		}
+	}
+	return ``, fmt.Errorf(`unsupported type %s`, t.String())
+}
+
+func strToType(name string) (reflect.Type, error) {
+	switch name {
+	case ``:
+		return nil, nil
+	case `interface`:
+		return typeInterface, nil
+	}
+	if t, ok := types[name]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf(`unsupported type %s`, name)
+}
+
+func typesToStr(list []reflect.Type) (ret []string, err error) {
+	ret = make([]string, len(list))
+	for i, item := range list {
+		if ret[i], err = typeToStr(item); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func strToTypes(list []string) (ret []reflect.Type, err error) {
+	ret = make([]reflect.Type, len(list))
+	for i, item := range list {
+		if ret[i], err = strToType(item); err != nil {
+			return
+		}
+	}
+	return
+}
+
+type encoder struct {
+	vm     *VM
+	blocks map[*Block]int32
+	objs   map[*ObjInfo]refData
+	vmObjs map[*ObjInfo]string
+}
+
+func (enc *encoder) indexBlocks(block *Block, list *[]*Block) {
+	enc.blocks[block] = int32(len(*list))
+	*list = append(*list, block)
+	for _, child := range block.Children {
+		enc.indexBlocks(child, list)
+	}
+}
+
+func (enc *encoder) blockIndex(block *Block) (int32, error) {
+	if block == nil {
+		return -1, nil
+	}
+	if ind, ok := enc.blocks[block]; ok {
+		return ind, nil
+	}
+	return 0, fmt.Errorf(`unknown block`)
+}
+
+// objRef returns the reference to the object which can be the object of the compiled blocks
+// or the object of the virtual machine
+func (enc *encoder) objRef(obj *ObjInfo) (refData, error) {
+	if ref, ok := enc.objs[obj]; ok {
+		return ref, nil
+	}
+	if name, ok := enc.vmObjs[obj]; ok {
+		return refData{Block: -1, Name: name}, nil
+	}
+	if obj.Type == ObjFunc {
+		for name, item := range enc.vm.Objects {
+			if item.Type == ObjFunc && item.Value == obj.Value {
+				return refData{Block: -1, Name: name}, nil
+			}
+		}
+		// the function of the imported library is referred by the full name like @1Lib.func
+		if block := obj.Value.(*Block); block.Parent != nil && block.Parent.Type == ObjContract {
+			name := block.Parent.Info.(*ContractInfo).Name + `.` + getNameByObj(obj)
+			if enc.vm.getObjByName(name) == obj {
+				return refData{Block: -1, Name: name}, nil
+			}
+		}
+	}
+	return refData{}, fmt.Errorf(`unknown object`)
+}
+
+// structRef returns the reference to the block of the struct. The struct of the virtual
+// machine is referred by its name.
+func (enc *encoder) structRef(block *Block) (refData, error) {
+	if ind, ok := enc.blocks[block]; ok {
+		return refData{Block: ind}, nil
+	}
+	name := block.Info.(*StructInfo).Name
+	if obj, ok := enc.vm.Objects[name]; ok && obj.Value == block {
+		return refData{Block: -1, Name: name}, nil
+	}
+	return refData{}, fmt.Errorf(`unknown struct %s`, name)
+}
+
+func (enc *encoder) varRef(ivar *VarInfo) (refData, error) {
+	if ivar.Owner == nil {
+		return refData{Block: -1, Name: ivar.Obj.Value.(string)}, nil
+	}
+	return enc.objRef(ivar.Obj)
+}
+
+func encodeValue(v interface{}) (ret valueData, err error) {
+	switch val := v.(type) {
+	case nil:
+		ret.Kind = valNil
+	case bool:
+		ret.Kind = valBool
+		if val {
+			ret.Int = 1
+		}
+	case int:
+		ret.Kind, ret.Int = valInt, int64(val)
+	case int64:
+		ret.Kind, ret.Int = valInt64, val
+	case uint16:
+		ret.Kind, ret.Int = valUint16, int64(val)
+	case uint32:
+		ret.Kind, ret.Int = valUint32, int64(val)
+	case float64:
+		ret.Kind, ret.Float = valFloat, val
+	case string:
+		ret.Kind, ret.Str = valString, val
+	case decimal.Decimal:
+		ret.Kind, ret.Str = valDecimal, val.String()
+	default:
+		err = fmt.Errorf(`unsupported value %T`, v)
+	}
+	return
+}
+
+func (enc *encoder) encodeCode(cmd *ByteCode) (ret codeData, err error) {
+	var ref refData
+
+	ret.Cmd = cmd.Cmd
+	switch val := cmd.Value.(type) {
+	case *Block:
+		ret.Value.Kind = valBlock
+		var ind int32
+		ind, err = enc.blockIndex(val)
+		ret.Value.Int = int64(ind)
+	case *ObjInfo:
+		ret.Value.Kind = valObj
+		ref, err = enc.objRef(val)
+		ret.Value.Refs = []refData{ref}
+	case *VarInfo:
+		ret.Value.Kind = valVar
+		ref, err = enc.varRef(val)
+		ret.Value.Refs = []refData{ref}
+	case []*VarInfo:
+		ret.Value.Kind = valVars
+		for _, ivar := range val {
+			if ref, err = enc.varRef(ivar); err != nil {
+				break
+			}
+			ret.Value.Refs = append(ret.Value.Refs, ref)
+		}
+	case *IndexInfo:
+		ret.Value.Kind = valIndex
+		var ind int32
+		ind, err = enc.blockIndex(val.Owner)
+		ret.Value.Int, ret.Value.Str = int64(val.VarOffset), val.Extend
+		ret.Value.Refs = []refData{{Block: ind}}
+	case FuncNameCmd:
+		ret.Value.Kind, ret.Value.Str, ret.Value.Int = valFuncName, val.Name, int64(val.Count)
+	default:
+		ret.Value, err = encodeValue(val)
+	}
+	return
+}
+
+func (enc *encoder) encodeInfo(info interface{}) (ret infoData, err error) {
+	switch val := info.(type) {
+	case nil:
+	case uint32:
+		ret.Kind, ret.ID = infoState, val
+	case *ContractInfo:
+		ret.Kind, ret.ID, ret.Name, ret.Library = infoContract, val.ID, val.Name, val.Library
+		for key := range val.Used {
+			ret.Used = append(ret.Used, key)
+		}
+		if val.Tx != nil {
+			ret.HasTx = true
+			for _, field := range *val.Tx {
+				var ftype string
+				if ftype, err = typeToStr(field.Type); err != nil {
+					return
+				}
+				ret.Tx = append(ret.Tx, fieldData{Name: field.Name, Type: ftype, Tags: field.Tags})
+			}
+		}
+		if val.Settings != nil {
+			ret.Settings = make(map[string]valueData)
+			for key, item := range val.Settings {
+				if ret.Settings[key], err = encodeValue(item); err != nil {
+					return
+				}
+			}
+		}
+	case *FuncInfo:
+		ret.Kind, ret.ID, ret.Variadic = infoFunc, val.ID, val.Variadic
+		if ret.Params, err = typesToStr(val.Params); err != nil {
+			return
+		}
+		if ret.Results, err = typesToStr(val.Results); err != nil {
+			return
+		}
+		if val.Names != nil {
+			ret.HasNames = true
+			for key, item := range *val.Names {
+				name := funcNameData{Name: key, Offset: item.Offset, Variadic: item.Variadic}
+				if name.Params, err = typesToStr(item.Params); err != nil {
+					return
+				}
+				ret.Names = append(ret.Names, name)
+			}
+		}
+	case *ParamsInfo:
+		ret.Kind, ret.Count = infoParams, val.Count
+	case *StructInfo:
+		ret.Kind, ret.Name = infoStruct, val.Name
+		for _, field := range val.Fields {
+			item := structFieldData{Name: field.Name}
+			if item.Type, err = typeToStr(field.Type); err != nil {
+				return
+			}
+			if field.Struct != nil {
+				var ref refData
+				if ref, err = enc.structRef(field.Struct); err != nil {
+					return
+				}
+				item.Struct = &ref
+			}
+			ret.Fields = append(ret.Fields, item)
+		}
+	default:
+		err = fmt.Errorf(`unsupported info %T`, info)
+	}
+	return
+}
+
+func (enc *encoder) encodeBlock(block *Block) (ret blockData, err error) {
+	if ret.Parent, err = enc.blockIndex(block.Parent); err != nil {
+		return
+	}
+	ret.Type = block.Type
+	for name, obj := range block.Objects {
+		item := objData{Name: name, Type: obj.Type}
+		switch obj.Type {
+		case ObjVar:
+			item.Value = int32(obj.Value.(int))
+		case ObjFunc, ObjContract, ObjStruct:
+			if item.Value, err = enc.blockIndex(obj.Value.(*Block)); err != nil {
+				return
+			}
+		default:
+			return ret, fmt.Errorf(`unsupported object %s`, name)
+		}
+		ret.Objects = append(ret.Objects, item)
+	}
+	if ret.Info, err = enc.encodeInfo(block.Info); err != nil {
+		return
+	}
+	if ret.Vars, err = typesToStr(block.Vars); err != nil {
+		return
+	}
+	if len(block.VarStructs) > 0 {
+		ret.Structs = make(map[int32]refData)
+		for key, sblock := range block.VarStructs {
+			if ret.Structs[int32(key)], err = enc.structRef(sblock); err != nil {
+				return
+			}
+		}
+	}
+	for _, cmd := range block.Code {
+		var code codeData
+		if code, err = enc.encodeCode(cmd); err != nil {
+			return
+		}
+		ret.Code = append(ret.Code, code)
+	}
+	ret.Positions = block.Positions
+	for _, child := range block.Children {
+		ret.Children = append(ret.Children, enc.blocks[child])
+	}
+	return
+}
+
+// EncodeBlock serializes the compiled block into the binary format. It must be called
+// before the block is loaded into the virtual machine by FlushBlock.
+func (vm *VM) EncodeBlock(root *Block) ([]byte, error) {
+	var (
+		list []*Block
+		data bytecodeData
+		buf  bytes.Buffer
+	)
+	enc := encoder{vm: vm, blocks: make(map[*Block]int32), objs: make(map[*ObjInfo]refData),
+		vmObjs: make(map[*ObjInfo]string)}
+	enc.indexBlocks(root, &list)
+	for i, block := range list {
+		for name, obj := range block.Objects {
+			enc.objs[obj] = refData{Block: int32(i), Name: name}
+		}
+	}
+	for name, obj := range vm.Objects {
+		enc.vmObjs[obj] = name
+	}
+	for _, block := range list {
+		item, err := enc.encodeBlock(block)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("encoding byte-code")
+			return nil, err
+		}
+		data.Blocks = append(data.Blocks, item)
+	}
+	buf.WriteString(bytecodeMagic)
+	binary.Write(&buf, binary.LittleEndian, uint16(BytecodeVersion))
+	if err := gob.NewEncoder(&buf).Encode(&data); err != nil {
+		log.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("encoding byte-code")
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type decoder struct {
+	vm     *VM
+	blocks []*Block
+}
+
+func (dec *decoder) block(ind int64) (*Block, error) {
+	if ind < 0 || ind >= int64(len(dec.blocks)) {
+		return nil, fmt.Errorf(`wrong index of block %d`, ind)
+	}
+	return dec.blocks[ind], nil
+}
+
+func (dec *decoder) obj(ref refData) (*ObjInfo, *Block, error) {
+	if ref.Block < 0 {
+		if obj := dec.vm.getObjByName(ref.Name); obj != nil {
+			return obj, nil, nil
+		}
+		return nil, nil, fmt.Errorf(`unknown object %s`, ref.Name)
+	}
+	block, err := dec.block(int64(ref.Block))
+	if err != nil {
+		return nil, nil, err
+	}
+	if obj, ok := block.Objects[ref.Name]; ok {
+		return obj, block, nil
+	}
+	return nil, nil, fmt.Errorf(`unknown object %s`, ref.Name)
+}
+
+func (dec *decoder) structBlock(ref refData) (*Block, error) {
+	if ref.Block >= 0 {
+		return dec.block(int64(ref.Block))
+	}
+	if obj, ok := dec.vm.Objects[ref.Name]; ok && obj.Type == ObjStruct {
+		return obj.Value.(*Block), nil
+	}
+	return nil, fmt.Errorf(`unknown struct %s`, ref.Name)
+}
+
+func (dec *decoder) varInfo(ref refData) (*VarInfo, error) {
+	if ref.Block < 0 {
+		return &VarInfo{Obj: &ObjInfo{Type: ObjExtend, Value: ref.Name}}, nil
+	}
+	obj, owner, err := dec.obj(ref)
+	if err != nil {
+		return nil, err
+	}
+	return &VarInfo{Obj: obj, Owner: owner}, nil
+}
+
+func decodeValue(val valueData) (interface{}, error) {
+	switch val.Kind {
+	case valNil:
+		return nil, nil
+	case valBool:
+		return val.Int != 0, nil
+	case valInt:
+		return int(val.Int), nil
+	case valInt64:
+		return val.Int, nil
+	case valUint16:
+		return uint16(val.Int), nil
+	case valUint32:
+		return uint32(val.Int), nil
+	case valFloat:
+		return val.Float, nil
+	case valString:
+		return val.Str, nil
+	case valDecimal:
+		return decimal.NewFromString(val.Str)
+	}
+	return nil, fmt.Errorf(`unsupported kind of value %d`, val.Kind)
+}
+
+func (dec *decoder) decodeCode(code codeData) (*ByteCode, error) {
+	var err error
+
+	cmd := &ByteCode{Cmd: code.Cmd}
+	val := code.Value
+	switch val.Kind {
+	case valBlock:
+		cmd.Value, err = dec.block(val.Int)
+	case valObj, valVar:
+		if len(val.Refs) != 1 {
+			return nil, fmt.Errorf(`wrong reference`)
+		}
+		if val.Kind == valObj {
+			cmd.Value, _, err = dec.obj(val.Refs[0])
+		} else {
+			cmd.Value, err = dec.varInfo(val.Refs[0])
+		}
+	case valVars:
+		vars := make([]*VarInfo, len(val.Refs))
+		for i, ref := range val.Refs {
+			if vars[i], err = dec.varInfo(ref); err != nil {
+				break
+			}
+		}
+		cmd.Value = vars
+	case valIndex:
+		info := &IndexInfo{VarOffset: int(val.Int), Extend: val.Str}
+		if len(val.Refs) != 1 {
+			return nil, fmt.Errorf(`wrong reference`)
+		}
+		if val.Refs[0].Block >= 0 {
+			info.Owner, err = dec.block(int64(val.Refs[0].Block))
+		}
+		cmd.Value = info
+	case valFuncName:
+		cmd.Value = FuncNameCmd{Name: val.Str, Count: int(val.Int)}
+	default:
+		cmd.Value, err = decodeValue(val)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (dec *decoder) decodeInfo(info infoData, owner *OwnerInfo) (ret interface{}, err error) {
+	switch info.Kind {
+	case infoNil:
+	case infoState:
+		ret = info.ID
+	case infoContract:
+		cinfo := &ContractInfo{ID: info.ID, Name: info.Name, Owner: owner, Library: info.Library}
+		if len(info.Used) > 0 {
+			cinfo.Used = make(map[string]bool)
+			for _, key := range info.Used {
+				cinfo.Used[key] = true
+			}
+		}
+		if info.HasTx {
+			tx := make([]*FieldInfo, len(info.Tx))
+			for i, field := range info.Tx {
+				tx[i] = &FieldInfo{Name: field.Name, Tags: field.Tags}
+				if tx[i].Type, err = strToType(field.Type); err != nil {
+					return
+				}
+			}
+			cinfo.Tx = &tx
+		}
+		if info.Settings != nil {
+			cinfo.Settings = make(map[string]interface{})
+			for key, item := range info.Settings {
+				if cinfo.Settings[key], err = decodeValue(item); err != nil {
+					return
+				}
+			}
+		}
+		ret = cinfo
+	case infoFunc:
+		finfo := &FuncInfo{ID: info.ID, Variadic: info.Variadic}
+		if finfo.Params, err = strToTypes(info.Params); err != nil {
+			return
+		}
+		if finfo.Results, err = strToTypes(info.Results); err != nil {
+			return
+		}
+		if info.HasNames {
+			names := make(map[string]FuncName)
+			for _, item := range info.Names {
+				name := FuncName{Offset: item.Offset, Variadic: item.Variadic}
+				if name.Params, err = strToTypes(item.Params); err != nil {
+					return
+				}
+				names[item.Name] = name
+			}
+			finfo.Names = &names
+		}
+		ret = finfo
+	case infoParams:
+		ret = &ParamsInfo{Count: info.Count}
+	case infoStruct:
+		sinfo := &StructInfo{Name: info.Name, Fields: make([]*StructField, len(info.Fields))}
+		for i, item := range info.Fields {
+			field := &StructField{Name: item.Name}
+			if field.Type, err = strToType(item.Type); err != nil {
+				return
+			}
+			if item.Struct != nil {
+				if field.Struct, err = dec.structBlock(*item.Struct); err != nil {
+					return
+				}
+			}
+			sinfo.Fields[i] = field
+		}
+		ret = sinfo
+	default:
+		err = fmt.Errorf(`unsupported kind of info %d`, info.Kind)
+	}
+	return
+}
+
+// DecodeBlock loads the block which has been serialized by EncodeBlock. The objects of
+// the virtual machine which are used in the byte-code must be defined. The returned block
+// can be loaded into the virtual machine by FlushBlock.
+func (vm *VM) DecodeBlock(input []byte, owner *OwnerInfo) (*Block, error) {
+	var (
+		data    bytecodeData
+		version uint16
+	)
+	logger := log.WithFields(log.Fields{"type": consts.VMError})
+	if len(input) < len(bytecodeMagic)+2 || string(input[:len(bytecodeMagic)]) != bytecodeMagic {
+		logger.Error("wrong format of byte-code")
+		return nil, fmt.Errorf(`wrong format of byte-code`)
+	}
+	buf := bytes.NewBuffer(input[len(bytecodeMagic):])
+	binary.Read(buf, binary.LittleEndian, &version)
+	if version != BytecodeVersion {
+		logger.WithFields(log.Fields{"version": version}).Error("wrong version of byte-code")
+		return nil, fmt.Errorf(`wrong version of byte-code %d`, version)
+	}
+	if err := gob.NewDecoder(buf).Decode(&data); err != nil {
+		logger.WithFields(log.Fields{"error": err}).Error("decoding byte-code")
+		return nil, err
+	}
+	if len(data.Blocks) == 0 {
+		logger.Error("byte-code is empty")
+		return nil, fmt.Errorf(`byte-code is empty`)
+	}
+	dec := decoder{vm: vm, blocks: make([]*Block, len(data.Blocks))}
+	for i := range data.Blocks {
+		dec.blocks[i] = &Block{}
+	}
+	err := dec.decodeBlocks(data.Blocks, owner)
+	if err != nil {
+		logger.WithFields(log.Fields{"error": err}).Error("decoding byte-code")
+		return nil, err
+	}
+	return dec.blocks[0], nil
+}
+
+func (dec *decoder) decodeBlocks(list []blockData, owner *OwnerInfo) (err error) {
+	// the objects of all blocks must be defined before the byte-code refers to them
+	for i, item := range list {
+		block := dec.blocks[i]
+		block.Type = item.Type
+		if item.Parent >= 0 {
+			if block.Parent, err = dec.block(int64(item.Parent)); err != nil {
+				return
+			}
+		}
+		if len(item.Objects) > 0 {
+			block.Objects = make(map[string]*ObjInfo)
+		}
+		for _, obj := range item.Objects {
+			ival := &ObjInfo{Type: obj.Type}
+			if obj.Type == ObjVar {
+				ival.Value = int(obj.Value)
+			} else if ival.Value, err = dec.block(int64(obj.Value)); err != nil {
+				return
+			}
+			block.Objects[obj.Name] = ival
+		}
+		if block.Info, err = dec.decodeInfo(item.Info, owner); err != nil {
+			return
+		}
+		if block.Vars, err = strToTypes(item.Vars); err != nil {
+			return
+		}
+		if len(item.Structs) > 0 {
+			block.VarStructs = make(map[int]*Block)
+			for key, ref := range item.Structs {
+				if block.VarStructs[int(key)], err = dec.structBlock(ref); err != nil {
+					return
+				}
+			}
+		}
+		for _, child := range item.Children {
+			var cblock *Block
+			if cblock, err = dec.block(int64(child)); err != nil {
+				return
+			}
+			block.Children = append(block.Children, cblock)
+		}
+		block.Positions = item.Positions
+	}
+	dec.blocks[0].Owner = owner
+	for i, item := range list {
+		block := dec.blocks[i]
+		if len(item.Code) > 0 {
+			block.Code = make(ByteCodes, len(item.Code))
+		}
+		for j, code := range item.Code {
+			if block.Code[j], err = dec.decodeCode(code); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// The value of the struct is map[string]interface{} which contains all fields of the struct.
+// The names of the fields are checked by the compiler and the values which are assigned to
+// the struct variables are converted to the struct so the maps from DBRow or JSONDecode can be used.
+// The struct is copied when it is assigned to the variable or passed to the function.
+
+var typeMap = reflect.TypeOf(map[string]interface{}{})
+
+// Field returns the field of the struct by its name
+func (info *StructInfo) Field(name string) *StructField {
+	for _, field := range info.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// lexemType returns the type of lexType lexem and the block of the struct if it is the struct type
+func lexemType(lexem *Lexem) (reflect.Type, *Block) {
+	if block, ok := lexem.Value.(*Block); ok {
+		return typeMap, block
+	}
+	return lexem.Value.(reflect.Type), nil
+}
+
+func zeroValue(vtype reflect.Type) interface{} {
+	switch vtype {
+	case typeMap:
+		return make(map[string]interface{})
+	case reflect.TypeOf([]interface{}{}):
+		return make([]interface{}, 0)
+	}
+	return reflect.New(vtype).Elem().Interface()
+}
+
+// newStruct returns the struct with the zero values of the fields
+func newStruct(block *Block) map[string]interface{} {
+	fields := block.Info.(*StructInfo).Fields
+	ret := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if field.Struct != nil {
+			ret[field.Name] = newStruct(field.Struct)
+		} else {
+			ret[field.Name] = zeroValue(field.Type)
+		}
+	}
+	return ret
+}
+
+func fieldValue(info *StructInfo, field *StructField, v interface{}) (interface{}, error) {
+	if field.Struct != nil {
+		return toStruct(field.Struct, v)
+	}
+	if v == nil {
+		return zeroValue(field.Type), nil
+	}
+	if reflect.TypeOf(v) == field.Type || field.Type.Kind() == reflect.Interface {
+		return v, nil
+	}
+	switch field.Type.String() {
+	case Decimal:
+		switch v.(type) {
+		case float64, int64, string:
+			return ValueToDecimal(v)
+		}
+	case `int64`:
+		switch val := v.(type) {
+		case float64:
+			if val == math.Trunc(val) {
+				return int64(val), nil
+			}
+		case string:
+			if ret, err := strconv.ParseInt(val, 10, 64); err == nil {
+				return ret, nil
+			}
+		}
+	case `float64`:
+		switch val := v.(type) {
+		case int64:
+			return float64(val), nil
+		case string:
+			if ret, err := strconv.ParseFloat(val, 64); err == nil {
+				return ret, nil
+			}
+		}
+	case `bool`:
+		if val, ok := v.(string); ok {
+			if ret, err := strconv.ParseBool(val); err == nil {
+				return ret, nil
+			}
+		}
+	default:
+		if reflect.TypeOf(v).ConvertibleTo(field.Type) && reflect.TypeOf(v).Kind() == field.Type.Kind() {
+			return reflect.ValueOf(v).Convert(field.Type).Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf(`cannot use %T as %s in field %s of struct %s`, v, typeName(field.Type),
+		field.Name, info.Name)
+}
+
+// toStruct converts the map to the struct. The missing fields get the zero values and
+// the unknown fields are not allowed.
+func toStruct(block *Block, v interface{}) (map[string]interface{}, error) {
+	info := block.Info.(*StructInfo)
+	if v == nil {
+		return newStruct(block), nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf(`cannot use %T as struct %s`, v, info.Name)
+	}
+	ret := newStruct(block)
+	for _, key := range rv.MapKeys() {
+		name := key.String()
+		field := info.Field(name)
+		if field == nil {
+			return nil, fmt.Errorf(`unknown field %s of struct %s`, name, info.Name)
+		}
+		val, err := fieldValue(info, field, rv.MapIndex(key).Interface())
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = val
+	}
+	return ret, nil
+}
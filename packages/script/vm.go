@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"reflect"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"unsafe"
@@ -74,10 +75,30 @@ var sysVars = map[string]struct{}{
 
 var ErrMemoryLimit = errors.New("Memory limit exceeded")
 
+// TraceItem is the position of the call in the stack trace of VM error
+type TraceItem struct {
+	Name   string `json:"name"`
+	Line   uint32 `json:"line"`
+	Column uint32 `json:"column"`
+}
+
 // VMError represents error of VM
 type VMError struct {
-	Type  string `json:"type"`
-	Error string `json:"error"`
+	Type  string      `json:"type"`
+	Error string      `json:"error"`
+	Trace []TraceItem `json:"trace,omitempty"`
+}
+
+// traceError is the runtime error which collects the stack trace of the calls
+// while it goes up through the functions and the contracts
+type traceError struct {
+	err      error
+	trace    []TraceItem
+	recorded bool // the position in the current function has been already added
+}
+
+func (e *traceError) Error() string {
+	return e.err.Error()
 }
 
 type blockStack struct {
@@ -87,17 +108,22 @@ type blockStack struct {
 
 // RunTime is needed for the execution of the byte-code
 type RunTime struct {
-	stack     []interface{}
-	blocks    []*blockStack
-	vars      []interface{}
-	extend    *map[string]interface{}
-	vm        *VM
-	cost      int64
-	err       error
-	unwrap    bool
-	callDepth uint16
-	mem       int64
-	memVars   map[interface{}]int64
+	stack      []interface{}
+	blocks     []*blockStack
+	vars       []interface{}
+	extend     *map[string]interface{}
+	vm         *VM
+	cost       int64
+	err        error
+	unwrap     bool
+	callDepth  uint16
+	mem        int64
+	memVars    map[interface{}]int64
+	memPaid    int64 // the charged memory in kilobytes
+	costs      *CostSchedule
+	debug      *Debugger
+	debugDepth int // the count of the calling frames of the parent runtimes
+	profile    *Profiler
 }
 
 func isSysVar(name string) bool {
@@ -230,6 +256,9 @@ func (rt *RunTime) callFunc(cmd uint16, obj *ObjInfo) (err error) {
 			if i == 0 && rt.vm.FuncCallsDB != nil {
 				if _, ok := rt.vm.FuncCallsDB[finfo.Name]; ok {
 					cost := iret.Int()
+					if rt.profile != nil {
+						rt.profile.query(cost)
+					}
 					if cost > rt.cost {
 						rt.cost = 0
 						rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Error("paid CPU resource is over")
@@ -437,6 +466,7 @@ func (vm *VM) RunInit(cost int64) *RunTime {
 		vm:      vm,
 		cost:    cost,
 		memVars: make(map[interface{}]int64),
+		costs:   defaultCosts,
 	}
 	return &rt
 }
@@ -451,8 +481,102 @@ func SetVMError(eType string, eText interface{}) error {
 	return fmt.Errorf(string(out))
 }
 
+// funcBlock returns the block of the function or the contract which contains the block
+func funcBlock(block *Block) *Block {
+	for block.Parent != nil && block.Type != ObjFunc && block.Type != ObjContract {
+		block = block.Parent
+	}
+	return block
+}
+
+// funcName returns the name of the function block. The functions of contracts
+// are named as contract.function
+func funcName(block *Block) (name string) {
+	if block.Type == ObjContract {
+		return block.Info.(*ContractInfo).Name
+	}
+	if block.Parent == nil {
+		return
+	}
+	for key, item := range block.Parent.Objects {
+		if item.Type == ObjFunc && item.Value.(*Block) == block {
+			name = key
+			break
+		}
+	}
+	if block.Parent.Type == ObjContract {
+		name = block.Parent.Info.(*ContractInfo).Name + `.` + name
+	}
+	return
+}
+
+// traceErr adds the position of the failed command to the stack trace of the error.
+// Only the innermost position is added for every called function.
+func traceErr(err error, block *Block, offset int) error {
+	terr, ok := err.(*traceError)
+	if !ok {
+		terr = &traceError{err: err}
+	}
+	if !terr.recorded {
+		pos := block.Position(offset)
+		terr.trace = append(terr.trace, TraceItem{Name: funcName(funcBlock(block)),
+			Line: pos.Line, Column: pos.Column})
+		terr.recorded = true
+	}
+	if block.Type == ObjFunc {
+		terr.recorded = false
+	}
+	return terr
+}
+
+// ErrorTrace returns the error in the format of VMError with the stack trace of the calls.
+// If err is not VMError then it gets eType type. The trace is cut from the outer calls
+// so that the error fits into maxLen bytes.
+func ErrorTrace(err error, eType string, maxLen int) error {
+	var (
+		vmerr VMError
+		trace []TraceItem
+	)
+	if terr, ok := err.(*traceError); ok {
+		trace = terr.trace
+	}
+	eText := err.Error()
+	if !strings.HasPrefix(eText, `{`) || json.Unmarshal([]byte(eText), &vmerr) != nil {
+		vmerr = VMError{Type: eType, Error: eText}
+	}
+	if len(trace) == 0 {
+		if strings.HasPrefix(eText, `{`) {
+			return err
+		}
+		return SetVMError(eType, eText)
+	}
+	for vmerr.Trace = trace; len(vmerr.Trace) > 0; vmerr.Trace = vmerr.Trace[:len(vmerr.Trace)-1] {
+		out, err := json.Marshal(&vmerr)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling VMError")
+			break
+		}
+		if maxLen <= 0 || len(out) <= maxLen {
+			return errors.New(string(out))
+		}
+	}
+	return SetVMError(vmerr.Type, vmerr.Error)
+}
+
 // RunCode executes Block
 func (rt *RunTime) RunCode(block *Block) (status int, err error) {
+	var ci int
+	defer func() {
+		if err != nil {
+			err = traceErr(err, block, ci)
+		}
+	}()
+	if rt.profile != nil && block.Type == ObjFunc {
+		depth := rt.profile.enter(rt.profile.blockName(block), rt.cost)
+		defer func() {
+			rt.profile.leave(depth, rt.cost)
+		}()
+	}
 	top := make([]interface{}, 8)
 	rt.blocks = append(rt.blocks, &blockStack{block, len(rt.vars)})
 	var namemap map[string][]interface{}
@@ -465,10 +589,12 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 	start := len(rt.stack)
 	varoff := len(rt.vars)
 	for vkey, vpar := range block.Vars {
-		rt.cost--
+		rt.cost -= rt.costs.Command
 		var value interface{}
 		if block.Type == ObjFunc && vkey < len(block.Info.(*FuncInfo).Params) {
 			value = rt.stack[start-len(block.Info.(*FuncInfo).Params)+vkey]
+		} else if pars, ok := block.Info.(*ParamsInfo); ok && vkey < pars.Count {
+			value = rt.stack[start-pars.Count+vkey]
 		} else {
 			value = reflect.New(vpar).Elem().Interface()
 			if vpar == reflect.TypeOf(map[string]interface{}{}) {
@@ -477,6 +603,12 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 				value = make([]interface{}, 0, len(rt.vars)+1)
 			}
 		}
+		if sblock := block.VarStructs[vkey]; sblock != nil {
+			if value, err = toStruct(sblock, value); err != nil {
+				rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("converting value to struct")
+				return 0, err
+			}
+		}
 		rt.addVar(value)
 	}
 	if namemap != nil {
@@ -497,15 +629,19 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 	}
 	if block.Type == ObjFunc {
 		start -= len(block.Info.(*FuncInfo).Params)
+	} else if pars, ok := block.Info.(*ParamsInfo); ok {
+		start -= pars.Count
 	}
 	var (
 		assign []*VarInfo
 		tmpInt int64
 		tmpDec decimal.Decimal
+		caught map[string]interface{}
 	)
 	labels := make([]int, 0)
-	for ci := 0; ci < len(block.Code); ci++ {
-		rt.cost--
+	for ci = 0; ci < len(block.Code); ci++ {
+		rt.cost -= rt.costs.opcode(block.Code[ci].Cmd)
+		rt.chargeMemory()
 		if rt.cost <= 0 {
 			rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warn("paid CPU resource is over")
 			return 0, fmt.Errorf(`paid CPU resource is over`)
@@ -516,6 +652,12 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 			return 0, ErrMemoryLimit
 		}
 
+		if rt.debug != nil {
+			if err = rt.debug.trace(rt, block, ci); err != nil {
+				return 0, err
+			}
+		}
+
 		cmd := block.Code[ci]
 		var bin interface{}
 		size := len(rt.stack)
@@ -556,6 +698,20 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 					break
 				}
 			}
+		case cmdFor:
+			val := rt.stack[len(rt.stack)-1]
+			rt.stack = rt.stack[:len(rt.stack)-1]
+			status, err = rt.runFor(cmd.Value.(*Block), val)
+		case cmdTry:
+			status, caught, err = rt.runTry(cmd.Value.(*Block))
+		case cmdCatch:
+			if caught != nil {
+				if pars, ok := cmd.Value.(*Block).Info.(*ParamsInfo); ok && pars.Count > 0 {
+					rt.stack = append(rt.stack, caught)
+				}
+				caught = nil
+				status, err = rt.RunCode(cmd.Value.(*Block))
+			}
 		case cmdLabel:
 			labels = append(labels, ci)
 		case cmdContinue:
@@ -581,6 +737,15 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 					for i = len(rt.blocks) - 1; i >= 0; i-- {
 						if item.Owner == rt.blocks[i].Block {
 							k := rt.blocks[i].Offset + item.Obj.Value.(int)
+							if sblock := rt.blocks[i].Block.VarStructs[item.Obj.Value.(int)]; sblock != nil {
+								v, err := toStruct(sblock, rt.stack[len(rt.stack)-count+ivar])
+								if err != nil {
+									rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("converting value to struct")
+									return 0, err
+								}
+								rt.setVar(k, v)
+								break
+							}
 							switch rt.blocks[i].Block.Vars[item.Obj.Value.(int)].String() {
 							case Decimal:
 								v, err := ValueToDecimal(rt.stack[len(rt.stack)-count+ivar])
@@ -605,7 +770,11 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 			} else if cmd.Value.(uint32) == keyInfo {
 				eType = msgInfo
 			}
-			err = SetVMError(eType, rt.stack[len(rt.stack)-1])
+			if cmd.Value.(uint32) == keyThrow {
+				err = throwError(rt.stack[len(rt.stack)-1])
+			} else {
+				err = SetVMError(eType, rt.stack[len(rt.stack)-1])
+			}
 		case cmdFuncName:
 			ifunc := cmd.Value.(FuncNameCmd)
 			mapoff := len(rt.stack) - 1 - ifunc.Count
@@ -627,24 +796,30 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 			rt.stack = rt.stack[:mapoff+1]
 			continue
 		case cmdCallVari, cmdCall:
+			depth := -1
 			if cmd.Value.(*ObjInfo).Type == ObjExtFunc {
 				finfo := cmd.Value.(*ObjInfo).Value.(ExtFuncInfo)
-				if rt.vm.ExtCost != nil {
-					cost := rt.vm.ExtCost(finfo.Name)
+				if rt.profile != nil {
+					depth = rt.profile.enter(finfo.Name, rt.cost)
+				}
+				if cost, ok := rt.extFuncCost(finfo.Name); ok {
 					if cost > rt.cost {
 						rt.cost = 0
 						rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warning("paid CPU resource is over")
 						return 0, fmt.Errorf(`paid CPU resource is over`)
 					} else if cost == -1 {
-						rt.cost -= CostCall
+						rt.cost -= rt.costs.Call
 					} else {
 						rt.cost -= cost
 					}
 				}
 			} else {
-				rt.cost -= CostCall
+				rt.cost -= rt.costs.Call
 			}
 			err = rt.callFunc(cmd.Cmd, cmd.Value.(*ObjInfo))
+			if depth >= 0 {
+				rt.profile.leave(depth, rt.cost)
+			}
 
 		case cmdVar:
 			ivar := cmd.Value.(*VarInfo)
@@ -661,7 +836,7 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 			}
 		case cmdExtend, cmdCallExtend:
 			if val, ok := (*rt.extend)[cmd.Value.(string)]; ok {
-				rt.cost -= CostExtend
+				rt.cost -= rt.costs.Extend
 				if cmd.Cmd == cmdCallExtend {
 					err = rt.extendFunc(cmd.Value.(string))
 					if err != nil {
@@ -1010,7 +1185,7 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 					bin = top[1].(decimal.Decimal).Cmp(tmpDec) == 0
 				}
 			}
-			if cmd.Cmd == cmdNotEq {
+			if cmd.Cmd == cmdNotEq && err == nil {
 				bin = !bin.(bool)
 			}
 		case cmdLess, cmdNotLess:
@@ -1050,7 +1225,7 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 				}
 				bin = top[1].(decimal.Decimal).Cmp(tmpDec) < 0
 			}
-			if cmd.Cmd == cmdNotLess {
+			if cmd.Cmd == cmdNotLess && err == nil {
 				bin = !bin.(bool)
 			}
 		case cmdGreat, cmdNotGreat:
@@ -1090,7 +1265,7 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 				}
 				bin = top[1].(decimal.Decimal).Cmp(tmpDec) > 0
 			}
-			if cmd.Cmd == cmdNotGreat {
+			if cmd.Cmd == cmdNotGreat && err == nil {
 				bin = !bin.(bool)
 			}
 		default:
@@ -1129,6 +1304,130 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 	return
 }
 
+// runFor executes the body of for ... in loop for every item of the array or the map.
+// The keys of the map are sorted so the order of the iterations is always the same.
+func (rt *RunTime) runFor(block *Block, val interface{}) (status int, err error) {
+	var keys, values []interface{}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Invalid:
+	case reflect.Slice:
+		for i := 0; i < rv.Len(); i++ {
+			keys = append(keys, int64(i))
+			values = append(values, rv.Index(i).Interface())
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return 0, fmt.Errorf(eMapIndex, rv.Type().Key().String())
+		}
+		mapKeys := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			mapKeys = append(mapKeys, key.String())
+		}
+		sort.Strings(mapKeys)
+		for _, key := range mapKeys {
+			keys = append(keys, key)
+			values = append(values, rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).Interface())
+		}
+		// the single loop variable of the map is the key
+		if block.Info.(*ParamsInfo).Count == 1 {
+			values = keys
+		}
+	default:
+		itype := reflect.TypeOf(val).String()
+		rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "vm_type": itype}).Error("type does not support iteration")
+		return 0, fmt.Errorf(`Type %s doesn't support iteration`, itype)
+	}
+	for i := range values {
+		rt.cost -= rt.costs.Command
+		if rt.cost <= 0 {
+			rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warn("paid CPU resource is over")
+			return 0, fmt.Errorf(`paid CPU resource is over`)
+		}
+		if block.Info.(*ParamsInfo).Count == 2 {
+			rt.stack = append(rt.stack, keys[i])
+		}
+		rt.stack = append(rt.stack, values[i])
+		if status, err = rt.RunCode(block); err != nil || status == statusReturn {
+			return
+		}
+		if status == statusBreak {
+			break
+		}
+	}
+	return statusNormal, nil
+}
+
+// isCatchable returns true if the error can be caught by try block. The errors of
+// the exceeded resources and the stop of the debugger cannot be caught.
+func (rt *RunTime) isCatchable(err error) bool {
+	if terr, ok := err.(*traceError); ok {
+		err = terr.err
+	}
+	return rt.cost > 0 && rt.mem <= memoryLimit && err != ErrMemoryLimit && err != errDebugStop
+}
+
+// errorValue converts the caught error to the map with type and error fields
+func errorValue(err error) map[string]interface{} {
+	var vmerr VMError
+
+	if terr, ok := err.(*traceError); ok {
+		err = terr.err
+	}
+	eText := err.Error()
+	if !strings.HasPrefix(eText, `{`) || json.Unmarshal([]byte(eText), &vmerr) != nil || len(vmerr.Type) == 0 {
+		vmerr = VMError{Type: msgPanic, Error: eText}
+	}
+	return map[string]interface{}{`type`: vmerr.Type, `error`: vmerr.Error}
+}
+
+// throwError returns the error for throw statement. The caught error is thrown
+// again with its type, any other value is thrown as error.
+func throwError(value interface{}) error {
+	if caught, ok := value.(map[string]interface{}); ok {
+		eType, okType := caught[`type`].(string)
+		if eText, okText := caught[`error`]; okType && okText && len(eType) > 0 {
+			return SetVMError(eType, eText)
+		}
+	}
+	return SetVMError(msgError, value)
+}
+
+// runTry executes the try block. If the catchable error occurs then the changes of the database
+// made in the block are rolled back and the error is returned as caught value.
+// The savepoint doesn't roll back the memory so Savepointer must disallow such changes in the block.
+func (rt *RunTime) runTry(block *Block) (status int, caught map[string]interface{}, err error) {
+	var id int
+
+	sp, ok := (*rt.extend)[`sc`].(Savepointer)
+	if ok {
+		if id, err = sp.Savepoint(); err != nil {
+			return
+		}
+	}
+	size, blocks := len(rt.stack), len(rt.blocks)
+	status, err = rt.RunCode(block)
+	if err == nil {
+		if sp != nil {
+			err = sp.ReleaseSavepoint(id)
+		}
+		return
+	}
+	if !rt.isCatchable(err) {
+		return
+	}
+	if sp != nil {
+		if errRoll := sp.RollbackSavepoint(id); errRoll != nil {
+			return 0, nil, errRoll
+		}
+	}
+	rt.stack = rt.stack[:size]
+	rt.blocks = rt.blocks[:blocks]
+	rt.unwrap = false
+	return statusNormal, errorValue(err), nil
+}
+
 // Run executes Block with the specified parameters and extended variables and functions
 func (rt *RunTime) Run(block *Block, params []interface{}, extend *map[string]interface{}) (ret []interface{}, err error) {
 	defer func() {
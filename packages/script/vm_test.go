@@ -1,6 +1,7 @@
 package script
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -25,3 +26,194 @@ func TestCalcMem(t *testing.T) {
 		assert.Equal(t, v.mem, calcMem(v.v))
 	}
 }
+
+func TestDebugger(t *testing.T) {
+	vm := NewVM()
+	err := vm.Compile([]rune(`func double(val int) int {
+			return val * 2
+		}
+		func result() int {
+			var a int
+			a = 10
+			a = double(a)
+			return a + 1
+		}`), &OwnerInfo{StateID: 1, Active: true, TableID: 1})
+	assert.NoError(t, err)
+	block := vm.getObjByName(`result`).Value.(*Block)
+
+	var states []*DebugState
+	rt := vm.RunInit(CostDefault)
+	rt.SetDebugger(NewDebugger(DebugContinue, []Breakpoint{{Name: `result`, Line: 7}},
+		func(state *DebugState) DebugAction {
+			states = append(states, state)
+			return DebugContinue
+		}))
+	ret, err := rt.Run(block, nil, &map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(21)}, ret)
+	if assert.Len(t, states, 1) {
+		assert.Equal(t, `result`, states[0].Name)
+		assert.Equal(t, uint32(7), states[0].Line)
+		assert.Equal(t, int64(10), states[0].Vars[`a`])
+	}
+
+	// Step into the called function and stop the execution there
+	states = states[:0]
+	rt = vm.RunInit(CostDefault)
+	rt.SetDebugger(NewDebugger(DebugContinue, []Breakpoint{{Name: `result`, Line: 7}},
+		func(state *DebugState) DebugAction {
+			states = append(states, state)
+			if state.Name == `double` {
+				return DebugStop
+			}
+			return DebugStepInto
+		}))
+	_, err = rt.Run(block, nil, &map[string]interface{}{})
+	assert.EqualError(t, err, errDebugStop.Error())
+	last := states[len(states)-1]
+	assert.Equal(t, `double`, last.Name)
+	assert.Equal(t, uint32(2), last.Line)
+	assert.Equal(t, 2, last.Depth)
+	assert.Equal(t, int64(10), last.Vars[`val`])
+
+	// Step over the call doesn't pause inside of the function
+	states = states[:0]
+	rt = vm.RunInit(CostDefault)
+	rt.SetDebugger(NewDebugger(DebugContinue, []Breakpoint{{Name: `result`, Line: 7}},
+		func(state *DebugState) DebugAction {
+			states = append(states, state)
+			return DebugStepOver
+		}))
+	_, err = rt.Run(block, nil, &map[string]interface{}{})
+	assert.NoError(t, err)
+	for _, state := range states {
+		assert.Equal(t, `result`, state.Name)
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	vm := NewVM()
+	vm.Extend(&ExtendData{Objects: map[string]interface{}{
+		"Query": func(val int64) (int64, int64) { return 50, val },
+	}})
+	vm.FuncCallsDB = map[string]struct{}{`Query`: {}}
+	err := vm.Compile([]rune(`func double(val int) int {
+			return Query(val) * 2
+		}
+		func result() int {
+			var a int
+			a = double(10)
+			return double(a) + 1
+		}`), &OwnerInfo{StateID: 1, Active: true, TableID: 1})
+	assert.NoError(t, err)
+
+	profiler := NewProfiler()
+	rt := vm.RunInit(CostDefault)
+	rt.SetProfiler(profiler)
+	ret, err := rt.Run(vm.getObjByName(`result`).Value.(*Block), nil, &map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(41)}, ret)
+
+	profile := profiler.Profile()
+	items := make(map[string]ProfileItem)
+	var self int64
+	for _, item := range profile.Items {
+		items[item.Name] = item
+		self += item.Self
+	}
+	assert.Equal(t, `result`, profile.Items[0].Name)
+	assert.Equal(t, int64(1), items[`result`].Calls)
+	assert.Equal(t, int64(2), items[`double`].Calls)
+	assert.Equal(t, int64(2), items[`Query`].Calls)
+	assert.Equal(t, int64(100), items[`Query`].Query)
+	assert.True(t, items[`Query`].Self >= 100)
+	assert.Equal(t, items[`double`].Self+items[`Query`].Total, items[`double`].Total)
+	assert.Equal(t, items[`result`].Total, self)
+	assert.Equal(t, CostDefault-rt.Cost(), items[`result`].Total)
+	assert.Len(t, profile.Folded, 3)
+	assert.Contains(t, profile.String(), `result;double;Query `)
+}
+
+func TestErrorTrace(t *testing.T) {
+	vm := NewVM()
+	err := vm.Compile([]rune(`func div(a b int) int {
+			if b == 0 {
+				return a / b
+			}
+			return 0
+		}
+		func result() int {
+			var a int
+			a = 10
+			return div(a, 0)
+		}`), &OwnerInfo{StateID: 1, Active: true, TableID: 1})
+	assert.NoError(t, err)
+
+	_, err = vm.Call(`result`, nil, &map[string]interface{}{})
+	assert.EqualError(t, err, errDivZero.Error())
+
+	var vmerr VMError
+	assert.NoError(t, json.Unmarshal([]byte(ErrorTrace(err, `panic`, 0).Error()), &vmerr))
+	assert.Equal(t, VMError{Type: `panic`, Error: errDivZero.Error(), Trace: []TraceItem{
+		{Name: `div`, Line: 3, Column: 6},
+		{Name: `result`, Line: 10, Column: 5},
+	}}, vmerr)
+
+	// The outer calls are cut if the error is too long
+	vmerr = VMError{}
+	assert.NoError(t, json.Unmarshal([]byte(ErrorTrace(err, `panic`, 100).Error()), &vmerr))
+	assert.Len(t, vmerr.Trace, 1)
+}
+
+func TestCostSchedule(t *testing.T) {
+	vm := NewVM()
+	err := vm.Compile([]rune(`func double(val int) int {
+			return val * 2
+		}
+		func result() int {
+			var a, i int
+			var s string
+			a = double(100)
+			while i < a {
+				s = s + "0123456789abcdef"
+				i = i + 1
+			}
+			return i
+		}`), &OwnerInfo{StateID: 1, Active: true, TableID: 1})
+	assert.NoError(t, err)
+	block := vm.getObjByName(`result`).Value.(*Block)
+	run := func(costs *CostSchedule) int64 {
+		rt := vm.RunInit(CostDefault)
+		rt.SetCostSchedule(costs)
+		_, err := rt.Run(block, nil, &map[string]interface{}{})
+		assert.NoError(t, err)
+		return CostDefault - rt.Cost()
+	}
+	cost := run(nil)
+
+	list, err := ParseCostSchedules(`[{"block": 10, "schedule": {"call": 150}},
+		{"block": 20, "schedule": {"opcodes": {"mul": 11}}},
+		{"block": 30, "schedule": {"memory": 5}}]`)
+	assert.NoError(t, err)
+	assert.Nil(t, list.Get(5))
+	assert.Equal(t, cost, run(list.Get(5)))
+	assert.Equal(t, cost+100, run(list.Get(15)))
+	assert.Equal(t, cost+10, run(list.Get(20)))
+	assert.True(t, run(list.Get(100)) > cost)
+
+	list, err = ParseCostSchedules(`[{"block": 10, "schedule": {"queries": {"select": 5, "join": 0}}}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{`select`: 5, `join`: 0}, list.Get(10).Queries)
+
+	for _, item := range []string{
+		`[{"block": 10, "schedule": {"command": 0}}]`,
+		`[{"block": 10, "schedule": {"opcodes": {"unknown": 1}}}]`,
+		`[{"block": 10, "schedule": {"price": 1}}]`,
+		`[{"block": 10, "schedule": {"queries": {"drop": 1}}}]`,
+		`[{"block": 10, "schedule": {"queries": {"select": -1}}}]`,
+		`[{"block": 10, "schedule": {}}, {"block": 5, "schedule": {}}]`,
+	} {
+		_, err = ParseCostSchedules(item)
+		assert.Error(t, err, item)
+	}
+}
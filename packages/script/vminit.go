@@ -55,6 +55,8 @@ const (
 	ObjVar
 	// ObjExtend is an extended variable. $myvar
 	ObjExtend
+	// ObjStruct is a user-defined struct type. struct mystruct {...}
+	ObjStruct
 
 	// CostCall is the cost of the function calling
 	CostCall = 50
@@ -103,9 +105,12 @@ type ContractInfo struct {
 	ID       uint32
 	Name     string
 	Owner    *OwnerInfo
-	Used     map[string]bool // Called contracts
+	Used     map[string]bool // Called contracts and imported libraries
 	Tx       *[]*FieldInfo
 	Settings map[string]interface{}
+	Library  bool // The library contract which contains only functions
+
+	imports []*Block // Imported libraries, it is used only by the compiler
 }
 
 // FuncNameCmd for cmdFuncName
@@ -130,6 +135,27 @@ type FuncInfo struct {
 	ID       uint32
 }
 
+// ParamsInfo contains the information of the block whose first variables get the values
+// from the stack such as the body of for ... in loop and the catch block. Count is the count
+// of these variables.
+type ParamsInfo struct {
+	Count int
+}
+
+// StructField is the field of the user-defined struct. Struct is the block of the struct
+// if the field has the struct type.
+type StructField struct {
+	Name   string
+	Type   reflect.Type
+	Struct *Block
+}
+
+// StructInfo contains the information of the user-defined struct
+type StructInfo struct {
+	Name   string
+	Fields []*StructField
+}
+
 // VarInfo contains the variable information
 type VarInfo struct {
 	Obj   *ObjInfo
@@ -156,18 +182,35 @@ type OwnerInfo struct {
 	TableID  int64  `json:"tableid"`
 	WalletID int64  `json:"walletid"`
 	TokenID  int64  `json:"tokenid"`
+	Version  int64  `json:"version"` // the version of the source, it is zero for the unversioned contracts
+}
+
+// Position is the position of the byte-code command in the source code
+type Position struct {
+	Line   uint32 `json:"line"`
+	Column uint32 `json:"column"`
 }
 
 // Block contains all information about compiled block {...} and its children
 type Block struct {
-	Objects  map[string]*ObjInfo
-	Type     int
-	Owner    *OwnerInfo
-	Info     interface{}
-	Parent   *Block
-	Vars     []reflect.Type
-	Code     ByteCodes
-	Children Blocks
+	Objects    map[string]*ObjInfo
+	Type       int
+	Owner      *OwnerInfo
+	Info       interface{}
+	Parent     *Block
+	Vars       []reflect.Type
+	VarStructs map[int]*Block // the blocks of the struct types of the variables by their offsets
+	Code       ByteCodes
+	Positions  []Position // Positions[i] is the position of Code[i] in the source code
+	Children   Blocks
+}
+
+// Position returns the position of the command with the specified offset in the source code
+func (block *Block) Position(offset int) Position {
+	if offset >= 0 && offset < len(block.Positions) {
+		return block.Positions[offset]
+	}
+	return Position{}
 }
 
 // Blocks is a slice of blocks
@@ -178,7 +221,9 @@ type VM struct {
 	Block
 	ExtCost     func(string) int64
 	FuncCallsDB map[string]struct{}
-	Extern      bool // extern mode of compilation
+	ParamCheck  func(name string, index int, value string) error // checks the constant parameters for the type checker
+	Extern      bool                                             // extern mode of compilation
+	Optimize    bool                                             // the compiled byte-code is optimized, it changes the cost of the execution
 	logger      *log.Entry
 }
 
@@ -193,6 +238,25 @@ type Stacker interface {
 	AppendStack(contract string)
 }
 
+// Multisigner represents interface for the multi-signature contracts. It returns the name of
+// the contract which has been approved by the signers and is being run.
+type Multisigner interface {
+	MultisigApproved() string
+}
+
+// Savepointer represents interface for the rollback of the changes which have been made in the try block
+type Savepointer interface {
+	Savepoint() (int, error)
+	RollbackSavepoint(id int) error
+	ReleaseSavepoint(id int) error
+}
+
+// VersionLoader represents interface for loading the pinned version of the contract like @1Name#v3.
+// The pinned versions are not kept in the objects of the virtual machine.
+type VersionLoader interface {
+	LoadContractVersion(name string) (*ObjInfo, error)
+}
+
 // ParseContract gets a state identifier and the name of the contract from the full name like @[id]name
 func ParseContract(in string) (id uint64, name string) {
 	var err error
@@ -208,6 +272,10 @@ func ParseContract(in string) (id uint64, name string) {
 	return
 }
 
+// MultisigThreshold is the setting of the contract which defines the count of the approvals
+// which are required to run its action
+const MultisigThreshold = `multisig_threshold`
+
 // ExecContract runs the name contract where txs contains the list of parameters and
 // params are the values of parameters
 func ExecContract(rt *RunTime, name, txs string, params ...interface{}) (interface{}, error) {
@@ -217,8 +285,25 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) (interfa
 		log.WithFields(log.Fields{"contract_name": name, "type": consts.ContractError}).Error("unknown contract")
 		return nil, fmt.Errorf(eUnknownContract, name)
 	}
+	return execContract(rt, contract, name, txs, params...)
+}
+
+func execContract(rt *RunTime, contract *ObjInfo, name, txs string, params ...interface{}) (interface{}, error) {
 	logger := log.WithFields(log.Fields{"contract_name": name, "type": consts.ContractError})
 	cblock := contract.Value.(*Block)
+	if cblock.Info.(*ContractInfo).Library {
+		logger.Error("library is called as contract")
+		return nil, fmt.Errorf(eLibraryCall, name)
+	}
+	// the pinned version is run under the name of the contract
+	name = cblock.Info.(*ContractInfo).Name
+	// the multi-signature contract can be called only when its call has been approved
+	if _, ok := cblock.Info.(*ContractInfo).Settings[MultisigThreshold]; ok {
+		if ms, ok := (*rt.extend)[`sc`].(Multisigner); ok && ms.MultisigApproved() != name {
+			logger.Error("multi-signature contract is called without approvals")
+			return nil, fmt.Errorf(eMultisigCall, name)
+		}
+	}
 	parnames := make(map[string]bool)
 	pars := strings.Split(txs, `,`)
 	if len(pars) != len(params) {
@@ -243,6 +328,22 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) (interfa
 		prevExtend[key] = item
 		delete(*rt.extend, key)
 	}
+	prevthis := (*rt.extend)[`this_contract`]
+	prevparent := (*rt.extend)[`parent`]
+	// the extended variables are restored even if the contract fails because the error can be caught
+	defer func() {
+		(*rt.extend)[`parent`] = prevparent
+		(*rt.extend)[`this_contract`] = prevthis
+		for key := range *rt.extend {
+			if isSysVar(key) {
+				continue
+			}
+			delete(*rt.extend, key)
+		}
+		for key, item := range prevExtend {
+			(*rt.extend)[key] = item
+		}
+	}()
 
 	var isSignature bool
 	if cblock.Info.(*ContractInfo).Tx != nil {
@@ -262,11 +363,9 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) (interfa
 	for i, ipar := range pars {
 		(*rt.extend)[ipar] = params[i]
 	}
-	prevthis := (*rt.extend)[`this_contract`]
 	_, nameContract := ParseContract(name)
 	(*rt.extend)[`this_contract`] = nameContract
 
-	prevparent := (*rt.extend)[`parent`]
 	parent := ``
 	for i := len(rt.blocks) - 1; i >= 0; i-- {
 		if rt.blocks[i].Block.Type == ObjFunc && rt.blocks[i].Block.Parent != nil &&
@@ -284,11 +383,11 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) (interfa
 			break
 		}
 	}
-	rt.cost -= CostContract
+	rt.cost -= rt.costs.Contract
 
-	var stack Stacker
-	if stack, ok = (*rt.extend)["sc"].(Stacker); ok {
+	if stack, ok := (*rt.extend)["sc"].(Stacker); ok {
 		stack.AppendStack(name)
+		defer stack.AppendStack("")
 	}
 	if (*rt.extend)[`sc`] != nil && isSignature {
 		obj := rt.vm.Objects[`check_signature`]
@@ -301,6 +400,9 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) (interfa
 	for _, method := range []string{`init`, `conditions`, `action`} {
 		if block, ok := (*cblock).Objects[method]; ok && block.Type == ObjFunc {
 			rtemp := rt.vm.RunInit(rt.cost)
+			rtemp.costs = rt.costs
+			rtemp.debug, rtemp.debugDepth = rt.debug, rt.frameDepth()
+			rtemp.profile = rt.profile
 			(*rt.extend)[`parent`] = parent
 			_, err := rtemp.Run(block.Value.(*Block), nil, rt.extend)
 			rt.cost = rtemp.cost
@@ -310,25 +412,7 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) (interfa
 			}
 		}
 	}
-	if stack != nil {
-		stack.AppendStack("")
-	}
-	(*rt.extend)[`parent`] = prevparent
-	(*rt.extend)[`this_contract`] = prevthis
-
-	result := (*rt.extend)[`result`]
-	for key := range *rt.extend {
-		if isSysVar(key) {
-			continue
-		}
-		delete(*rt.extend, key)
-	}
-
-	for key, item := range prevExtend {
-		(*rt.extend)[key] = item
-	}
-
-	return result, nil
+	return (*rt.extend)[`result`], nil
 }
 
 // NewVM creates a new virtual machine
@@ -469,6 +553,13 @@ func ExContract(rt *RunTime, state uint32, name string, params map[string]interf
 
 	name = StateName(state, name)
 	contract, ok := rt.vm.Objects[name]
+	if loader, isLoader := (*rt.extend)[`sc`].(VersionLoader); !ok && isLoader && strings.Contains(name, `#v`) {
+		var err error
+		if contract, err = loader.LoadContractVersion(name); err != nil {
+			return nil, err
+		}
+		ok = contract != nil
+	}
 	if !ok {
 		log.WithFields(log.Fields{"contract_name": name, "type": consts.ContractError}).Error("unknown contract")
 		return nil, fmt.Errorf(eUnknownContract, name)
@@ -497,7 +588,7 @@ func ExContract(rt *RunTime, state uint32, name string, params map[string]interf
 	if len(vals) == 0 {
 		vals = append(vals, ``)
 	}
-	return ExecContract(rt, name, strings.Join(names, `,`), vals...)
+	return execContract(rt, contract, name, strings.Join(names, `,`), vals...)
 }
 
 // GetSettings returns the value of the parameter
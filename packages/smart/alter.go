@@ -0,0 +1,375 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// converterToken splits the converter expression into the string literals, the names, the numbers
+// and the operators
+var converterToken = regexp.MustCompile(`'(?:[^']|'')*'|[A-Za-z_]\w*|\d+(?:\.\d+)?|::|\|\||<=|>=|<>|!=|\S`)
+
+// converterFuncs are the pure functions which can be used in the converter expression of ChangeColumnType
+var converterFuncs = map[string]bool{
+	`abs`: true, `ceil`: true, `floor`: true, `round`: true, `trunc`: true, `coalesce`: true,
+	`nullif`: true, `lower`: true, `upper`: true, `trim`: true, `substr`: true, `length`: true,
+	`replace`: true, `concat`: true, `encode`: true, `decode`: true, `greatest`: true, `least`: true,
+	`to_date`: true,
+}
+
+// converterTypes are the types which can be used in the casts of the converter expression. The date
+// and time types are not allowed because the casts of the strings like 'now' depend on the time of the node.
+var converterTypes = map[string]bool{
+	`numeric`: true, `decimal`: true, `varchar`: true, `char`: true, `character`: true, `text`: true,
+	`bigint`: true, `integer`: true, `int`: true, `smallint`: true, `boolean`: true, `bool`: true,
+	`bytea`: true, `uuid`: true,
+}
+
+// converterWords are the keywords which can be used in the converter expression
+var converterWords = map[string]bool{
+	`and`: true, `or`: true, `not`: true, `is`: true, `null`: true, `true`: true, `false`: true,
+}
+
+// converterDenied are the keywords which can't be the names of the columns in the converter expression
+// because they have the different values on the nodes or read the other data
+var converterDenied = map[string]bool{
+	`select`: true, `current_date`: true, `current_time`: true, `current_timestamp`: true,
+	`localtime`: true, `localtimestamp`: true, `current_user`: true, `current_role`: true,
+	`current_catalog`: true, `current_schema`: true, `session_user`: true, `user`: true,
+}
+
+// converterTime matches the special values of the dates which depend on the time of the node
+var converterTime = regexp.MustCompile(`(?i)now|today|tomorrow|yesterday`)
+
+// checkConverter checks the converter expression of ChangeColumnType. The expression can use
+// the columns of the table, the string and number literals, the operators, the casts to converterTypes
+// and the functions of converterFuncs.
+func checkConverter(expr string, columns map[string]string) error {
+	if strings.Contains(expr, `--`) || strings.Contains(expr, `/*`) {
+		return fmt.Errorf(`converter %s is not valid`, expr)
+	}
+	tokens := converterToken.FindAllString(expr, -1)
+	for i, token := range tokens {
+		ch := token[0]
+		switch {
+		case ch == '\'':
+			if len(token) < 2 || token[len(token)-1] != '\'' {
+				return fmt.Errorf(`converter %s is not valid`, expr)
+			}
+			if converterTime.MatchString(token) {
+				return fmt.Errorf(`%s can not be used in converter`, token)
+			}
+		case ch >= '0' && ch <= '9':
+			continue
+		case ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z'):
+			word := strings.ToLower(token)
+			if converterDenied[word] {
+				return fmt.Errorf(`%s can not be used in converter`, token)
+			}
+			if i > 0 && tokens[i-1] == `::` {
+				if !converterTypes[word] {
+					return fmt.Errorf(`type %s can not be used in converter`, token)
+				}
+			} else if i+1 < len(tokens) && tokens[i+1] == `(` {
+				if !converterFuncs[word] {
+					return fmt.Errorf(`function %s can not be used in converter`, token)
+				}
+			} else if _, ok := columns[word]; !ok && !converterWords[word] {
+				return fmt.Errorf(`%s can not be used in converter`, token)
+			}
+		case len(token) == 2 && strings.IndexByte(`:|<>!`, ch) >= 0:
+			continue
+		case len(token) != 1 || strings.IndexByte(`,+-*/%()<>=`, ch) < 0:
+			return fmt.Errorf(`converter %s is not valid`, expr)
+		}
+	}
+	return nil
+}
+
+// systemTables are the tables of the ecosystems which are used by the platform. They can't be renamed
+// and their columns can't be changed.
+var systemTables = map[string]bool{
+	`keys`: true, `history`: true, `languages`: true, `sections`: true, `menu`: true, `pages`: true,
+	`blocks`: true, `signatures`: true, `contracts`: true, `parameters`: true, `app_params`: true,
+	`tables`: true, `notifications`: true, `roles`: true, `roles_participants`: true, `members`: true,
+	`applications`: true, `binaries`: true, `buffer_data`: true, `ecosystems`: true,
+	`system_parameters`: true, `delayed_contracts`: true, `metrics`: true, `bad_blocks`: true,
+	`node_ban_logs`: true,
+}
+
+// checkSystemTable returns an error if the table is the system table
+func checkSystemTable(tblname string) error {
+	if _, name := PrefixName(tblname); systemTables[name] {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "table": tblname}).Error("changing system table")
+		return fmt.Errorf(`system table %s can not be changed`, tblname)
+	}
+	return nil
+}
+
+// accessAlter checks the alter permission of the table. The new_column permission is used
+// if the table doesn't have the alter permission.
+func (sc *SmartContract) accessAlter(tblname string) error {
+	if sc.FullAccess {
+		return nil
+	}
+	perm, err := sc.AccessTablePerm(tblname, `alter`)
+	if err != nil {
+		return err
+	}
+	if len(perm[`alter`]) == 0 {
+		return sc.AccessTable(tblname, `new_column`)
+	}
+	return nil
+}
+
+// alterColumn checks the access to the changing of the column and returns the name of the table
+// and the permissions of the columns
+func (sc *SmartContract) alterColumn(tableName, name string) (string, map[string]string, error) {
+	tblname := getDefTableName(sc, strings.ToLower(tableName))
+	if err := checkSystemTable(tblname); err != nil {
+		return ``, nil, err
+	}
+	if err := sc.accessAlter(tblname); err != nil {
+		return ``, nil, err
+	}
+	prefix, table := PrefixName(tblname)
+	t := &model.Table{}
+	t.SetTablePrefix(prefix)
+	columns, err := t.GetColumns(sc.DbTransaction, table, ``)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("getting table columns")
+		return ``, nil, err
+	}
+	if _, ok := columns[name]; !ok {
+		log.WithFields(log.Fields{"type": consts.NotFound, "column": name, "table": tblname}).Error("column does not exist")
+		return ``, nil, fmt.Errorf(`column %s doesn't exists`, name)
+	}
+	return tblname, columns, nil
+}
+
+// setColumns updates the permissions of the columns of the table
+func (sc *SmartContract) setColumns(tblname string, columns map[string]string) error {
+	prefix, table := PrefixName(tblname)
+	out, err := json.Marshal(columns)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling columns to json")
+		return err
+	}
+	_, _, err = sc.selectiveLoggingAndUpd([]string{`columns`}, []interface{}{string(out)},
+		prefix+`_tables`, []string{`name`}, []string{table}, !sc.VDE && sc.Rollback, false)
+	return err
+}
+
+// alterRollback stores the schema change of the table in rollback_tx
+func (sc *SmartContract) alterRollback(tblname, alterType string, data interface{}) error {
+	if sc.VDE || !sc.Rollback {
+		return nil
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling rollback of schema change")
+		return err
+	}
+	rollbackTx := &model.RollbackTx{
+		BlockID:   sc.BlockData.BlockID,
+		TxHash:    sc.TxHash,
+		NameTable: tblname,
+		Data:      string(out),
+		AlterType: alterType,
+	}
+	if err = rollbackTx.Create(sc.DbTransaction); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating rollback of schema change")
+		return err
+	}
+	return nil
+}
+
+func checkNewName(name, newName string) error {
+	if len(newName) == 0 || newName != converter.Sanitize(newName, ``) || newName[0] == '@' {
+		return fmt.Errorf(`name %s is not valid`, newName)
+	}
+	if name == `id` || newName == `id` {
+		return fmt.Errorf(`column id can not be changed`)
+	}
+	return nil
+}
+
+// RenameColumn renames the column of the table
+func RenameColumn(sc *SmartContract, tableName, name, newName string) error {
+	if !accessContracts(sc, `EditColumnName`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("RenameColumn can be only called from @1EditColumnName")
+		return fmt.Errorf(`RenameColumn can be only called from EditColumnName`)
+	}
+	name, newName = strings.ToLower(name), strings.ToLower(newName)
+	if err := checkNewName(name, newName); err != nil {
+		return err
+	}
+	tblname, columns, err := sc.alterColumn(tableName, name)
+	if err != nil {
+		return err
+	}
+	if _, ok := columns[newName]; ok {
+		return fmt.Errorf(`column %s exists`, newName)
+	}
+	if err = model.AlterTableRenameColumn(sc.DbTransaction, tblname, name, newName); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("renaming column")
+		return err
+	}
+	columns[newName] = columns[name]
+	delete(columns, name)
+	if err = sc.setColumns(tblname, columns); err != nil {
+		return err
+	}
+	return sc.alterRollback(tblname, model.AlterRenameColumn, map[string]string{`name`: name, `new_name`: newName})
+}
+
+// DropColumn drops the column of the table. The values of the column are kept in rollback_tx
+// so the cost depends on the count of the rows.
+func DropColumn(sc *SmartContract, tableName, name string) (int64, error) {
+	if !accessContracts(sc, `DelColumn`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("DropColumn can be only called from @1DelColumn")
+		return 0, fmt.Errorf(`DropColumn can be only called from DelColumn`)
+	}
+	name = strings.ToLower(name)
+	if name == `id` {
+		return 0, fmt.Errorf(`column id can not be changed`)
+	}
+	tblname, columns, err := sc.alterColumn(tableName, name)
+	if err != nil {
+		return 0, err
+	}
+	backup, err := model.BackupColumn(sc.DbTransaction, tblname, name)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("backing up column")
+		return 0, err
+	}
+	if err = model.AlterTableDropColumn(sc.DbTransaction, tblname, name); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("dropping column")
+		return 0, err
+	}
+	delete(columns, name)
+	if err = sc.setColumns(tblname, columns); err != nil {
+		return 0, err
+	}
+	return int64(len(backup.Values)), sc.alterRollback(tblname, model.AlterDropColumn, backup)
+}
+
+// ChangeColumnType changes the type of the column. The new values are calculated with the converter
+// expression where the name of the column means its old value. The old values are kept in rollback_tx.
+// The indexes of the column are created again for the new type.
+func ChangeColumnType(sc *SmartContract, tableName, name, colType, conv string) (int64, error) {
+	if !accessContracts(sc, `EditColumnType`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("ChangeColumnType can be only called from @1EditColumnType")
+		return 0, fmt.Errorf(`ChangeColumnType can be only called from EditColumnType`)
+	}
+	name = strings.ToLower(name)
+	if name == `id` {
+		return 0, fmt.Errorf(`column id can not be changed`)
+	}
+	sqlColType, err := columnType(name, colType)
+	if err != nil {
+		return 0, err
+	}
+	tblname, columns, err := sc.alterColumn(tableName, name)
+	if err != nil {
+		return 0, err
+	}
+	if len(strings.TrimSpace(conv)) == 0 {
+		conv = `"` + name + `"`
+	} else if err = checkConverter(conv, columns); err != nil {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "converter": conv}).Error("checking converter")
+		return 0, err
+	}
+	backup, err := model.BackupColumn(sc.DbTransaction, tblname, name)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("backing up column")
+		return 0, err
+	}
+	oldName := name + `__old`
+	if err = model.AlterTableRenameColumn(sc.DbTransaction, tblname, name, oldName); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("renaming column")
+		return 0, err
+	}
+	if err = model.AlterTableAddColumn(sc.DbTransaction, tblname, name, sqlColType); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("adding column to the table")
+		return 0, err
+	}
+	// the column of the subquery hides the new column so the converter gets the old value
+	if err = model.Update(sc.DbTransaction, tblname, fmt.Sprintf(`"%[1]s" = (SELECT %[2]s FROM (SELECT "%[3]s"."%[4]s" AS "%[1]s") AS "old")`,
+		name, conv, tblname, oldName), ``); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("converting column values")
+		return 0, err
+	}
+	if err = model.AlterTableDropColumn(sc.DbTransaction, tblname, oldName); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("dropping column")
+		return 0, err
+	}
+	// the indexes have been dropped with the old column and they have the same definitions for the new one
+	if err = model.CreateIndexes(sc.DbTransaction, backup.Indexes); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("creating indexes of column")
+		return 0, err
+	}
+	return int64(len(backup.Values)), sc.alterRollback(tblname, model.AlterColumnType, backup)
+}
+
+// RenameTable renames the table of the ecosystem
+func RenameTable(sc *SmartContract, name, newName string) error {
+	if !accessContracts(sc, `EditTableName`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("RenameTable can be only called from @1EditTableName")
+		return fmt.Errorf(`RenameTable can be only called from EditTableName`)
+	}
+	name, newName = strings.ToLower(name), strings.ToLower(newName)
+	if err := checkNewName(name, newName); err != nil {
+		return err
+	}
+	tblname := getDefTableName(sc, name)
+	if err := checkSystemTable(tblname); err != nil {
+		return err
+	}
+	if err := sc.accessAlter(tblname); err != nil {
+		return err
+	}
+	newTblname := getDefTableName(sc, newName)
+	if err := checkSystemTable(newTblname); err != nil {
+		return err
+	}
+	if model.IsTable(newTblname) {
+		return fmt.Errorf("table %s exists", newName)
+	}
+	prefix, table := PrefixName(tblname)
+	_, newTable := PrefixName(newTblname)
+	if err := model.AlterTableRename(sc.DbTransaction, tblname, newTblname); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("renaming table")
+		return err
+	}
+	_, _, err := sc.selectiveLoggingAndUpd([]string{`name`}, []interface{}{newTable},
+		prefix+`_tables`, []string{`name`}, []string{table}, !sc.VDE && sc.Rollback, false)
+	if err != nil {
+		return err
+	}
+	return sc.alterRollback(newTblname, model.AlterRenameTable, map[string]string{`name`: tblname})
+}
@@ -0,0 +1,179 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxAssetData is the maximum size of the JSON metadata of the asset
+const maxAssetData = 8192
+
+func (sc *SmartContract) getAsset(id int64) (map[string]string, error) {
+	row, err := sc.getRow(model.AssetTableName, []string{`id`}, []string{converter.Int64ToStr(id)})
+	if err != nil {
+		return nil, err
+	}
+	if row == nil || converter.StrToInt64(row[`ecosystem`]) != sc.TxSmart.EcosystemID {
+		log.WithFields(log.Fields{"type": consts.NotFound, "id": id}).Error("asset has not been found")
+		return nil, fmt.Errorf(`asset %d has not been found`, id)
+	}
+	return row, nil
+}
+
+// checkBinary checks that the file exists in the binaries table of the ecosystem
+func (sc *SmartContract) checkBinary(id int64) error {
+	row, err := sc.getRow(getDefTableName(sc, `binaries`), []string{`id`}, []string{converter.Int64ToStr(id)})
+	if err != nil {
+		return err
+	}
+	if row == nil {
+		log.WithFields(log.Fields{"type": consts.NotFound, "id": id}).Error("binary has not been found")
+		return fmt.Errorf(`binary %d has not been found`, id)
+	}
+	return nil
+}
+
+// assetHistory writes the change of the owner of the asset
+func (sc *SmartContract) assetHistory(id, sender, recipient int64) (int64, error) {
+	cost, _, err := sc.selectiveLoggingAndUpd([]string{`asset_id`, `sender_id`, `recipient_id`, `block_id`,
+		`tx_hash`, `time`}, []interface{}{id, sender, recipient, sc.blockID(), hex.EncodeToString(sc.TxHash),
+		sc.blockTime()}, model.AssetHistoryTableName, nil, nil, !sc.VDE && sc.Rollback, false)
+	return cost, err
+}
+
+// AssetCreate creates the asset which is owned by the current key. The asset is described by the metadata
+// and the optional file of the binaries table. It returns the id of the asset.
+func AssetCreate(sc *SmartContract, data map[string]interface{}, binaryID int64) (qcost int64, ret int64, err error) {
+	out, err := json.Marshal(data)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling asset data")
+		return 0, 0, err
+	}
+	if len(out) > maxAssetData {
+		log.WithFields(log.Fields{"type": consts.ParameterExceeded, "size": len(out)}).Error("asset data is too big")
+		return 0, 0, fmt.Errorf(`asset data is larger than %d bytes`, maxAssetData)
+	}
+	if binaryID != 0 {
+		if err = sc.checkBinary(binaryID); err != nil {
+			return 0, 0, err
+		}
+	}
+	keyID := sc.currentKeyID()
+	qcost, lastID, err := sc.selectiveLoggingAndUpd([]string{`ecosystem`, `key_id`, `creator_id`, `data`,
+		`binary_id`, `block_id`, `time`}, []interface{}{sc.TxSmart.EcosystemID, keyID, keyID, string(out),
+		binaryID, sc.blockID(), sc.blockTime()}, model.AssetTableName, nil, nil, !sc.VDE && sc.Rollback, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	ret = converter.StrToInt64(lastID)
+	cost, err := sc.assetHistory(ret, 0, keyID)
+	return qcost + cost, ret, err
+}
+
+// AssetOwner returns the owner of the asset
+func AssetOwner(sc *SmartContract, id int64) (int64, error) {
+	asset, err := sc.getAsset(id)
+	if err != nil {
+		return 0, err
+	}
+	return converter.StrToInt64(asset[`key_id`]), nil
+}
+
+// AssetInfo returns the owner, the approved key, the creator, the metadata and the binary of the asset
+func AssetInfo(sc *SmartContract, id int64) (map[string]interface{}, error) {
+	asset, err := sc.getAsset(id)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{})
+	if len(asset[`data`]) > 0 && asset[`data`] != `NULL` {
+		if err = json.Unmarshal([]byte(asset[`data`]), &data); err != nil {
+			log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling asset data")
+			return nil, err
+		}
+	}
+	return map[string]interface{}{
+		`id`:          id,
+		`key_id`:      converter.StrToInt64(asset[`key_id`]),
+		`approved_id`: converter.StrToInt64(asset[`approved_id`]),
+		`creator_id`:  converter.StrToInt64(asset[`creator_id`]),
+		`binary_id`:   converter.StrToInt64(asset[`binary_id`]),
+		`data`:        data,
+	}, nil
+}
+
+// AssetApprove allows the spender to transfer the asset of the current key. The zero spender
+// revokes the approval.
+func AssetApprove(sc *SmartContract, id, spender int64) (int64, error) {
+	if !accessContracts(sc, `ApproveAsset`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("AssetApprove can be only called from @1ApproveAsset")
+		return 0, fmt.Errorf(`AssetApprove can be only called from ApproveAsset`)
+	}
+	asset, err := sc.getAsset(id)
+	if err != nil {
+		return 0, err
+	}
+	owner := sc.currentKeyID()
+	if converter.StrToInt64(asset[`key_id`]) != owner {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "id": id, "key_id": owner}).Error("approving asset of other key")
+		return 0, errAccessDenied
+	}
+	if spender == owner {
+		return 0, fmt.Errorf(`spender %d is not valid`, spender)
+	}
+	cost, _, err := sc.selectiveLoggingAndUpd([]string{`approved_id`}, []interface{}{spender},
+		model.AssetTableName, []string{`id`}, []string{converter.Int64ToStr(id)}, !sc.VDE && sc.Rollback, true)
+	return cost, err
+}
+
+// AssetTransfer transfers the asset to the recipient. The asset can be transferred by the owner
+// or by the approved key, the approval is revoked by the transfer.
+func AssetTransfer(sc *SmartContract, id, recipient int64) (int64, error) {
+	if !accessContracts(sc, `TransferAsset`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("AssetTransfer can be only called from @1TransferAsset")
+		return 0, fmt.Errorf(`AssetTransfer can be only called from TransferAsset`)
+	}
+	asset, err := sc.getAsset(id)
+	if err != nil {
+		return 0, err
+	}
+	keyID := sc.currentKeyID()
+	owner := converter.StrToInt64(asset[`key_id`])
+	if owner != keyID && converter.StrToInt64(asset[`approved_id`]) != keyID {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "id": id, "key_id": keyID}).Error("transferring asset of other key")
+		return 0, errAccessDenied
+	}
+	if recipient == 0 || recipient == owner {
+		return 0, fmt.Errorf(`recipient %d is not valid`, recipient)
+	}
+	qcost, _, err := sc.selectiveLoggingAndUpd([]string{`key_id`, `approved_id`}, []interface{}{recipient, 0},
+		model.AssetTableName, []string{`id`}, []string{converter.Int64ToStr(id)}, !sc.VDE && sc.Rollback, true)
+	if err != nil {
+		return 0, err
+	}
+	cost, err := sc.assetHistory(id, owner, recipient)
+	return qcost + cost, err
+}
@@ -0,0 +1,279 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"fmt"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// The escrow locks the amount of the sender until the deadline in the block time. The amount can be
+// the amount of the keys table or the token of the ecosystem. The locked amount is kept in the escrows
+// table and it doesn't belong to any key. The expired escrows are settled by the block generator with
+// @1SettleEscrow contract on behalf of the sender who pays the fuel, the amount is released to the recipient
+// if the condition is empty or true and it is refunded to the sender otherwise. Anyone can settle
+// the expired escrow by calling @1SettleEscrow too.
+
+func (sc *SmartContract) getEscrow(id int64) (map[string]string, error) {
+	row, err := sc.getRow(model.EscrowTableName, []string{`id`}, []string{converter.Int64ToStr(id)})
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		log.WithFields(log.Fields{"type": consts.NotFound, "id": id}).Error("escrow has not been found")
+		return nil, fmt.Errorf(`escrow %d has not been found`, id)
+	}
+	if row[`status`] != model.EscrowLocked {
+		return nil, fmt.Errorf(`escrow %d has been %s`, id, row[`status`])
+	}
+	return row, nil
+}
+
+// changeBalance adds the amount to the balance of the key. The amount is negative for the debit.
+func (sc *SmartContract) changeBalance(ecosystem, tokenID, keyID int64, amount decimal.Decimal) (int64, error) {
+	var balance decimal.Decimal
+	table := model.KeyTableName(ecosystem)
+	if tokenID != 0 {
+		var err error
+		if balance, err = sc.tokenBalance(tokenID, keyID); err != nil {
+			return 0, err
+		}
+	} else {
+		row, err := sc.getRow(table, []string{`id`}, []string{converter.Int64ToStr(keyID)})
+		if err != nil {
+			return 0, err
+		}
+		if row == nil {
+			log.WithFields(log.Fields{"type": consts.NotFound, "key_id": keyID}).Error("key has not been found")
+			return 0, fmt.Errorf(`key %d has not been found`, keyID)
+		}
+		if balance, err = decimal.NewFromString(row[`amount`]); err != nil {
+			log.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "value": row[`amount`]}).Error("converting amount")
+			return 0, err
+		}
+	}
+	balance = balance.Add(amount)
+	if balance.Sign() < 0 {
+		log.WithFields(log.Fields{"type": consts.NoFunds, "token_id": tokenID, "key_id": keyID}).Error("not enough funds")
+		return 0, fmt.Errorf(`not enough funds on the balance of %d`, keyID)
+	}
+	if tokenID != 0 {
+		return sc.setTokenBalance(tokenID, keyID, balance)
+	}
+	cost, _, err := sc.selectiveLoggingAndUpd([]string{`amount`}, []interface{}{balance.String()}, table,
+		[]string{`id`}, []string{converter.Int64ToStr(keyID)}, !sc.VDE && sc.Rollback, true)
+	return cost, err
+}
+
+// escrowHistory writes the movement of the escrow to the history table. The zero key is the escrow.
+func (sc *SmartContract) escrowHistory(ecosystem, tokenID, id, sender, recipient int64, amount string) (int64, error) {
+	cost, _, err := sc.selectiveLoggingAndUpd([]string{`sender_id`, `recipient_id`, `amount`, `comment`,
+		`block_id`, `txhash`, `token_id`}, []interface{}{sender, recipient, amount, fmt.Sprintf(`escrow %d`, id),
+		sc.blockID(), sc.TxHash, tokenID}, model.HistoryTableName(ecosystem), nil, nil, !sc.VDE && sc.Rollback, false)
+	return cost, err
+}
+
+// evalEscrow returns the value of the condition of the escrow in its ecosystem. The condition is
+// evaluated like the try block which is always rolled back so it can't change the state.
+func (sc *SmartContract) evalEscrow(escrow map[string]string) (bool, error) {
+	ecosystem := converter.StrToInt64(escrow[`ecosystem`])
+	id, err := sc.Savepoint()
+	if err != nil {
+		return false, err
+	}
+	ret, err := VMEvalIf(sc.VM, escrow[`condition`], uint32(ecosystem), &map[string]interface{}{
+		`ecosystem_id`: ecosystem, `key_id`: sc.currentKeyID(), `sc`: sc, `original_contract`: ``,
+		`this_contract`: ``, `block_time`: sc.blockTime(), `time`: sc.TxSmart.Time,
+		`escrow_id`: converter.StrToInt64(escrow[`id`])})
+	if errRoll := sc.RollbackSavepoint(id); errRoll != nil {
+		return false, errRoll
+	}
+	return ret, err
+}
+
+// closeEscrow releases the amount of the escrow to the recipient or refunds it to the sender
+func (sc *SmartContract) closeEscrow(escrow map[string]string, release bool) (int64, string, error) {
+	ecosystem := converter.StrToInt64(escrow[`ecosystem`])
+	tokenID := converter.StrToInt64(escrow[`token_id`])
+	id := converter.StrToInt64(escrow[`id`])
+	keyID, status := converter.StrToInt64(escrow[`sender_id`]), model.EscrowRefunded
+	if release {
+		keyID, status = converter.StrToInt64(escrow[`recipient_id`]), model.EscrowReleased
+	}
+	amount, err := decimal.NewFromString(escrow[`amount`])
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "value": escrow[`amount`]}).Error("converting escrow amount")
+		return 0, ``, err
+	}
+	qcost, err := sc.changeBalance(ecosystem, tokenID, keyID, amount)
+	if err != nil {
+		return 0, ``, err
+	}
+	cost, _, err := sc.selectiveLoggingAndUpd([]string{`status`, `settled_block`}, []interface{}{status, sc.blockID()},
+		model.EscrowTableName, []string{`id`}, []string{escrow[`id`]}, !sc.VDE && sc.Rollback, true)
+	if err != nil {
+		return 0, ``, err
+	}
+	qcost += cost
+	if cost, err = sc.escrowHistory(ecosystem, tokenID, id, 0, keyID, escrow[`amount`]); err != nil {
+		return 0, ``, err
+	}
+	return qcost + cost, status, nil
+}
+
+// EscrowLock locks the amount of the current key for the recipient until the deadline in the block time.
+// The empty symbol means the amount of the keys table, otherwise it is the symbol of the token.
+// It returns the id of the escrow.
+func EscrowLock(sc *SmartContract, symbol string, recipient int64, amount interface{}, condition string,
+	deadline int64) (qcost int64, ret int64, err error) {
+	if !accessContracts(sc, `LockEscrow`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("EscrowLock can be only called from @1LockEscrow")
+		return 0, 0, fmt.Errorf(`EscrowLock can be only called from LockEscrow`)
+	}
+	value, err := tokenAmount(amount)
+	if err != nil {
+		return 0, 0, err
+	}
+	if value.Sign() == 0 {
+		return 0, 0, fmt.Errorf(`escrow amount must be greater than zero`)
+	}
+	sender := sc.currentKeyID()
+	if recipient == 0 || recipient == sender {
+		return 0, 0, fmt.Errorf(`recipient %d is not valid`, recipient)
+	}
+	if deadline <= sc.blockTime() {
+		return 0, 0, fmt.Errorf(`deadline %d of escrow has passed`, deadline)
+	}
+	ecosystem := sc.TxSmart.EcosystemID
+	if len(condition) > 0 {
+		if err = VMCompileEval(sc.VM, condition, uint32(ecosystem)); err != nil {
+			return 0, 0, err
+		}
+	}
+	var tokenID int64
+	if len(symbol) > 0 {
+		if tokenID, err = sc.getToken(symbol); err != nil {
+			return 0, 0, err
+		}
+	} else {
+		// the amount of the keys table can be released only to the existing key
+		row, err := sc.getRow(model.KeyTableName(ecosystem), []string{`id`}, []string{converter.Int64ToStr(recipient)})
+		if err != nil {
+			return 0, 0, err
+		}
+		if row == nil {
+			return 0, 0, fmt.Errorf(`key %d has not been found`, recipient)
+		}
+	}
+	if qcost, err = sc.changeBalance(ecosystem, tokenID, sender, value.Neg()); err != nil {
+		return 0, 0, err
+	}
+	cost, lastID, err := sc.selectiveLoggingAndUpd([]string{`ecosystem`, `sender_id`, `recipient_id`, `token_id`,
+		`amount`, `condition`, `deadline`, `status`, `block_id`}, []interface{}{ecosystem, sender, recipient, tokenID,
+		value.String(), condition, deadline, model.EscrowLocked, sc.blockID()}, model.EscrowTableName, nil, nil,
+		!sc.VDE && sc.Rollback, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	qcost += cost
+	ret = converter.StrToInt64(lastID)
+	if cost, err = sc.escrowHistory(ecosystem, tokenID, ret, sender, 0, value.String()); err != nil {
+		return 0, 0, err
+	}
+	return qcost + cost, ret, nil
+}
+
+// EscrowRelease releases the escrow to the recipient before the deadline. The escrow can be released
+// by the sender or by anyone if the condition of the escrow is not empty and true.
+func EscrowRelease(sc *SmartContract, id int64) (int64, error) {
+	if !accessContracts(sc, `ReleaseEscrow`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("EscrowRelease can be only called from @1ReleaseEscrow")
+		return 0, fmt.Errorf(`EscrowRelease can be only called from ReleaseEscrow`)
+	}
+	escrow, err := sc.getEscrow(id)
+	if err != nil {
+		return 0, err
+	}
+	if sc.blockTime() >= converter.StrToInt64(escrow[`deadline`]) {
+		return 0, fmt.Errorf(`escrow %d is expired`, id)
+	}
+	if sc.currentKeyID() != converter.StrToInt64(escrow[`sender_id`]) {
+		var ok bool
+		if len(escrow[`condition`]) > 0 {
+			if ok, err = sc.evalEscrow(escrow); err != nil {
+				return 0, err
+			}
+		}
+		if !ok {
+			log.WithFields(log.Fields{"type": consts.AccessDenied, "id": id}).Error("releasing escrow")
+			return 0, errAccessDenied
+		}
+	}
+	cost, _, err := sc.closeEscrow(escrow, true)
+	return cost, err
+}
+
+// EscrowCancel refunds the escrow to the sender before the deadline. Only the sender can cancel the escrow.
+func EscrowCancel(sc *SmartContract, id int64) (int64, error) {
+	if !accessContracts(sc, `CancelEscrow`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("EscrowCancel can be only called from @1CancelEscrow")
+		return 0, fmt.Errorf(`EscrowCancel can be only called from CancelEscrow`)
+	}
+	escrow, err := sc.getEscrow(id)
+	if err != nil {
+		return 0, err
+	}
+	if sc.blockTime() >= converter.StrToInt64(escrow[`deadline`]) {
+		return 0, fmt.Errorf(`escrow %d is expired`, id)
+	}
+	if sc.currentKeyID() != converter.StrToInt64(escrow[`sender_id`]) {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "id": id}).Error("cancelling escrow")
+		return 0, errAccessDenied
+	}
+	cost, _, err := sc.closeEscrow(escrow, false)
+	return cost, err
+}
+
+// EscrowSettle settles the expired escrow. The amount is released if the condition is empty or true
+// and it is refunded if the condition is false or it fails. It returns the status of the escrow.
+func EscrowSettle(sc *SmartContract, id int64) (int64, string, error) {
+	if !accessContracts(sc, `SettleEscrow`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("EscrowSettle can be only called from @1SettleEscrow")
+		return 0, ``, fmt.Errorf(`EscrowSettle can be only called from SettleEscrow`)
+	}
+	escrow, err := sc.getEscrow(id)
+	if err != nil {
+		return 0, ``, err
+	}
+	if sc.blockTime() < converter.StrToInt64(escrow[`deadline`]) {
+		return 0, ``, fmt.Errorf(`escrow %d is not expired`, id)
+	}
+	release := true
+	if len(escrow[`condition`]) > 0 {
+		if release, err = sc.evalEscrow(escrow); err != nil {
+			log.WithFields(log.Fields{"type": consts.EvalError, "error": err, "id": id}).Warning("evaluating escrow condition")
+			release = false
+		}
+	}
+	return sc.closeEscrow(escrow, release)
+}
@@ -19,7 +19,6 @@ package smart
 import (
 	"bytes"
 	"crypto/md5"
-	"database/sql"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -54,10 +53,25 @@ import (
 
 const nodeBanNotificationHeader = "Your node was banned"
 
+// maxEventData is the maximum size of the JSON data of the event
+const maxEventData = 8192
+
+var eventName = regexp.MustCompile(`^[\w\.\-]{1,255}$`)
+
+const (
+	maxBytesColumn      = 1048576 // the maximum size of bytes columns
+	maxDecimalPrecision = 1000
+)
+
+// sizedType matches the column types with the size like bytes(1024) and decimal(20,8)
+var sizedType = regexp.MustCompile(`^(bytes|decimal)(?:\((\d{1,7})(?:,\s*(\d{1,7}))?\))?$`)
+
 type permTable struct {
 	Insert    string `json:"insert"`
 	Update    string `json:"update"`
 	NewColumn string `json:"new_column"`
+	Delete    string `json:"delete,omitempty"`
+	Alter     string `json:"alter,omitempty"`
 	Read      string `json:"read,omitempty"`
 	Filter    string `json:"filter,omitempty"`
 }
@@ -84,6 +98,16 @@ type SmartContract struct {
 	TxHash        []byte
 	PublicKeys    [][]byte
 	DbTransaction *model.DbTransaction
+	Debugger      *script.Debugger // The debugger of the contract if it is run in the debug mode
+	Profiler      *script.Profiler // The profiler of the fuel if it is defined
+	SkipSign      bool             // The signature is not checked if the transaction is simulated
+	DryRun        bool             // The contract is run by the API and its changes of the database are rolled back
+	Storage       Storage          // The storage of the tables which is used instead of the database
+
+	savepoints int // The count of the savepoints of try blocks
+	tries      int // The depth of the running try blocks
+
+	multisigApproved string // The name of the approved multi-signature contract which is run
 }
 
 // AppendStack adds an element to the stack of contract call or removes the top element when name is empty
@@ -97,13 +121,73 @@ func (sc *SmartContract) AppendStack(contract string) {
 	(*sc.TxContract.Extend)["stack"] = cont.StackCont
 }
 
+// Savepoint creates the savepoint of the database before the try block of the contract
+func (sc *SmartContract) Savepoint() (int, error) {
+	sc.tries++
+	if sc.DbTransaction == nil {
+		return 0, nil
+	}
+	sc.savepoints++
+	if err := sc.DbTransaction.TrySavepoint(sc.savepoints); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating savepoint of try block")
+		sc.tries--
+		return 0, err
+	}
+	return sc.savepoints, nil
+}
+
+// RollbackSavepoint rolls back the changes of the database which have been made in the failed try block
+func (sc *SmartContract) RollbackSavepoint(id int) error {
+	sc.tries--
+	if sc.DbTransaction == nil {
+		return nil
+	}
+	if err := sc.DbTransaction.RollbackTrySavepoint(id); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("rolling back savepoint of try block")
+		return err
+	}
+	return nil
+}
+
+// ReleaseSavepoint releases the savepoint of the successful try block
+func (sc *SmartContract) ReleaseSavepoint(id int) error {
+	sc.tries--
+	if sc.DbTransaction == nil {
+		return nil
+	}
+	if err := sc.DbTransaction.ReleaseTrySavepoint(id); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("releasing savepoint of try block")
+		return err
+	}
+	return nil
+}
+
+// checkMemory returns the error if the function is called in the try block or in the dry run.
+// The savepoint and the dry run roll back only the database so the functions which change
+// the virtual machine or the caches are disallowed there.
+func (sc *SmartContract) checkMemory(name string) error {
+	if sc.tries > 0 {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract, "func_name": name}).Error("calling function in try block")
+		return fmt.Errorf(`%s can't be called in the try block`, name)
+	}
+	if sc.DryRun {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract, "func_name": name}).Error("calling function in dry run")
+		return fmt.Errorf(`%s can't be called in the dry run`, name)
+	}
+	return nil
+}
+
 var (
 	funcCallsDB = map[string]struct{}{
-		"DBInsert":    {},
-		"DBSelect":    {},
-		"DBUpdate":    {},
-		"DBUpdateExt": {},
-		"SetPubKey":   {},
+		"ChangeColumnType": {},
+		"DBDelete":         {},
+		"DBInsert":         {},
+		"DBSelect":         {},
+		"DBSelectQuery":    {},
+		"DBUpdate":         {},
+		"DBUpdateExt":      {},
+		"DropColumn":       {},
+		"SetPubKey":        {},
 	}
 	extendCost = map[string]int64{
 		"AddressToId":                  10,
@@ -171,10 +255,16 @@ func EmbedFuncs(vm *script.VM, vt script.VMType) {
 		"ContractConditions":           ContractConditions,
 		"ContractName":                 contractName,
 		"ValidateEditContractNewValue": ValidateEditContractNewValue,
+		"ChangeColumnType":             ChangeColumnType,
 		"CreateColumn":                 CreateColumn,
 		"CreateTable":                  CreateTable,
+		"DropColumn":                   DropColumn,
+		"RenameColumn":                 RenameColumn,
+		"RenameTable":                  RenameTable,
+		"DBDelete":                     DBDelete,
 		"DBInsert":                     DBInsert,
 		"DBSelect":                     DBSelect,
+		"DBSelectQuery":                DBSelectQuery,
 		"DBUpdate":                     DBUpdate,
 		"DBUpdateSysParam":             UpdateSysParam,
 		"DBUpdateExt":                  DBUpdateExt,
@@ -227,6 +317,8 @@ func EmbedFuncs(vm *script.VM, vt script.VMType) {
 		"RollbackContract":             RollbackContract,
 		"RollbackEditContract":         RollbackEditContract,
 		"RollbackNewContract":          RollbackNewContract,
+		"CreateSystemContracts":        CreateSystemContracts,
+		"RollbackSystemContracts":      RollbackSystemContracts,
 		"check_signature":              CheckSignature,
 		"RowConditions":                RowConditions,
 		"UUID":                         UUID,
@@ -245,6 +337,7 @@ func EmbedFuncs(vm *script.VM, vt script.VMType) {
 		"SortedKeys":                   SortedKeys,
 		"Append":                       Append,
 	}
+	vm.ParamCheck = checkColumnTypeParam
 
 	switch vt {
 	case script.VMTypeVDE:
@@ -255,17 +348,39 @@ func EmbedFuncs(vm *script.VM, vt script.VMType) {
 		f["UpdateCron"] = UpdateCron
 		vmExtendCost(vm, getCost)
 		vmFuncCallsDB(vm, funcCallsDB)
+		// VDE contracts are not executed by the consensus so their byte-code can be optimized
+		vm.Optimize = true
 	case script.VMTypeSmart:
 		f["GetBlock"] = GetBlock
 		f["UpdateNodesBan"] = UpdateNodesBan
 		f["DBSelectMetrics"] = DBSelectMetrics
 		f["DBCollectMetrics"] = DBCollectMetrics
+		f["EmitEvent"] = EmitEvent
+		f["MultisigApprove"] = MultisigApprove
+		f["TokenCreate"] = TokenCreate
+		f["TokenBalance"] = TokenBalance
+		f["TokenTransfer"] = TokenTransfer
+		f["TokenApprove"] = TokenApprove
+		f["TokenAllowance"] = TokenAllowance
+		f["TokenTransferFrom"] = TokenTransferFrom
+		f["AssetCreate"] = AssetCreate
+		f["AssetOwner"] = AssetOwner
+		f["AssetInfo"] = AssetInfo
+		f["AssetApprove"] = AssetApprove
+		f["AssetTransfer"] = AssetTransfer
+		f["EscrowLock"] = EscrowLock
+		f["EscrowRelease"] = EscrowRelease
+		f["EscrowCancel"] = EscrowCancel
+		f["EscrowSettle"] = EscrowSettle
+		f["SponsorSet"] = SponsorSet
+		f["SponsorBudget"] = SponsorBudget
 		ExtendCost(getCostP)
 		FuncCallsDB(funcCallsDBP)
 	}
 
 	vmExtend(vm, &script.ExtendData{Objects: f, AutoPars: map[string]string{
 		`*smart.SmartContract`: `sc`,
+		`*script.RunTime`:      `rt`,
 	}})
 }
 
@@ -305,7 +420,21 @@ func CompileContract(sc *SmartContract, code string, state, id, token int64) (in
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("CompileContract can be only called from NewContract or EditContract")
 		return 0, fmt.Errorf(`CompileContract can be only called from NewContract or EditContract`)
 	}
-	return VMCompileBlock(sc.VM, code, &script.OwnerInfo{StateID: uint32(state), WalletID: id, TokenID: token})
+	return compileContract(sc, code, state, id, token)
+}
+
+func compileContract(sc *SmartContract, code string, state, id, token int64) (interface{}, error) {
+	root, err := VMCompileBlock(sc.VM, code, &script.OwnerInfo{StateID: uint32(state), WalletID: id, TokenID: token})
+	if err != nil {
+		return nil, err
+	}
+	if syspar.IsCheckContractTypes() {
+		if diags := sc.VM.TypeCheck(root).Errors(); len(diags) > 0 {
+			log.WithFields(log.Fields{"type": consts.ParseError, "error": diags}).Error("checking types of contract")
+			return nil, diags
+		}
+	}
+	return root, nil
 }
 
 // ContractAccess checks whether the name of the executable contract matches one of the names listed in the parameters.
@@ -413,6 +542,15 @@ func UpdateContract(sc *SmartContract, id int64, value, conditions, walletID str
 		}
 		pars = append(pars, "value")
 		vals = append(vals, value)
+		if !sc.VDE {
+			version, err := sc.newContractVersion(id, value)
+			if err != nil {
+				return err
+			}
+			root.(*script.Block).Owner.Version = version
+			pars = append(pars, "version")
+			vals = append(vals, version)
+		}
 	}
 	if conditions != "" {
 		pars = append(pars, "conditions")
@@ -446,17 +584,33 @@ func CreateContract(sc *SmartContract, name, value, conditions string, walletID,
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("CreateContract can be only called from NewContract")
 		return 0, fmt.Errorf(`CreateContract can be only called from NewContract`)
 	}
+	return createContract(sc, name, value, conditions, walletID, tokenEcosystem, appID)
+}
+
+func createContract(sc *SmartContract, name, value, conditions string, walletID, tokenEcosystem, appID int64) (int64, error) {
 	var id int64
 	var err error
-	root, err := CompileContract(sc, value, sc.TxSmart.EcosystemID, walletID, tokenEcosystem)
+	root, err := compileContract(sc, value, sc.TxSmart.EcosystemID, walletID, tokenEcosystem)
 	if err != nil {
 		return 0, err
 	}
-	_, id, err = DBInsert(sc, "contracts", "name,value,conditions,wallet_id,token_id,app_id", name, value, conditions, walletID, tokenEcosystem, appID)
+	fields := "name,value,conditions,wallet_id,token_id,app_id"
+	vals := []interface{}{name, value, conditions, walletID, tokenEcosystem, appID}
+	if !sc.VDE {
+		root.(*script.Block).Owner.Version = 1
+		fields += ",version"
+		vals = append(vals, 1)
+	}
+	_, id, err = DBInsert(sc, "contracts", fields, vals...)
 	if err != nil {
 		return 0, err
 	}
-	if err := FlushContract(sc, root, id, false); err != nil {
+	if !sc.VDE {
+		if err = sc.addContractVersion(id, name, value, 1, sc.blockID(), hex.EncodeToString(sc.TxHash)); err != nil {
+			return 0, err
+		}
+	}
+	if err := flushContract(sc, root, id, false); err != nil {
 		return 0, err
 	}
 	return id, nil
@@ -471,6 +625,7 @@ func RollbackNewContract(sc *SmartContract, value string) error {
 		if err := RollbackContract(sc, contract); err != nil {
 			return err
 		}
+		dropContractVersion(sc.VM, script.StateName(uint32(sc.TxSmart.EcosystemID), contract), 1)
 	}
 	return nil
 }
@@ -495,35 +650,21 @@ func CreateTable(sc *SmartContract, name, columns, permissions string, applicati
 		return fmt.Errorf("table %s exists", name)
 	}
 
-	var cols []interface{}
-	err = json.Unmarshal([]byte(columns), &cols)
+	cols, indexes, err := parseColumns(columns)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "source": columns}).Error("unmarshalling columns to JSON")
 		return err
 	}
 
 	colsSQL := ""
 	colperm := make(map[string]string)
 	colList := make(map[string]bool)
-	for _, icol := range cols {
-		var data map[string]interface{}
-		switch v := icol.(type) {
-		case string:
-			err = json.Unmarshal([]byte(v), &data)
-			if err != nil {
-				log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err,
-					"source": v}).Error("unmarshalling columns permissions from json")
-				return err
-			}
-		default:
-			data = v.(map[string]interface{})
-		}
+	for _, data := range cols {
 		colname := converter.EscapeSQL(strings.ToLower(data[`name`].(string)))
 		if colList[colname] {
 			return fmt.Errorf(`There are the same columns`)
 		}
 
-		sqlColType, err := columnType(data["type"].(string))
+		sqlColType, err := columnType(colname, data["type"].(string))
 		if err != nil {
 			return err
 		}
@@ -549,6 +690,9 @@ func CreateTable(sc *SmartContract, name, columns, permissions string, applicati
 		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling columns to JSON")
 		return err
 	}
+	if err = checkIndexes(indexes, colList, 0); err != nil {
+		return err
+	}
 	if sc.VDE {
 		err = model.CreateVDETable(sc.DbTransaction, tableName, strings.TrimRight(colsSQL, ",\n"))
 	} else {
@@ -558,6 +702,12 @@ func CreateTable(sc *SmartContract, name, columns, permissions string, applicati
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating VDE tables")
 		return err
 	}
+	for _, index := range indexes {
+		if err = index.create(sc.DbTransaction, tableName); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tableName}).Error("creating index")
+			return err
+		}
+	}
 
 	var perm permTable
 	err = json.Unmarshal([]byte(permissions), &perm)
@@ -611,7 +761,10 @@ func CreateTable(sc *SmartContract, name, columns, permissions string, applicati
 	return nil
 }
 
-func columnType(colType string) (sqlColType string, err error) {
+func columnType(colName, colType string) (sqlColType string, err error) {
+	if match := sizedType.FindStringSubmatch(colType); match != nil {
+		return sizedColumnType(colName, colType, match)
+	}
 	switch colType {
 	case "json":
 		sqlColType = `jsonb`
@@ -629,6 +782,16 @@ func columnType(colType string) (sqlColType string, err error) {
 		sqlColType = `decimal (30, 0) NOT NULL DEFAULT '0'`
 	case "text":
 		sqlColType = "text"
+	case "bool":
+		sqlColType = `boolean NOT NULL DEFAULT false`
+	case "uuid":
+		sqlColType = `uuid`
+	case "date":
+		sqlColType = `date`
+	case "int_array":
+		sqlColType = `bigint[] NOT NULL DEFAULT '{}'`
+	case "text_array":
+		sqlColType = `text[] NOT NULL DEFAULT '{}'`
 	default:
 		err = fmt.Errorf("Type '%s' of columns is not supported", colType)
 	}
@@ -636,6 +799,48 @@ func columnType(colType string) (sqlColType string, err error) {
 	return
 }
 
+// isColumnType returns true if the columns of the type can be created
+func isColumnType(colType string) bool {
+	if colType == `bytea` {
+		return true
+	}
+	_, err := columnType(``, colType)
+	return err == nil
+}
+
+// columnTypeParams are the indexes of the column type parameters of the functions
+var columnTypeParams = map[string]int{`ColumnCondition`: 2, `CreateColumn`: 2, `ChangeColumnType`: 2}
+
+// checkColumnTypeParam checks the constant column types of the functions for the type checker
+func checkColumnTypeParam(name string, index int, value string) error {
+	if param, ok := columnTypeParams[name]; ok && param == index && !isColumnType(value) {
+		return fmt.Errorf("Type '%s' of columns is not supported", value)
+	}
+	return nil
+}
+
+// sizedColumnType returns the SQL type of bytes(size) and decimal(precision,scale) columns
+func sizedColumnType(colName, colType string, match []string) (string, error) {
+	switch match[1] {
+	case "bytes":
+		size := maxBytesColumn
+		if len(match[2]) > 0 {
+			size = converter.StrToInt(match[2])
+			if size <= 0 || size > maxBytesColumn || len(match[3]) > 0 {
+				return ``, fmt.Errorf("Type '%s' of columns is not valid", colType)
+			}
+		}
+		return fmt.Sprintf(`bytea CHECK (octet_length("%s") <= %d)`, colName, size), nil
+	case "decimal":
+		precision, scale := converter.StrToInt(match[2]), converter.StrToInt(match[3])
+		if len(match[2]) == 0 || precision <= 0 || precision > maxDecimalPrecision || scale > precision {
+			return ``, fmt.Errorf("Type '%s' of columns is not valid", colType)
+		}
+		return fmt.Sprintf(`decimal(%d, %d) NOT NULL DEFAULT '0'`, precision, scale), nil
+	}
+	return ``, fmt.Errorf("Type '%s' of columns is not supported", colType)
+}
+
 // DBInsert inserts a record into the specified database table
 func DBInsert(sc *SmartContract, tblname string, params string, val ...interface{}) (qcost int64, ret int64, err error) {
 	if tblname == "system_parameters" {
@@ -648,10 +853,6 @@ func DBInsert(sc *SmartContract, tblname string, params string, val ...interface
 	}
 	var ind int
 	var lastID string
-	if ind, err = model.NumIndexes(tblname); err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("num indexes")
-		return
-	}
 	if len(val) == 0 {
 		err = fmt.Errorf(`values are undefined`)
 		return
@@ -659,6 +860,9 @@ func DBInsert(sc *SmartContract, tblname string, params string, val ...interface
 	if reflect.TypeOf(val[0]) == reflect.TypeOf([]interface{}{}) {
 		val = val[0].([]interface{})
 	}
+	if ind, err = sc.storage().NumIndexes(sc, tblname); err != nil {
+		return
+	}
 	qcost, lastID, err = sc.selectiveLoggingAndUpd(strings.Split(params, `,`), val, tblname, nil,
 		nil, !sc.VDE && sc.Rollback, false)
 	if ind > 0 {
@@ -670,6 +874,40 @@ func DBInsert(sc *SmartContract, tblname string, params string, val ...interface
 	return
 }
 
+// EmitEvent stores the event of the current contract. The events are inserted into the events table
+// in the block of the transaction and they are rolled back together with the transaction.
+func EmitEvent(sc *SmartContract, name string, data map[string]interface{}) (qcost int64, ret int64, err error) {
+	if !eventName.MatchString(name) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "name": name}).Error("invalid event name")
+		return 0, 0, fmt.Errorf(`invalid event name %s`, name)
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling event data")
+		return 0, 0, err
+	}
+	if len(out) > maxEventData {
+		log.WithFields(log.Fields{"type": consts.ParameterExceeded, "size": len(out)}).Error("event data is too big")
+		return 0, 0, fmt.Errorf(`event data is larger than %d bytes`, maxEventData)
+	}
+	var contract string
+	if stack := sc.TxContract.StackCont; len(stack) > 0 {
+		contract = stack[len(stack)-1]
+	}
+	var blockID int64
+	if sc.BlockData != nil {
+		blockID = sc.BlockData.BlockID
+	}
+	var lastID string
+	qcost, lastID, err = sc.selectiveLoggingAndUpd([]string{`ecosystem`, `block_id`, `tx_hash`, `contract`,
+		`name`, `data`, `time`}, []interface{}{sc.TxSmart.EcosystemID, blockID, hex.EncodeToString(sc.TxHash),
+		contract, name, string(out), sc.TxSmart.Time}, model.EventTableName, nil, nil, !sc.VDE && sc.Rollback, false)
+	if err == nil {
+		ret, _ = strconv.ParseInt(lastID, 10, 64)
+	}
+	return
+}
+
 // PrepareColumns replaces jsonb fields -> in the list of columns for db selecting
 // For example, name,doc->title => name,doc::jsonb->>'title' as "doc.title"
 func PrepareColumns(columns string) string {
@@ -728,7 +966,6 @@ func DBSelect(sc *SmartContract, tblname string, columns string, id int64, order
 
 	var (
 		err  error
-		rows *sql.Rows
 		perm map[string]string
 	)
 	if len(columns) == 0 {
@@ -764,43 +1001,10 @@ func DBSelect(sc *SmartContract, tblname string, columns string, id int64, order
 		}
 		columns = strings.Join(cols, `,`)
 	}
-	columns = PrepareColumns(columns)
-
-	rows, err = model.GetDB(sc.DbTransaction).Table(tblname).Select(columns).Where(where, params...).Order(order).
-		Offset(offset).Limit(limit).Rows()
+	result, err := sc.storage().Select(sc, tblname, columns, where, params, order, offset, limit)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting rows from table")
 		return 0, nil, err
 	}
-	defer rows.Close()
-	cols, err := rows.Columns()
-	if err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting rows columns")
-		return 0, nil, err
-	}
-	values := make([][]byte, len(cols))
-	scanArgs := make([]interface{}, len(values))
-	for i := range values {
-		scanArgs[i] = &values[i]
-	}
-
-	result := make([]interface{}, 0, 50)
-	for rows.Next() {
-		err = rows.Scan(scanArgs...)
-		if err != nil {
-			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("scanning next row")
-			return 0, nil, err
-		}
-		row := make(map[string]string)
-		for i, col := range values {
-			var value string
-			if col != nil {
-				value = string(col)
-			}
-			row[cols[i]] = value
-		}
-		result = append(result, reflect.ValueOf(row).Interface())
-	}
 	if sc.VDE && perm != nil && len(perm[`filter`]) > 0 {
 		fltResult, err := VMEvalIf(sc.VM, perm[`filter`], uint32(sc.TxSmart.EcosystemID),
 			&map[string]interface{}{
@@ -840,22 +1044,45 @@ func DBUpdate(sc *SmartContract, tblname string, id int64, params string, val ..
 	return
 }
 
+// DBDelete deletes the item with the specified id from the table. The deletion is allowed only if
+// the table has the delete permission. The deleted row is kept in rollback_tx.
+func DBDelete(sc *SmartContract, tblname string, id int64) (qcost int64, err error) {
+	if tblname == "system_parameters" {
+		return 0, fmt.Errorf("system parameters access denied")
+	}
+
+	tblname = getDefTableName(sc, tblname)
+	if !sc.FullAccess {
+		var perm map[string]string
+		if perm, err = sc.AccessTablePerm(tblname, "delete"); err != nil {
+			return
+		}
+		if perm != nil && len(perm[`delete`]) == 0 {
+			log.WithFields(log.Fields{"type": consts.AccessDenied, "table": tblname}).Error("delete permission is undefined")
+			return 0, errAccessDenied
+		}
+	}
+	if strings.Contains(tblname, `_reports_`) {
+		err = fmt.Errorf(`Access denied to report table`)
+		return
+	}
+	return sc.deleteWithLogging(tblname, converter.Int64ToStr(id), !sc.VDE && sc.Rollback)
+}
+
 // EcosysParam returns the value of the specified parameter for the ecosystem
 func EcosysParam(sc *SmartContract, name string) string {
-	val, _ := model.Single(`SELECT value FROM "`+getDefTableName(sc, `parameters`)+`" WHERE name = ?`, name).String()
-	return val
+	row, _ := sc.getRow(getDefTableName(sc, `parameters`), []string{`name`}, []string{name})
+	return row[`value`]
 }
 
 // AppParam returns the value of the specified app parameter for the ecosystem
 func AppParam(sc *SmartContract, app int64, name string) (string, error) {
-	ap := &model.AppParam{}
-	ap.SetTablePrefix(converter.Int64ToStr(sc.TxSmart.EcosystemID))
-	_, err := ap.Get(sc.DbTransaction, app, name)
+	row, err := sc.getRow(getDefTableName(sc, `app_params`), []string{`app_id`, `name`},
+		[]string{converter.Int64ToStr(app), name})
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting app param")
 		return ``, err
 	}
-	return ap.Value, nil
+	return row[`value`], nil
 }
 
 // Eval evaluates the condition
@@ -882,6 +1109,13 @@ func FlushContract(sc *SmartContract, iroot interface{}, id int64, active bool)
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("FlushContract can be only called from NewContract or EditContract")
 		return fmt.Errorf(`FlushContract can be only called from NewContract or EditContract`)
 	}
+	return flushContract(sc, iroot, id, active)
+}
+
+func flushContract(sc *SmartContract, iroot interface{}, id int64, active bool) error {
+	if err := sc.checkMemory(`FlushContract`); err != nil {
+		return err
+	}
 	root := iroot.(*script.Block)
 	if id != 0 {
 		if len(root.Children) != 1 || root.Children[0].Type != script.ObjContract {
@@ -974,7 +1208,7 @@ func TableConditions(sc *SmartContract, name, columns, permissions string) (err
 	for i := 0; i < v.NumField(); i++ {
 		cond := v.Field(i).Interface().(string)
 		name := v.Type().Field(i).Name
-		if len(cond) == 0 && name != `Read` && name != `Filter` {
+		if len(cond) == 0 && name != `Read` && name != `Filter` && name != `Delete` && name != `Alter` {
 			log.WithFields(log.Fields{"condition_type": name, "type": consts.EmptyObject}).Error("condition is empty")
 			return fmt.Errorf(`%v condition is empty`, name)
 		}
@@ -993,10 +1227,8 @@ func TableConditions(sc *SmartContract, name, columns, permissions string) (err
 		return nil
 	}
 
-	var cols []interface{}
-	err = json.Unmarshal([]byte(columns), &cols)
+	cols, indexes, err := parseColumns(columns)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "source": columns}).Error("unmarshalling columns permissions from json")
 		return
 	}
 	if len(cols) == 0 {
@@ -1007,27 +1239,15 @@ func TableConditions(sc *SmartContract, name, columns, permissions string) (err
 		log.WithFields(log.Fields{"size": len(cols), "max_size": syspar.GetMaxColumns(), "type": consts.ParameterExceeded}).Error("Too many columns")
 		return fmt.Errorf(`Too many columns. Limit is %d`, syspar.GetMaxColumns())
 	}
-	for _, icol := range cols {
-		var data map[string]interface{}
-		switch v := icol.(type) {
-		case string:
-			err = json.Unmarshal([]byte(v), &data)
-			if err != nil {
-				log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err,
-					"source": v}).Error("unmarshalling columns permissions from json")
-				return
-			}
-		default:
-			data = v.(map[string]interface{})
-		}
+	colList := make(map[string]bool)
+	for _, data := range cols {
 		if data[`name`] == nil || data[`type`] == nil {
 			log.WithFields(log.Fields{"type": consts.InvalidObject}).Error("wrong column")
 			return fmt.Errorf(`worng column`)
 		}
+		colList[converter.EscapeSQL(strings.ToLower(data[`name`].(string)))] = true
 		itype := data[`type`].(string)
-		if itype != `varchar` && itype != `number` && itype != `datetime` && itype != `text` &&
-			itype != `bytea` && itype != `double` && itype != `json` && itype != `money` &&
-			itype != `character` {
+		if !isColumnType(itype) {
 			log.WithFields(log.Fields{"type": consts.InvalidObject}).Error("incorrect type")
 			return fmt.Errorf(`incorrect type`)
 		}
@@ -1064,6 +1284,9 @@ func TableConditions(sc *SmartContract, name, columns, permissions string) (err
 		}
 
 	}
+	if err = checkIndexes(indexes, colList, 0); err != nil {
+		return err
+	}
 	if err := sc.AccessRights("new_table", false); err != nil {
 		return err
 	}
@@ -1136,9 +1359,18 @@ func ColumnCondition(sc *SmartContract, tableName, name, coltype, permissions st
 		log.WithFields(log.Fields{"size": count, "max_size": syspar.GetMaxColumns(), "type": consts.ParameterExceeded}).Error("Too many columns")
 		return fmt.Errorf(`Too many columns. Limit is %d`, syspar.GetMaxColumns())
 	}
-	if coltype != `varchar` && coltype != `number` && coltype != `datetime` &&
-		coltype != `character` && coltype != `json` &&
-		coltype != `text` && coltype != `bytea` && coltype != `double` && coltype != `money` {
+	coltype, index := splitColumnType(name, coltype)
+	if index != nil {
+		ind, err := model.NumIndexes(tblName)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("num indexes")
+			return err
+		}
+		if err = checkIndexes([]*tableIndex{index}, map[string]bool{name: true}, ind); err != nil {
+			return err
+		}
+	}
+	if !isColumnType(coltype) {
 		log.WithFields(log.Fields{"column_type": coltype, "type": consts.InvalidObject}).Error("Unknown column type")
 		return fmt.Errorf(`incorrect type`)
 	}
@@ -1189,7 +1421,8 @@ func CreateColumn(sc *SmartContract, tableName, name, colType, permissions strin
 	tableName = strings.ToLower(tableName)
 	tblname := getDefTableName(sc, tableName)
 
-	sqlColType, err := columnType(colType)
+	colType, index := splitColumnType(name, colType)
+	sqlColType, err := columnType(name, colType)
 	if err != nil {
 		return err
 	}
@@ -1199,6 +1432,12 @@ func CreateColumn(sc *SmartContract, tableName, name, colType, permissions strin
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("adding column to the table")
 		return err
 	}
+	if index != nil {
+		if err = index.create(sc.DbTransaction, tblname); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": tblname}).Error("creating index")
+			return err
+		}
+	}
 
 	tables := getDefTableName(sc, `tables`)
 	type cols struct {
@@ -0,0 +1,181 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/conf/syspar"
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// tableIndex is the index of the table columns which is declared by the contract
+type tableIndex struct {
+	Columns []string
+	Unique  bool
+}
+
+// name returns the name of the index without the suffix which is added by the model
+func (index *tableIndex) name(table string) string {
+	return table + `_` + strings.Join(index.Columns, `_`)
+}
+
+func (index *tableIndex) create(transaction *model.DbTransaction, table string) error {
+	columns := `"` + strings.Join(index.Columns, `", "`) + `"`
+	if index.Unique {
+		return model.CreateUniqueIndex(transaction, index.name(table), table, columns)
+	}
+	return model.CreateIndex(transaction, index.name(table), table, columns)
+}
+
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v == `1` || strings.ToLower(v) == `true`
+	}
+	return false
+}
+
+// parseColumns parses the columns of CreateTable. The columns are the array of the column definitions
+// or the object {"columns": [...], "indexes": [{"columns": "a,b", "unique": true}]} with the composite
+// indexes. The column definition can have "index" or "unique" flags.
+func parseColumns(columns string) (cols []map[string]interface{}, indexes []*tableIndex, err error) {
+	var (
+		list  []interface{}
+		items []interface{}
+		value interface{}
+	)
+	if err = json.Unmarshal([]byte(columns), &value); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "source": columns}).Error("unmarshalling columns from json")
+		return
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		list = v
+	case map[string]interface{}:
+		list, _ = v[`columns`].([]interface{})
+		if v[`indexes`] != nil {
+			var ok bool
+			if items, ok = v[`indexes`].([]interface{}); !ok {
+				return nil, nil, fmt.Errorf(`indexes must be an array`)
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf(`columns must be an array or an object`)
+	}
+	for _, icol := range list {
+		var data map[string]interface{}
+		switch v := icol.(type) {
+		case string:
+			if err = json.Unmarshal([]byte(v), &data); err != nil {
+				log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err,
+					"source": v}).Error("unmarshalling columns permissions from json")
+				return
+			}
+		case map[string]interface{}:
+			data = v
+		default:
+			return nil, nil, fmt.Errorf(`worng column`)
+		}
+		cols = append(cols, data)
+		name, ok := data[`name`].(string)
+		if !ok {
+			continue
+		}
+		name = converter.EscapeSQL(strings.ToLower(name))
+		if isTrue(data[`unique`]) {
+			indexes = append(indexes, &tableIndex{Columns: []string{name}, Unique: true})
+		} else if isTrue(data[`index`]) {
+			indexes = append(indexes, &tableIndex{Columns: []string{name}})
+		}
+	}
+	for _, item := range items {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf(`wrong index`)
+		}
+		index := &tableIndex{Unique: isTrue(data[`unique`])}
+		var names []string
+		switch v := data[`columns`].(type) {
+		case string:
+			names = strings.Split(v, `,`)
+		case []interface{}:
+			for _, name := range v {
+				names = append(names, fmt.Sprint(name))
+			}
+		}
+		for _, name := range names {
+			if name = converter.EscapeSQL(strings.ToLower(strings.TrimSpace(name))); len(name) > 0 {
+				index.Columns = append(index.Columns, name)
+			}
+		}
+		if len(index.Columns) == 0 {
+			return nil, nil, fmt.Errorf(`columns of index are undefined`)
+		}
+		indexes = append(indexes, index)
+	}
+	return
+}
+
+// checkIndexes checks that the indexed columns are defined and the count of the indexes
+// doesn't exceed max_indexes
+func checkIndexes(indexes []*tableIndex, columns map[string]bool, count int) error {
+	if count+len(indexes) > syspar.GetMaxIndexes() {
+		log.WithFields(log.Fields{"size": count + len(indexes), "max_size": syspar.GetMaxIndexes(),
+			"type": consts.ParameterExceeded}).Error("Too many indexes")
+		return fmt.Errorf(`Too many indexes. Limit is %d`, syspar.GetMaxIndexes())
+	}
+	used := make(map[string]bool)
+	for _, index := range indexes {
+		key := strings.Join(index.Columns, `,`)
+		if used[key] {
+			return fmt.Errorf(`There are the same indexes %s`, key)
+		}
+		used[key] = true
+		for _, col := range index.Columns {
+			if col != `id` && !columns[col] {
+				return fmt.Errorf(`column %s of index is not defined`, col)
+			}
+		}
+	}
+	return nil
+}
+
+// splitColumnType returns the type of the column and the index of the column type like "number index"
+// or "varchar unique"
+func splitColumnType(name, colType string) (string, *tableIndex) {
+	fields := strings.Fields(colType)
+	if len(fields) == 2 {
+		switch fields[1] {
+		case `index`:
+			return fields[0], &tableIndex{Columns: []string{name}}
+		case `unique`:
+			return fields[0], &tableIndex{Columns: []string{name}, Unique: true}
+		}
+	}
+	return colType, nil
+}
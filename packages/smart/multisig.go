@@ -0,0 +1,371 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The settings of the multi-signature contract. For example,
+//
+//	settings {
+//	    multisig_signers = "101,102,103"
+//	    multisig_roles = "5"
+//	    multisig_threshold = 2
+//	    multisig_expiration = 86400
+//	}
+//
+// The signers are the keys and the members of the roles of the ecosystem. The direct call of the contract
+// is kept as pending until the threshold of the approvals is reached, the action is run by the last
+// approval with the key of the initiator. The call expires after multisig_expiration seconds of the block time.
+const (
+	multisigSigners    = `multisig_signers`
+	multisigRoles      = `multisig_roles`
+	multisigExpiration = `multisig_expiration`
+
+	defaultMultisigExpiration = 7 * 24 * 3600
+)
+
+type multisig struct {
+	signers    []int64
+	roles      []int64
+	threshold  int64
+	expiration int64
+}
+
+func settingList(value interface{}) []int64 {
+	var list []int64
+	if value == nil {
+		return list
+	}
+	for _, item := range strings.Split(fmt.Sprint(value), `,`) {
+		if id := converter.StrToInt64(strings.TrimSpace(item)); id != 0 {
+			list = append(list, id)
+		}
+	}
+	return list
+}
+
+// getMultisig returns the multi-signature settings of the contract or nil if the contract doesn't have them
+func getMultisig(contract *Contract) (*multisig, error) {
+	settings := contract.Block.Info.(*script.ContractInfo).Settings
+	threshold, ok := settings[script.MultisigThreshold]
+	if !ok {
+		return nil, nil
+	}
+	ms := &multisig{
+		signers:    settingList(settings[multisigSigners]),
+		roles:      settingList(settings[multisigRoles]),
+		threshold:  converter.StrToInt64(fmt.Sprint(threshold)),
+		expiration: defaultMultisigExpiration,
+	}
+	if expiration, ok := settings[multisigExpiration]; ok {
+		ms.expiration = converter.StrToInt64(fmt.Sprint(expiration))
+	}
+	if ms.threshold <= 0 || ms.expiration <= 0 || (len(ms.signers) == 0 && len(ms.roles) == 0) ||
+		(len(ms.roles) == 0 && ms.threshold > int64(len(ms.signers))) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "contract_name": contract.Name}).Error("wrong multi-signature settings")
+		return nil, fmt.Errorf(`multi-signature settings of %s contract are not valid`, contract.Name)
+	}
+	return ms, nil
+}
+
+// isSigner returns true if the key is the signer or the member of the role of the multi-signature contract
+func (sc *SmartContract) isSigner(ms *multisig, keyID int64) (bool, error) {
+	for _, signer := range ms.signers {
+		if signer == keyID {
+			return true, nil
+		}
+	}
+	for _, role := range ms.roles {
+		ok, err := sc.storage().HasRole(sc, sc.TxSmart.EcosystemID, keyID, role)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (sc *SmartContract) blockTime() int64 {
+	if sc.BlockData != nil {
+		return sc.BlockData.Time
+	}
+	return sc.TxSmart.Time
+}
+
+func (sc *SmartContract) blockID() int64 {
+	if sc.BlockData != nil {
+		return sc.BlockData.BlockID
+	}
+	return 0
+}
+
+// parkMultisig keeps the call of the multi-signature contract as pending instead of running its action.
+// It returns false if the initiator approves the call alone and the action can be run.
+func (sc *SmartContract) parkMultisig(ms *multisig) (bool, error) {
+	approvals := make([]int64, 0, ms.threshold)
+	signer, err := sc.isSigner(ms, sc.TxSmart.KeyID)
+	if err != nil {
+		return false, err
+	}
+	if signer {
+		if ms.threshold <= 1 {
+			return false, nil
+		}
+		approvals = append(approvals, sc.TxSmart.KeyID)
+	}
+	params := make(map[string]interface{})
+	if fields := sc.TxContract.Block.Info.(*script.ContractInfo).Tx; fields != nil {
+		for _, field := range *fields {
+			params[field.Name] = sc.TxData[field.Name]
+			if field.ContainsTag(script.TagFile) {
+				params[field.Name+`MimeType`] = sc.TxData[field.Name+`MimeType`]
+			}
+		}
+	}
+	outParams, err := json.Marshal(params)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling multi-signature params")
+		return false, err
+	}
+	outApprovals, err := json.Marshal(approvals)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling approvals")
+		return false, err
+	}
+	time := sc.blockTime()
+	_, id, err := sc.selectiveLoggingAndUpd([]string{`ecosystem`, `contract`, `params`, `key_id`, `tx_hash`,
+		`block_id`, `threshold`, `approvals`, `time`, `expiration`, `status`},
+		[]interface{}{sc.TxSmart.EcosystemID, sc.TxContract.Name, string(outParams), sc.TxSmart.KeyID,
+			fmt.Sprintf(`%x`, sc.TxHash), sc.blockID(), ms.threshold, string(outApprovals), time,
+			time + ms.expiration, model.MultisigPending}, model.MultisigTableName, nil, nil, !sc.VDE && sc.Rollback, false)
+	if err != nil {
+		return false, err
+	}
+	(*sc.TxContract.Extend)[`result`] = id
+	return true, nil
+}
+
+// isParked returns true if the transaction has been kept as the pending call
+func (sc *SmartContract) isParked() (bool, error) {
+	row, err := sc.getRow(model.MultisigTableName, []string{`tx_hash`}, []string{fmt.Sprintf(`%x`, sc.TxHash)})
+	if err != nil {
+		return false, err
+	}
+	return row != nil && row[`status`] != model.MultisigExecuted, nil
+}
+
+func (sc *SmartContract) getMultisigCall(id int64) (map[string]string, error) {
+	return sc.getRow(model.MultisigTableName, []string{`id`}, []string{converter.Int64ToStr(id)})
+}
+
+// multisigValue converts the JSON value of the parameter of the pending call to the type of the field
+func multisigValue(field *script.FieldInfo, v interface{}) (interface{}, error) {
+	if num, ok := v.(json.Number); ok {
+		v = num.String()
+	}
+	if v == nil {
+		return reflect.New(field.Type).Elem().Interface(), nil
+	}
+	switch field.Type.String() {
+	case `int64`:
+		return converter.ValueToInt(v)
+	case `uint64`:
+		val, err := strconv.ParseUint(fmt.Sprint(v), 10, 64)
+		return val, err
+	case `float64`:
+		return Float(v), nil
+	case script.Decimal:
+		return script.ValueToDecimal(v)
+	case `string`:
+		return fmt.Sprint(v), nil
+	case `[]uint8`:
+		// the file is kept as the bytes which JSON encodes to base64, other values are hex strings
+		if !field.ContainsTag(script.TagFile) {
+			return fmt.Sprint(v), nil
+		}
+		val, ok := v.(string)
+		if !ok {
+			break
+		}
+		data, err := base64.StdEncoding.DecodeString(val)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "name": field.Name}).Error("decoding file of multi-signature params")
+			return nil, err
+		}
+		return data, nil
+	case `bool`:
+		val, ok := v.(bool)
+		if !ok {
+			break
+		}
+		return val, nil
+	case `[]interface {}`:
+		list, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+		for i, item := range list {
+			if num, ok := item.(json.Number); ok {
+				list[i] = num.String()
+			}
+		}
+		return list, nil
+	case `map[string]interface {}`:
+		if val, ok := v.(map[string]interface{}); ok {
+			return val, nil
+		}
+	}
+	return nil, fmt.Errorf(`wrong value of %s parameter`, field.Name)
+}
+
+// MultisigApproved returns the name of the approved multi-signature contract which is being run
+func (sc *SmartContract) MultisigApproved() string {
+	return sc.multisigApproved
+}
+
+// execMultisig runs the approved call of the multi-signature contract with the key of the initiator
+func (sc *SmartContract) execMultisig(rt *script.RunTime, contract *Contract, call map[string]string) (string, error) {
+	var params map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewBufferString(call[`params`]))
+	decoder.UseNumber()
+	if err := decoder.Decode(&params); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling multi-signature params")
+		return ``, err
+	}
+	names := make([]string, 0)
+	values := make([]interface{}, 0)
+	if fields := contract.Block.Info.(*script.ContractInfo).Tx; fields != nil {
+		for _, field := range *fields {
+			val, err := multisigValue(field, params[field.Name])
+			if err != nil {
+				return ``, err
+			}
+			names = append(names, field.Name)
+			values = append(values, val)
+			if field.ContainsTag(script.TagFile) {
+				mimeType, _ := params[field.Name+`MimeType`].(string)
+				names = append(names, field.Name+`MimeType`)
+				values = append(values, mimeType)
+			}
+		}
+	}
+	if len(values) == 0 {
+		values = append(values, ``)
+	}
+	extend := *sc.TxContract.Extend
+	keyID := extend[`key_id`]
+	extend[`key_id`] = converter.StrToInt64(call[`key_id`])
+	sc.multisigApproved = contract.Name
+	defer func() {
+		extend[`key_id`] = keyID
+		sc.multisigApproved = ``
+	}()
+	ret, err := script.ExecContract(rt, contract.Name, strings.Join(names, `,`), values...)
+	if err != nil {
+		return ``, err
+	}
+	if ret == nil {
+		return ``, nil
+	}
+	return fmt.Sprint(ret), nil
+}
+
+// MultisigApprove approves the pending call of the multi-signature contract. The action of the contract
+// is run when the count of the approvals reaches the threshold. It returns the status of the call.
+func MultisigApprove(rt *script.RunTime, sc *SmartContract, id int64) (string, error) {
+	if !accessContracts(sc, `ApproveMultisig`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("MultisigApprove can be only called from @1ApproveMultisig")
+		return ``, fmt.Errorf(`MultisigApprove can be only called from ApproveMultisig`)
+	}
+	call, err := sc.getMultisigCall(id)
+	if err != nil {
+		return ``, err
+	}
+	if call == nil || converter.StrToInt64(call[`ecosystem`]) != sc.TxSmart.EcosystemID {
+		log.WithFields(log.Fields{"type": consts.NotFound, "id": id}).Error("multi-signature call has not been found")
+		return ``, fmt.Errorf(`multi-signature call %d has not been found`, id)
+	}
+	m := &model.Multisig{Status: call[`status`], Expiration: converter.StrToInt64(call[`expiration`]),
+		Approvals: call[`approvals`]}
+	if state := m.State(sc.blockTime()); state != model.MultisigPending {
+		return ``, fmt.Errorf(`multi-signature call %d is %s`, id, state)
+	}
+	contract := VMGetContract(sc.VM, call[`contract`], uint32(sc.TxSmart.EcosystemID))
+	if contract == nil {
+		return ``, fmt.Errorf(`unknown contract %s`, call[`contract`])
+	}
+	ms, err := getMultisig(contract)
+	if err != nil {
+		return ``, err
+	}
+	if ms == nil {
+		return ``, fmt.Errorf(`%s is not a multi-signature contract`, contract.Name)
+	}
+	if signer, err := sc.isSigner(ms, sc.TxSmart.KeyID); err != nil {
+		return ``, err
+	} else if !signer {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": sc.TxSmart.KeyID, "id": id}).Error("approval of not signer")
+		return ``, errAccessDenied
+	}
+	approvals, err := m.ApprovedBy()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling approvals")
+		return ``, err
+	}
+	for _, key := range approvals {
+		if key == sc.TxSmart.KeyID {
+			return ``, fmt.Errorf(`multi-signature call %d has been approved by %d`, id, key)
+		}
+	}
+	approvals = append(approvals, sc.TxSmart.KeyID)
+	out, err := json.Marshal(approvals)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling approvals")
+		return ``, err
+	}
+	status, result := model.MultisigPending, ``
+	if int64(len(approvals)) >= converter.StrToInt64(call[`threshold`]) {
+		if result, err = sc.execMultisig(rt, contract, call); err != nil {
+			return ``, err
+		}
+		status = model.MultisigExecuted
+	}
+	_, _, err = sc.selectiveLoggingAndUpd([]string{`approvals`, `status`, `result`},
+		[]interface{}{string(out), status, result}, model.MultisigTableName, []string{`id`},
+		[]string{converter.Int64ToStr(id)}, !sc.VDE && sc.Rollback, true)
+	if err != nil {
+		return ``, err
+	}
+	return status, nil
+}
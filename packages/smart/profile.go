@@ -0,0 +1,57 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"sync"
+
+	"github.com/GenesisKernel/go-genesis/packages/script"
+)
+
+// maxFailedProfiles is the count of the kept profiles of the failed transactions
+const maxFailedProfiles = 1000
+
+// failedProfiles keeps the fuel profiles of the last failed transactions of the node
+var failedProfiles = struct {
+	sync.Mutex
+	profiles map[string]*script.Profile
+	hashes   []string
+}{profiles: make(map[string]*script.Profile)}
+
+// SaveFailedProfile keeps the profile of the failed transaction. The oldest profile is removed
+// if there are more than maxFailedProfiles ones.
+func SaveFailedProfile(hash []byte, profile *script.Profile) {
+	failedProfiles.Lock()
+	defer failedProfiles.Unlock()
+
+	key := string(hash)
+	if _, ok := failedProfiles.profiles[key]; !ok {
+		failedProfiles.hashes = append(failedProfiles.hashes, key)
+	}
+	failedProfiles.profiles[key] = profile
+	if len(failedProfiles.hashes) > maxFailedProfiles {
+		delete(failedProfiles.profiles, failedProfiles.hashes[0])
+		failedProfiles.hashes = failedProfiles.hashes[1:]
+	}
+}
+
+// FailedProfile returns the profile of the failed transaction or nil if it has not been kept
+func FailedProfile(hash []byte) *script.Profile {
+	failedProfiles.Lock()
+	defer failedProfiles.Unlock()
+	return failedProfiles.profiles[string(hash)]
+}
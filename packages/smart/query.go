@@ -0,0 +1,333 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Query is the structured query of DBFind with the joined table, the grouping and the sums.
+// The names of the tables without the ecosystem prefix are the aliases of the tables, the columns
+// of the joined table are specified with them like "keys.amount". The columns without the alias
+// belong to the main table.
+type Query struct {
+	Table     string
+	Columns   string
+	Join      string
+	On        string
+	Where     string
+	WhereID   int64
+	GroupBy   string
+	Sum       string
+	Order     string
+	Offset    int64
+	Limit     int64
+	Ecosystem int64
+}
+
+type queryTable struct {
+	name    string // the full name of the table
+	alias   string
+	columns []string // the used columns which are checked by AccessColumns
+}
+
+type queryBuilder struct {
+	tables  []*queryTable
+	outputs map[string]bool // the names of the selected columns
+}
+
+func (b *queryBuilder) table(alias string) *queryTable {
+	for _, table := range b.tables {
+		if table.alias == alias {
+			return table
+		}
+	}
+	return nil
+}
+
+// column returns the SQL expression of the column and the name of the column in the result
+func (b *queryBuilder) column(name string) (expr, out string, err error) {
+	out = strings.TrimSpace(strings.ToLower(name))
+	path := strings.Split(out, `->`)
+	col := path[0]
+	table := b.tables[0]
+	if off := strings.IndexByte(col, '.'); off >= 0 {
+		if table = b.table(col[:off]); table == nil {
+			return ``, ``, fmt.Errorf(`table %s is not used in the query`, col[:off])
+		}
+		col = col[off+1:]
+	}
+	if col != converter.Sanitize(col, ``) || len(col) == 0 {
+		return ``, ``, fmt.Errorf(`column %s is not valid`, name)
+	}
+	for _, key := range path[1:] {
+		if key != converter.Sanitize(key, ``) || len(key) == 0 {
+			return ``, ``, fmt.Errorf(`column %s is not valid`, name)
+		}
+	}
+	table.columns = append(table.columns, col)
+	expr = fmt.Sprintf(`"%s"."%s"`, table.alias, col)
+	switch len(path) {
+	case 1:
+	case 2:
+		expr += fmt.Sprintf(`::jsonb->>'%s'`, path[1])
+	default:
+		expr += fmt.Sprintf(`::jsonb#>>'{%s}'`, strings.Join(path[1:], `,`))
+	}
+	return expr, strings.Join(path, `.`), nil
+}
+
+func (b *queryBuilder) columns(list string) (exprs, outs []string, err error) {
+	for _, name := range strings.Split(list, `,`) {
+		if len(strings.TrimSpace(name)) == 0 {
+			continue
+		}
+		expr, out, err := b.column(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		exprs = append(exprs, expr)
+		outs = append(outs, out)
+	}
+	return
+}
+
+func (b *queryBuilder) selectColumn(expr, out string) string {
+	b.outputs[out] = true
+	return fmt.Sprintf(`%s as "%s"`, expr, out)
+}
+
+func (b *queryBuilder) order(order string) (string, error) {
+	items := make([]string, 0)
+	for _, item := range strings.Split(order, `,`) {
+		fields := strings.Fields(strings.ToLower(item))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 || (len(fields) == 2 && fields[1] != `asc` && fields[1] != `desc`) {
+			return ``, fmt.Errorf(`order %s is not valid`, item)
+		}
+		expr := fmt.Sprintf(`"%s"`, fields[0])
+		if !b.outputs[fields[0]] {
+			var err error
+			if expr, _, err = b.column(fields[0]); err != nil {
+				return ``, err
+			}
+		}
+		items = append(items, strings.Join(append([]string{expr}, fields[1:]...), ` `))
+	}
+	return strings.Join(items, `, `), nil
+}
+
+// escapeQuery deletes the characters which are not allowed in the conditions of the query.
+// It is converter.Escape which keeps the dots of the table aliases and the numbers.
+func escapeQuery(data string) string {
+	out := make([]byte, 0, len(data))
+	for _, ch := range []byte(data) {
+		if (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
+			strings.IndexByte(`_ ,=!-'()"?*$#{}<>:.`, ch) >= 0 {
+			out = append(out, ch)
+		}
+	}
+	return string(out)
+}
+
+// PrepareQuery returns the SQL query and the names of the result columns. The joined table must belong
+// to the ecosystem of the main table. The read permissions of the tables and the columns are checked
+// in VDE the same as in DBSelect.
+func (sc *SmartContract) PrepareQuery(q *Query) (string, []string, error) {
+	if q.Ecosystem == 0 {
+		q.Ecosystem = sc.TxSmart.EcosystemID
+	}
+	b := &queryBuilder{outputs: make(map[string]bool)}
+	name := converter.EscapeSQL(GetTableName(sc, q.Table, q.Ecosystem))
+	prefix, alias := PrefixName(name)
+	b.tables = append(b.tables, &queryTable{name: name, alias: alias})
+	if len(q.Join) > 0 {
+		joinName := converter.EscapeSQL(GetTableName(sc, q.Join, q.Ecosystem))
+		joinPrefix, joinAlias := PrefixName(joinName)
+		if joinPrefix != prefix {
+			log.WithFields(log.Fields{"type": consts.AccessDenied, "table": name, "join": joinName}).Error("joining table of other ecosystem")
+			return ``, nil, fmt.Errorf(`table %s can not be joined with %s`, joinName, name)
+		}
+		if joinAlias == alias {
+			return ``, nil, fmt.Errorf(`table %s can not be joined with itself`, name)
+		}
+		if len(q.On) == 0 {
+			return ``, nil, fmt.Errorf(`join condition of %s is undefined`, joinName)
+		}
+		b.tables = append(b.tables, &queryTable{name: joinName, alias: joinAlias})
+	}
+
+	var selects, outs []string
+	groupExprs, groupOuts, err := b.columns(q.GroupBy)
+	if err != nil {
+		return ``, nil, err
+	}
+	columns := strings.TrimSpace(q.Columns)
+	if len(columns) == 0 || columns == `*` {
+		if len(b.tables) > 1 && len(groupExprs) == 0 && len(q.Sum) == 0 {
+			return ``, nil, fmt.Errorf(`columns of the joined tables must be specified`)
+		}
+		if len(q.GroupBy) == 0 && len(q.Sum) == 0 {
+			selects = append(selects, `*`)
+		}
+		for i, expr := range groupExprs {
+			selects = append(selects, b.selectColumn(expr, groupOuts[i]))
+			outs = append(outs, groupOuts[i])
+		}
+	} else {
+		exprs, names, err := b.columns(columns)
+		if err != nil {
+			return ``, nil, err
+		}
+		for i, expr := range exprs {
+			selects = append(selects, b.selectColumn(expr, names[i]))
+			outs = append(outs, names[i])
+		}
+	}
+	sumExprs, sumOuts, err := b.columns(q.Sum)
+	if err != nil {
+		return ``, nil, err
+	}
+	for i, expr := range sumExprs {
+		if strings.Contains(expr, `::jsonb`) {
+			expr = `(` + expr + `)::numeric`
+		}
+		out := `sum_` + sumOuts[i]
+		selects = append(selects, b.selectColumn(`sum(`+expr+`)`, out))
+		outs = append(outs, out)
+	}
+
+	query := fmt.Sprintf(`select %s from "%s" as "%s"`, strings.Join(selects, `, `), name, alias)
+	if len(b.tables) > 1 {
+		query += fmt.Sprintf(` inner join "%s" as "%s" on %s`, b.tables[1].name, b.tables[1].alias,
+			PrepareWhere(escapeQuery(q.On)))
+	}
+	where := PrepareWhere(strings.Replace(escapeQuery(q.Where), `$`, `?`, -1))
+	if q.WhereID != 0 {
+		where = fmt.Sprintf(`"%s"."id" = '%d'`, alias, q.WhereID)
+		q.Limit = 1
+	}
+	if len(where) > 0 {
+		query += ` where ` + where
+	}
+	if len(groupExprs) > 0 {
+		query += ` group by ` + strings.Join(groupExprs, `, `)
+	}
+	order, err := b.order(q.Order)
+	if err != nil {
+		return ``, nil, err
+	}
+	if len(order) == 0 {
+		if len(groupExprs) > 0 {
+			order = strings.Join(groupExprs, `, `)
+		} else if len(q.Sum) == 0 {
+			order = fmt.Sprintf(`"%s"."id"`, alias)
+		}
+	}
+	if len(order) > 0 {
+		query += ` order by ` + order
+	}
+	if q.Limit == 0 {
+		q.Limit = 25
+	}
+	if q.Limit < 0 || q.Limit > 250 {
+		q.Limit = 250
+	}
+	query += fmt.Sprintf(` offset %d limit %d`, q.Offset, q.Limit)
+
+	if sc.VDE {
+		for _, table := range b.tables {
+			perm, err := sc.AccessTablePerm(table.name, `read`)
+			if err != nil {
+				return ``, nil, err
+			}
+			if perm != nil && len(perm[`filter`]) > 0 {
+				log.WithFields(log.Fields{"type": consts.AccessDenied, "table": table.name}).Error("query of filtered table")
+				return ``, nil, errAccessDenied
+			}
+			if len(table.columns) == 0 {
+				continue
+			}
+			cols := uniqueColumns(table.columns)
+			count := len(cols)
+			if err = sc.AccessColumns(table.name, &cols, false); err != nil {
+				return ``, nil, err
+			}
+			if len(cols) != count {
+				return ``, nil, errAccessDenied
+			}
+		}
+	}
+	return query, outs, nil
+}
+
+func uniqueColumns(columns []string) []string {
+	used := make(map[string]bool)
+	ret := make([]string, 0, len(columns))
+	for _, col := range columns {
+		if !used[col] {
+			used[col] = true
+			ret = append(ret, col)
+		}
+	}
+	return ret
+}
+
+// DBSelectQuery returns the rows of the query with the joined table, the grouping and the sums
+func DBSelectQuery(sc *SmartContract, tblname, columns, join, on, groupBy, sum string, id int64, order string,
+	offset, limit, ecosystem int64, where string, params []interface{}) (int64, []interface{}, error) {
+	return sc.storage().SelectQuery(sc, &Query{Table: tblname, Columns: columns, Join: join, On: on,
+		Where: where, WhereID: id, GroupBy: groupBy, Sum: sum, Order: order, Offset: offset,
+		Limit: limit, Ecosystem: ecosystem}, params)
+}
+
+// SelectQuery returns the rows of the query and its cost
+func (dbStorage) SelectQuery(sc *SmartContract, q *Query, params []interface{}) (int64, []interface{}, error) {
+	query, _, err := sc.PrepareQuery(q)
+	if err != nil {
+		return 0, nil, err
+	}
+	cost, err := sc.queryCoster().QueryCost(sc.DbTransaction, query)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": query}).Error("getting query cost")
+		return 0, nil, err
+	}
+	list, err := model.GetAllTransaction(sc.DbTransaction, query, -1, params...)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting rows of query")
+		return 0, nil, err
+	}
+	result := make([]interface{}, len(list))
+	for i, row := range list {
+		for key, val := range row {
+			if val == `NULL` {
+				row[key] = ``
+			}
+		}
+		result[i] = row
+	}
+	return cost, result, nil
+}
@@ -26,24 +26,19 @@ import (
 	"github.com/GenesisKernel/go-genesis/packages/consts"
 	"github.com/GenesisKernel/go-genesis/packages/converter"
 	"github.com/GenesisKernel/go-genesis/packages/model"
-	"github.com/GenesisKernel/go-genesis/packages/model/querycost"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// The errors which are returned by Storage if the row doesn't exist
 var (
-	errUpdNotExistRecord = errors.New(`Update for not existing record`)
+	ErrUpdNotExistRecord = errors.New(`Update for not existing record`)
+	ErrDelNotExistRecord = errors.New(`Delete for not existing record`)
 )
 
 func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []interface{},
 	table string, whereFields, whereValues []string, generalRollback bool, exists bool) (int64, string, error) {
-	queryCoster := querycost.GetQueryCoster(querycost.FormulaQueryCosterType)
-	var (
-		tableID         string
-		err             error
-		cost            int64
-		rollbackInfoStr string
-	)
+	var err error
 	logger := sc.GetLogger()
 
 	if generalRollback && sc.BlockData == nil {
@@ -59,6 +54,15 @@ func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []inter
 					ivalues[i] = vbyte
 				}
 			}
+		} else {
+			switch val := v.(type) {
+			case []byte:
+				ivalues[i] = byteaValue(val)
+			case []interface{}:
+				if ivalues[i], err = arrayValue(val); err != nil {
+					return 0, ``, err
+				}
+			}
 		}
 	}
 
@@ -67,10 +71,26 @@ func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []inter
 		return 0, ``, err
 	}
 
-	addSQLFields := `id,`
 	for i, field := range fields {
-		field = strings.TrimSpace(strings.ToLower(field))
-		fields[i] = field
+		fields[i] = strings.TrimSpace(strings.ToLower(field))
+	}
+	return sc.storage().Update(sc, fields, values, table, whereFields, whereValues, generalRollback, exists)
+}
+
+// Update updates the row of the table and keeps the previous values in rollback_tx
+func (dbStorage) Update(sc *SmartContract, fields, values []string, table string, whereFields, whereValues []string,
+	generalRollback, exists bool) (int64, string, error) {
+	queryCoster := sc.queryCoster()
+	var (
+		tableID         string
+		err             error
+		cost            int64
+		rollbackInfoStr string
+	)
+	logger := sc.GetLogger()
+
+	addSQLFields := `id,`
+	for _, field := range fields {
 		if field[:1] == "+" || field[:1] == "-" {
 			addSQLFields += field[1:] + ","
 		} else if strings.HasPrefix(field, `timestamp `) {
@@ -109,11 +129,16 @@ func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []inter
 	}
 	cost += selectCost
 	if exists && len(logData) == 0 {
-		logger.WithFields(log.Fields{"type": consts.NotFound, "err": errUpdNotExistRecord, "query": selectQuery}).Error("updating for not existing record")
-		return 0, tableID, errUpdNotExistRecord
+		logger.WithFields(log.Fields{"type": consts.NotFound, "err": ErrUpdNotExistRecord, "query": selectQuery}).Error("updating for not existing record")
+		return 0, tableID, ErrUpdNotExistRecord
 	}
 	jsonFields := make(map[string]map[string]string)
 	if whereFields != nil && len(logData) > 0 {
+		byteaColumns, err := model.GetByteaColumns(sc.DbTransaction, table)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting bytea columns")
+			return 0, tableID, err
+		}
 		rollbackInfo := make(map[string]string)
 		for k, v := range logData {
 			if k == `id` {
@@ -121,6 +146,8 @@ func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []inter
 			}
 			if converter.IsByteColumn(table, k) && v != "" {
 				rollbackInfo[k] = string(converter.BinToHex([]byte(v)))
+			} else if byteaColumns[k] && v != `NULL` {
+				rollbackInfo[k] = byteaValue([]byte(v))
 			} else {
 				rollbackInfo[k] = v
 			}
@@ -298,6 +325,104 @@ func (sc *SmartContract) selectiveLoggingAndUpd(fields []string, ivalues []inter
 	return cost, tableID, nil
 }
 
+// deleteWithLogging deletes the row and keeps its values in rollback_tx so the row can be restored
+func (sc *SmartContract) deleteWithLogging(table, id string, generalRollback bool) (int64, error) {
+	logger := sc.GetLogger()
+
+	if generalRollback && sc.BlockData == nil {
+		logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("Block is undefined")
+		return 0, fmt.Errorf(`It is impossible to write to DB when Block is undefined`)
+	}
+	return sc.storage().Delete(sc, table, id, generalRollback)
+}
+
+// Delete deletes the row of the table and keeps its values in rollback_tx
+func (dbStorage) Delete(sc *SmartContract, table, id string, generalRollback bool) (int64, error) {
+	queryCoster := sc.queryCoster()
+	logger := sc.GetLogger()
+
+	selectQuery := `SELECT * FROM "` + table + `" WHERE id = ?`
+	cost, err := queryCoster.QueryCost(sc.DbTransaction, selectQuery, id)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": selectQuery}).Error("getting query total cost")
+		return 0, err
+	}
+	logData, err := model.GetOneRowTransaction(sc.DbTransaction, selectQuery, id).String()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": selectQuery}).Error("getting one row transaction")
+		return 0, err
+	}
+	if len(logData) == 0 {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "table": table, "id": id}).Error("deleting not existing record")
+		return 0, ErrDelNotExistRecord
+	}
+	deleteQuery := `DELETE FROM "` + table + `" WHERE id = ?`
+	deleteCost, err := queryCoster.QueryCost(sc.DbTransaction, deleteQuery, id)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": deleteQuery}).Error("getting query total cost for delete query")
+		return 0, err
+	}
+	cost += deleteCost
+	if err = model.DeleteTx(sc.DbTransaction, table, `WHERE id = ?`, id); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": deleteQuery}).Error("executing delete query")
+		return 0, err
+	}
+	if generalRollback {
+		byteaColumns, err := model.GetByteaColumns(sc.DbTransaction, table)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting bytea columns")
+			return 0, err
+		}
+		for k, v := range logData {
+			if converter.IsByteColumn(table, k) && v != "" {
+				logData[k] = string(converter.BinToHex([]byte(v)))
+			} else if byteaColumns[k] && v != `NULL` {
+				logData[k] = byteaValue([]byte(v))
+			}
+		}
+		jsonRollbackInfo, err := json.Marshal(logData)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling deleted row to json")
+			return 0, err
+		}
+		rollbackTx := &model.RollbackTx{
+			BlockID:   sc.BlockData.BlockID,
+			TxHash:    sc.TxHash,
+			NameTable: table,
+			TableID:   id,
+			Data:      string(jsonRollbackInfo),
+			Deleted:   true,
+		}
+		if err = rollbackTx.Create(sc.DbTransaction); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating rollback tx")
+			return 0, err
+		}
+	}
+	return cost, nil
+}
+
+// byteaValue returns the bytes in the hex format of bytea values
+func byteaValue(val []byte) string {
+	return `\x` + hex.EncodeToString(val)
+}
+
+// arrayValue returns the literal of the array of PostgreSQL
+func arrayValue(list []interface{}) (string, error) {
+	items := make([]string, len(list))
+	for i, item := range list {
+		if item == nil {
+			items[i] = `NULL`
+			continue
+		}
+		val, err := converter.InterfaceToStr(item)
+		if err != nil {
+			return ``, err
+		}
+		items[i] = `"` + strings.Replace(strings.Replace(val, `\`, `\\`, -1), `"`, `\"`, -1) + `"`
+	}
+	return `{` + strings.Join(items, `,`) + `}`, nil
+}
+
 func escapeSingleQuotes(val string) string {
 	return strings.Replace(val, `'`, `''`, -1)
 }
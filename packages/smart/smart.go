@@ -21,6 +21,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -62,6 +65,8 @@ const (
 	CallRollback = 0x08
 
 	MaxPrice = 100000000000000000
+	// MaxErrorLen is the maximum length of the error which is saved in the status of the transaction
+	MaxErrorLen = 255
 )
 
 var (
@@ -77,6 +82,7 @@ var (
 	ErrFuelRate       = errors.New(`Fuel rate must be greater than 0`)
 	ErrIncorrectSign  = errors.New(`incorrect sign`)
 	ErrInvalidValue   = errors.New(`Invalid value`)
+	ErrLibraryCall    = errors.New(`Library cannot be called as contract`)
 	ErrUnknownNodeID  = errors.New(`Unknown node id`)
 	ErrWrongPriceFunc = errors.New(`Wrong type of price function`)
 	ErrNegPrice       = errors.New(`Price value is negative`)
@@ -140,6 +146,57 @@ func vmCompile(vm *script.VM, src string, owner *script.OwnerInfo) error {
 	return vm.Compile([]rune(src), owner)
 }
 
+// vmCompileCached loads the byte-code of the source from the cache of the compiled contracts.
+// If the byte-code is missing or it cannot be loaded then the source is compiled and
+// the byte-code is saved into the cache.
+func vmCompileCached(vm *script.VM, src string, owner *script.OwnerInfo) error {
+	dir := conf.Config.ContractCacheDir
+	if len(dir) == 0 {
+		return vmCompile(vm, src, owner)
+	}
+	path := filepath.Join(dir, vm.BytecodeKey(src, owner))
+	if data, err := ioutil.ReadFile(path); err == nil {
+		if root, err := vm.DecodeBlock(data, owner); err == nil {
+			vm.FlushBlock(root)
+			return nil
+		}
+		log.WithFields(log.Fields{"type": consts.VMError, "path": path}).Warning("loading compiled contract, compiling it again")
+	}
+	root, err := VMCompileBlock(vm, src, owner)
+	if err != nil {
+		return err
+	}
+	if data, err := vm.EncodeBlock(root); err == nil {
+		if err = saveBytecode(dir, path, data); err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": path}).Error("saving compiled contract")
+		}
+	}
+	vm.FlushBlock(root)
+	return nil
+}
+
+// saveBytecode writes the byte-code into the temporary file and renames it so
+// the cache never contains the partially written files
+func saveBytecode(dir, path string, data []byte) error {
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(dir, `tmp`)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err = file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
 // VMCompileBlock is compiling block
 func VMCompileBlock(vm *script.VM, src string, owner *script.OwnerInfo) (*script.Block, error) {
 	return vm.CompileBlock([]rune(src), owner)
@@ -168,6 +225,15 @@ func VMRun(vm *script.VM, block *script.Block, params []interface{}, extend *map
 		cost = ecost.(int64)
 	}
 	rt := vm.RunInit(cost)
+	if sc, ok := (*extend)[`sc`].(*SmartContract); ok {
+		rt.SetCostSchedule(sc.costSchedule())
+		if sc.Debugger != nil {
+			rt.SetDebugger(sc.Debugger)
+		}
+		if sc.Profiler != nil {
+			rt.SetProfiler(sc.Profiler)
+		}
+	}
 	ret, err = rt.Run(block, params, extend)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("running block in smart vm")
@@ -301,6 +367,9 @@ func SetContractWallet(sc *SmartContract, tblid, state int64, wallet int64) erro
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("SetContractWallet can be only called from @1EditContract")
 		return fmt.Errorf(`SetContractWallet can be only called from @1EditContract`)
 	}
+	if err := sc.checkMemory(`SetContractWallet`); err != nil {
+		return err
+	}
 	for i, item := range smartVM.Block.Children {
 		if item != nil && item.Type == script.ObjContract {
 			cinfo := item.Info.(*script.ContractInfo)
@@ -361,7 +430,11 @@ func LoadContracts(transaction *model.DbTransaction) error {
 
 func LoadSysFuncs(vm *script.VM, state int) error {
 	code := `func DBFind(table string).Columns(columns string).Where(where string, params ...)
-	.WhereId(id int).Order(order string).Limit(limit int).Offset(offset int).Ecosystem(ecosystem int) array {
+	.WhereId(id int).Order(order string).Limit(limit int).Offset(offset int).Ecosystem(ecosystem int)
+	.Join(join string, on string).GroupBy(group string).Sum(sum string) array {
+   if join || group || sum {
+	   return DBSelectQuery(table, columns, join, on, group, sum, id, order, offset, limit, ecosystem, where, params)
+   }
    return DBSelect(table, columns, id, order, offset, limit, ecosystem, where, params)
 }
 
@@ -463,8 +536,9 @@ func LoadContract(transaction *model.DbTransaction, prefix string) (err error) {
 			TableID:  converter.StrToInt64(item[`id`]),
 			WalletID: converter.StrToInt64(item[`wallet_id`]),
 			TokenID:  converter.StrToInt64(item[`token_id`]),
+			Version:  converter.StrToInt64(item[`version`]),
 		}
-		if err = Compile(item[`value`], &owner); err != nil {
+		if err = vmCompileCached(smartVM, item[`value`], &owner); err != nil {
 			log.WithFields(log.Fields{"type": consts.EvalError, "names": names, "error": err}).Error("Load Contract")
 		} else {
 			log.WithFields(log.Fields{"contract_name": names, "contract_id": item["id"], "contract_active": item["active"]}).Info("OK Loading Contract")
@@ -811,22 +885,37 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 		public                        []byte
 		sizeFuel, toID, fromID, price int64
 		fuelRate                      decimal.Decimal
+		sponsored                     *sponsor
 	)
 	logger := sc.GetLogger()
 	payWallet := &model.Key{}
 	sc.TxContract.Extend = sc.getExtend()
 
 	retError := func(err error) (string, error) {
-		eText := err.Error()
-		if !strings.HasPrefix(eText, `{`) {
-			err = script.SetVMError(`panic`, eText)
-		}
-		return ``, err
+		return ``, script.ErrorTrace(err, `panic`, MaxErrorLen)
 	}
 
 	methods := []string{`init`, `conditions`, `action`, `rollback`}
+	if sc.TxContract.Block.Info.(*script.ContractInfo).Library {
+		logger.WithFields(log.Fields{"type": consts.ContractError, "contract_name": sc.TxContract.Name}).Error("library is called as contract")
+		return retError(ErrLibraryCall)
+	}
 	sc.AppendStack(sc.TxContract.Name)
 	sc.VM = GetVM(sc.VDE, sc.TxSmart.EcosystemID)
+	ms, err := getMultisig(sc.TxContract)
+	if err != nil {
+		return retError(err)
+	}
+	if ms != nil && (flags&CallRollback) != 0 {
+		// the rollback function is not called if the action has not been run
+		parked, err := sc.isParked()
+		if err != nil {
+			return retError(err)
+		}
+		if parked {
+			flags &^= CallRollback
+		}
+	}
 	if (flags&CallRollback) == 0 && (flags&CallAction) != 0 {
 		if !sc.VDE {
 			toID = sc.BlockData.KeyID
@@ -835,17 +924,17 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 		if len(sc.TxSmart.PublicKey) > 0 && string(sc.TxSmart.PublicKey) != `null` {
 			public = sc.TxSmart.PublicKey
 		}
-		wallet := &model.Key{}
-		wallet.SetTablePrefix(sc.TxSmart.EcosystemID)
 		signedBy := sc.TxSmart.KeyID
 		if sc.TxSmart.SignedBy != 0 {
 			signedBy = sc.TxSmart.SignedBy
 		}
-		_, err = wallet.Get(signedBy)
-		if err != nil {
-			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting wallet")
+		var wallet *model.Key
+		if wallet, err = sc.getKey(sc.TxSmart.EcosystemID, signedBy); err != nil {
 			return retError(err)
 		}
+		if wallet == nil {
+			wallet = &model.Key{}
+		}
 		if wallet.Deleted == 1 {
 			return retError(ErrDeletedKey)
 		}
@@ -860,20 +949,22 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 			}
 			public = node.PublicKey
 		}
-		if len(public) == 0 {
-			logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("empty public key")
-			return retError(ErrEmptyPublicKey)
-		}
-		sc.PublicKeys = append(sc.PublicKeys, public)
-		var CheckSignResult bool
-		CheckSignResult, err = utils.CheckSign(sc.PublicKeys, sc.TxData[`forsign`].(string), sc.TxSmart.BinSignatures, false)
-		if err != nil {
-			logger.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("checking tx data sign")
-			return retError(err)
-		}
-		if !CheckSignResult {
-			logger.WithFields(log.Fields{"type": consts.InvalidObject}).Error("incorrect sign")
-			return retError(ErrIncorrectSign)
+		if !sc.SkipSign {
+			if len(public) == 0 {
+				logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("empty public key")
+				return retError(ErrEmptyPublicKey)
+			}
+			sc.PublicKeys = append(sc.PublicKeys, public)
+			var CheckSignResult bool
+			CheckSignResult, err = utils.CheckSign(sc.PublicKeys, sc.TxData[`forsign`].(string), sc.TxSmart.BinSignatures, false)
+			if err != nil {
+				logger.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("checking tx data sign")
+				return retError(err)
+			}
+			if !CheckSignResult {
+				logger.WithFields(log.Fields{"type": consts.InvalidObject}).Error("incorrect sign")
+				return retError(ErrIncorrectSign)
+			}
 		}
 		if sc.TxSmart.EcosystemID > 0 && !sc.VDE && !conf.Config.PrivateBlockchain {
 			if sc.TxSmart.TokenEcosystem == 0 {
@@ -909,15 +1000,23 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 				}
 				fuelRate = fuelRate.Add(payOver)
 			}
-			payWallet.SetTablePrefix(sc.TxSmart.TokenEcosystem)
-			if found, err := payWallet.Get(fromID); err != nil || !found {
-				if !found {
-					return retError(ErrCurrentBalance)
+			if sc.TxSmart.Sponsor != 0 {
+				if isActive {
+					return retError(ErrSponsorActive)
 				}
-				logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting wallet")
+				fromID = sc.TxSmart.Sponsor
+			}
+			if payWallet, err = sc.getKey(sc.TxSmart.TokenEcosystem, fromID); err != nil {
 				return retError(err)
 			}
-			if !isActive && !bytes.Equal(wallet.PublicKey, payWallet.PublicKey) && !bytes.Equal(sc.TxSmart.PublicKey, payWallet.PublicKey) && sc.TxSmart.SignedBy == 0 {
+			if payWallet == nil {
+				return retError(ErrCurrentBalance)
+			}
+			if sc.TxSmart.Sponsor != 0 {
+				if sponsored, err = sc.checkSponsor(sc.TxSmart.TokenEcosystem, payWallet.PublicKey); err != nil {
+					return retError(err)
+				}
+			} else if !isActive && !bytes.Equal(wallet.PublicKey, payWallet.PublicKey) && !bytes.Equal(sc.TxSmart.PublicKey, payWallet.PublicKey) && sc.TxSmart.SignedBy == 0 {
 				return retError(ErrDiffKeys)
 			}
 			var amount decimal.Decimal
@@ -962,6 +1061,10 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 				logger.WithFields(log.Fields{"type": consts.NoFunds}).Error("current balance is not enough")
 				return retError(ErrCurrentBalance)
 			}
+			if sponsored != nil && sponsored.budget.Cmp(decimal.New(sizeFuel+price, 0).Mul(fuelRate)) <= 0 {
+				logger.WithFields(log.Fields{"type": consts.NoFunds, "sponsor": sc.TxSmart.Sponsor}).Error("sponsor budget is not enough")
+				return retError(ErrSponsorBudget)
+			}
 		}
 	}
 	before := (*sc.TxContract.Extend)[`txcost`].(int64) + price
@@ -980,6 +1083,16 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 			if cfunc == nil {
 				continue
 			}
+			if ms != nil && methods[i] == `action` {
+				var parked bool
+				if parked, err = sc.parkMultisig(ms); err != nil {
+					before -= price
+					break
+				}
+				if parked {
+					continue
+				}
+			}
 			sc.TxContract.Called = 1 << i
 			_, err = VMRun(sc.VM, cfunc, nil, sc.TxContract.Extend)
 			if err != nil {
@@ -997,6 +1110,11 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 			result = result[:255]
 		}
 	}
+	if err == nil && (flags&CallRollback) == 0 && (flags&CallAction) != 0 && !sc.VDE && sc.BlockData != nil {
+		if ierr := sc.saveTxContract(); ierr != nil {
+			return retError(ierr)
+		}
+	}
 	if (flags&CallRollback) == 0 && (flags&CallAction) != 0 && sc.TxSmart.EcosystemID > 0 &&
 		!sc.VDE && !conf.Config.PrivateBlockchain && sc.TxContract.Name != `@1NewUser` {
 		apl := sc.TxUsedCost.Mul(fuelRate)
@@ -1009,6 +1127,9 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 		if wltAmount.Cmp(apl) < 0 {
 			apl = wltAmount
 		}
+		if sponsored != nil && sponsored.budget.Cmp(apl) < 0 {
+			apl = sponsored.budget
+		}
 
 		commission := apl.Mul(decimal.New(syspar.SysInt64(`commission_size`), 0)).Div(decimal.New(100, 0)).Floor()
 		walletTable := model.KeyTableName(sc.TxSmart.TokenEcosystem)
@@ -1039,14 +1160,14 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 		}
 
 		if err := payCommission(converter.Int64ToStr(toID), apl.Sub(commission)); err != nil {
-			if err != errUpdNotExistRecord {
+			if err != ErrUpdNotExistRecord {
 				return retError(err)
 			}
 			apl = commission
 		}
 
 		if err := payCommission(syspar.GetCommissionWallet(sc.TxSmart.TokenEcosystem), commission); err != nil {
-			if err != errUpdNotExistRecord {
+			if err != ErrUpdNotExistRecord {
 				return retError(err)
 			}
 			apl = apl.Sub(commission)
@@ -1056,6 +1177,13 @@ func (sc *SmartContract) CallContract(flags int) (string, error) {
 			[]string{fromIDString}, true, true); ierr != nil {
 			return retError(errCommission)
 		}
+		// the fuel which has been paid by the sponsor is deducted from its budget
+		if sponsored != nil {
+			if _, _, ierr := sc.selectiveLoggingAndUpd([]string{`-budget`, `+spent`}, []interface{}{apl, apl},
+				model.SponsorTableName, []string{`id`}, []string{sponsored.id}, true, true); ierr != nil {
+				return retError(ierr)
+			}
+		}
 		logger.WithFields(log.Fields{"commission": commission}).Debug("Paid commission")
 	}
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/GenesisKernel/go-genesis/packages/conf/syspar"
 	"github.com/GenesisKernel/go-genesis/packages/consts"
@@ -30,6 +31,7 @@ import (
 	"github.com/GenesisKernel/go-genesis/packages/crypto"
 	"github.com/GenesisKernel/go-genesis/packages/language"
 	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/model/querycost"
 	"github.com/GenesisKernel/go-genesis/packages/script"
 	"github.com/GenesisKernel/go-genesis/packages/utils"
 	"github.com/GenesisKernel/go-genesis/packages/utils/metric"
@@ -40,11 +42,28 @@ import (
 
 var (
 	funcCallsDBP = map[string]struct{}{
-		"DBInsert":         {},
-		"DBUpdate":         {},
-		"DBUpdateSysParam": {},
-		"DBUpdateExt":      {},
-		"DBSelect":         {},
+		"AssetApprove":      {},
+		"AssetCreate":       {},
+		"AssetTransfer":     {},
+		"ChangeColumnType":  {},
+		"DBDelete":          {},
+		"DropColumn":        {},
+		"DBInsert":          {},
+		"DBUpdate":          {},
+		"DBUpdateSysParam":  {},
+		"DBUpdateExt":       {},
+		"DBSelect":          {},
+		"DBSelectQuery":     {},
+		"EmitEvent":         {},
+		"EscrowCancel":      {},
+		"EscrowLock":        {},
+		"EscrowRelease":     {},
+		"EscrowSettle":      {},
+		"SponsorSet":        {},
+		"TokenApprove":      {},
+		"TokenCreate":       {},
+		"TokenTransfer":     {},
+		"TokenTransferFrom": {},
 	}
 
 	extendCostSysParams = map[string]string{
@@ -80,6 +99,13 @@ var (
 		"GetContractByName": "extend_cost_contract_by_name",
 		"GetContractById":   "extend_cost_contract_by_id",
 	}
+
+	// costSchedules is the parsed value of fuel_schedule system parameter
+	costSchedules = struct {
+		sync.Mutex
+		value string
+		list  script.CostSchedules
+	}{}
 )
 
 const (
@@ -115,12 +141,84 @@ func getCostP(name string) int64 {
 	return -1
 }
 
+func getCostSchedules() script.CostSchedules {
+	value := syspar.SysString(syspar.FuelSchedule)
+	costSchedules.Lock()
+	defer costSchedules.Unlock()
+	if value != costSchedules.value {
+		list, err := script.ParseCostSchedules(value)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "error": err}).Error("parsing fuel schedule, using default one")
+		}
+		costSchedules.value, costSchedules.list = value, list
+	}
+	return costSchedules.list
+}
+
+// costSchedule returns the fuel schedule which is in force at the block of the transaction.
+// The schedule of the next block is used if the block is not defined.
+func (sc *SmartContract) costSchedule() *script.CostSchedule {
+	list := getCostSchedules()
+	if sc.VDE || len(list) == 0 {
+		return nil
+	}
+	if sc.BlockData != nil {
+		return list.Get(sc.BlockData.BlockID)
+	}
+	if model.DBConn == nil {
+		return nil
+	}
+	infoBlock := &model.InfoBlock{}
+	found, err := infoBlock.Get()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting last block, using default fuel schedule")
+		return nil
+	}
+	if !found {
+		return nil
+	}
+	return list.Get(infoBlock.BlockID + 1)
+}
+
+// queryCoster returns the coster of the database queries with the query costs of the fuel schedule
+func (sc *SmartContract) queryCoster() querycost.QueryCoster {
+	if costs := sc.costSchedule(); costs != nil && len(costs.Queries) > 0 {
+		return querycost.NewFormulaQueryCoster(costs.Queries)
+	}
+	return querycost.GetQueryCoster(querycost.FormulaQueryCosterType)
+}
+
+// checkCostSchedules checks that the new fuel schedules don't change the schedules of the processed blocks
+func checkCostSchedules(sc *SmartContract, value string) error {
+	list, err := script.ParseCostSchedules(value)
+	if err != nil || sc.BlockData == nil {
+		return err
+	}
+	processed := func(list script.CostSchedules) (ret script.CostSchedules) {
+		for _, item := range list {
+			if item.Block <= sc.BlockData.BlockID {
+				ret = append(ret, item)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(processed(list), processed(getCostSchedules())) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "block_id": sc.BlockData.BlockID}).Error("changing fuel schedule of processed blocks")
+		return fmt.Errorf(`fuel schedule of processed blocks cannot be changed`)
+	}
+	return nil
+}
+
 // UpdateSysParam updates the system parameter
 func UpdateSysParam(sc *SmartContract, name, value, conditions string) (int64, error) {
 	var (
 		fields []string
 		values []interface{}
 	)
+	// the system parameters are updated in the memory too
+	if err := sc.checkMemory(`DBUpdateSysParam`); err != nil {
+		return 0, err
+	}
 	par := &model.SystemParameter{}
 	found, err := par.Get(name)
 	if err != nil {
@@ -178,6 +276,11 @@ func UpdateSysParam(sc *SmartContract, name, value, conditions string) (int64, e
 				}
 			}
 			checked = true
+		case syspar.FuelSchedule:
+			if err := checkCostSchedules(sc, value); err != nil {
+				return 0, err
+			}
+			checked = true
 		case syspar.FullNodes:
 			fnodes := []syspar.FullNode{}
 			if err := json.Unmarshal([]byte(value), &fnodes); err != nil {
@@ -342,6 +445,9 @@ func CreateLanguage(sc *SmartContract, name, trans string, appID int64) (id int6
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("CreateLanguage can be only called from @1NewLang, @1NewLangJoint, @1Import")
 		return 0, fmt.Errorf(`CreateLanguage can be only called from @1NewLang, @1NewLangJoint, @1Import`)
 	}
+	if err = sc.checkMemory(`CreateLanguage`); err != nil {
+		return 0, err
+	}
 	idStr := converter.Int64ToStr(sc.TxSmart.EcosystemID)
 	if _, id, err = DBInsert(sc, `@`+idStr+"_languages", "name,res,app_id", name, trans, appID); err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("inserting new language")
@@ -357,6 +463,9 @@ func EditLanguage(sc *SmartContract, id int64, name, trans string, appID int64)
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("EditLanguage can be only called from @1EditLang, @1EditLangJoint and @1Import")
 		return fmt.Errorf(`EditLanguage can be only called from @1EditLang, @1EditLangJoint and @1Import`)
 	}
+	if err := sc.checkMemory(`EditLanguage`); err != nil {
+		return err
+	}
 	idStr := converter.Int64ToStr(sc.TxSmart.EcosystemID)
 	if _, err := DBUpdate(sc, `@`+idStr+"_languages", id, "name,res,app_id", name, trans, appID); err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("inserting new language")
@@ -422,6 +531,10 @@ func CreateEcosystem(sc *SmartContract, wallet int64, name string) (int64, error
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("CreateEcosystem can be only called from @1NewEcosystem")
 		return 0, fmt.Errorf(`CreateEcosystem can be only called from @1NewEcosystem`)
 	}
+	// the contracts of the new ecosystem are loaded into the virtual machine
+	if err := sc.checkMemory(`CreateEcosystem`); err != nil {
+		return 0, err
+	}
 
 	var sp model.StateParameter
 	sp.SetTablePrefix(`1`)
@@ -625,6 +738,10 @@ func RollbackTable(sc *SmartContract, name string) error {
 		return err
 	}
 
+	if err = model.DropIndexes(sc.DbTransaction, tableName, ``); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("dropping indexes")
+		return err
+	}
 	err = model.DropTable(sc.DbTransaction, tableName)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("dropping table")
@@ -667,7 +784,12 @@ func RollbackColumn(sc *SmartContract, tableName, name string) error {
 		// if there is not such hash then NewColumn was faulty. Do nothing.
 		return nil
 	}
-	return model.AlterTableDropColumn(getDefTableName(sc, tableName), name)
+	tblname := getDefTableName(sc, tableName)
+	if err = model.DropIndexes(sc.DbTransaction, tblname, name); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("dropping indexes of column")
+		return err
+	}
+	return model.AlterTableDropColumn(sc.DbTransaction, tblname, name)
 }
 
 // Size returns the length of the string
@@ -693,6 +815,9 @@ func Activate(sc *SmartContract, tblid int64, state int64) error {
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("ActivateContract can be only called from @1ActivateContract or @1DeactivateContract")
 		return fmt.Errorf(`ActivateContract can be only called from @1ActivateContract or @1DeactivateContract`)
 	}
+	if err := sc.checkMemory(`Activate`); err != nil {
+		return err
+	}
 	ActivateContract(tblid, state, true)
 	return nil
 }
@@ -703,6 +828,9 @@ func Deactivate(sc *SmartContract, tblid int64, state int64) error {
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("DeactivateContract can be only called from @1ActivateContract or @1DeactivateContract")
 		return fmt.Errorf(`DeactivateContract can be only called from @1ActivateContract or @1DeactivateContract`)
 	}
+	if err := sc.checkMemory(`Deactivate`); err != nil {
+		return err
+	}
 	ActivateContract(tblid, state, false)
 	return nil
 }
@@ -759,7 +887,12 @@ func RollbackContract(sc *SmartContract, name string) error {
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract, "error": errAccessRollbackContract}).Error("Check contract access")
 		return errAccessRollbackContract
 	}
+	rollbackContract(sc, name)
+	return nil
+}
 
+// rollbackContract removes the contract and the following contracts from the virtual machine
+func rollbackContract(sc *SmartContract, name string) {
 	if c := VMGetContract(sc.VM, name, uint32(sc.TxSmart.EcosystemID)); c != nil {
 		id := c.Block.Info.(*script.ContractInfo).ID
 		if int(id) < len(sc.VM.Children) {
@@ -767,8 +900,6 @@ func RollbackContract(sc *SmartContract, name string) error {
 		}
 		delete(sc.VM.Objects, c.Name)
 	}
-
-	return nil
 }
 
 // DBSelectMetrics returns list of metrics by name and time interval
@@ -815,13 +946,21 @@ func RollbackEditContract(sc *SmartContract) error {
 		return err
 	}
 	if len(fields["value"]) > 0 {
-		var owner *script.OwnerInfo
+		var (
+			owner *script.OwnerInfo
+			name  string
+		)
 		for i, item := range smartVM.Block.Children {
 			if item != nil && item.Type == script.ObjContract {
 				cinfo := item.Info.(*script.ContractInfo)
+				// the compiled pinned versions have the same table id as the active contract
+				if obj, ok := smartVM.Objects[cinfo.Name]; !ok || obj.Value != item {
+					continue
+				}
 				if cinfo.Owner.TableID == converter.StrToInt64(rollbackTx.TableID) &&
 					cinfo.Owner.StateID == uint32(sc.TxSmart.EcosystemID) {
 					owner = smartVM.Children[i].Info.(*script.ContractInfo).Owner
+					name = cinfo.Name
 					break
 				}
 			}
@@ -835,11 +974,27 @@ func RollbackEditContract(sc *SmartContract) error {
 		if len(fields["wallet_id"]) > 0 {
 			wallet = converter.StrToInt64(fields["wallet_id"])
 		}
-		root, err := CompileContract(sc, fields["value"], int64(owner.StateID), wallet, owner.TokenID)
+		// the versions are immutable so only the active version is moved back
+		value, version := fields["value"], converter.StrToInt64(fields["version"])
+		if version > 0 {
+			row, err := sc.getRow(model.ContractVersionTableName, []string{`ecosystem`, `contract_id`, `version`},
+				[]string{converter.Int64ToStr(sc.TxSmart.EcosystemID), rollbackTx.TableID, fields["version"]})
+			if err != nil {
+				return err
+			}
+			if row != nil {
+				value = row[`value`]
+			}
+		}
+		root, err := CompileContract(sc, value, int64(owner.StateID), wallet, owner.TokenID)
 		if err != nil {
 			log.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("compiling contract")
 			return err
 		}
+		if !sc.VDE {
+			dropContractVersion(sc.VM, name, owner.Version)
+			root.(*script.Block).Owner.Version = version
+		}
 		err = FlushContract(sc, root, owner.TableID, owner.Active)
 		if err != nil {
 			log.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("flushing contract")
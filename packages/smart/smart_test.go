@@ -22,6 +22,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/utils/tx"
 )
 
 type TestSmart struct {
@@ -93,3 +94,140 @@ func TestCheckAppend(t *testing.T) {
 	_, err := Run(cfunc, nil, &map[string]interface{}{})
 	require.NoError(t, err)
 }
+
+func TestPrepareQuery(t *testing.T) {
+	sc := &SmartContract{TxSmart: tx.SmartContract{Header: tx.Header{EcosystemID: 1}}}
+
+	query, columns, err := sc.PrepareQuery(&Query{Table: `deposits`, Columns: `name,keys.amount`,
+		Join: `keys`, On: `deposits.key_id = keys.id`, Where: `deposits.amount > $`, Order: `keys.amount desc`})
+	require.NoError(t, err)
+	require.Equal(t, `select "deposits"."name" as "name", "keys"."amount" as "keys.amount" from "1_deposits" as "deposits" `+
+		`inner join "1_keys" as "keys" on deposits.key_id = keys.id where deposits.amount > ? `+
+		`order by "keys.amount" desc offset 0 limit 25`, query)
+	require.Equal(t, []string{`name`, `keys.amount`}, columns)
+
+	query, columns, err = sc.PrepareQuery(&Query{Table: `deposits`, GroupBy: `key_id,data->kind`,
+		Sum: `amount`, Order: `sum_amount desc`, Limit: 1000})
+	require.NoError(t, err)
+	require.Equal(t, `select "deposits"."key_id" as "key_id", "deposits"."data"::jsonb->>'kind' as "data.kind", `+
+		`sum("deposits"."amount") as "sum_amount" from "1_deposits" as "deposits" `+
+		`group by "deposits"."key_id", "deposits"."data"::jsonb->>'kind' order by "sum_amount" desc offset 0 limit 250`, query)
+	require.Equal(t, []string{`key_id`, `data.kind`, `sum_amount`}, columns)
+
+	_, _, err = sc.PrepareQuery(&Query{Table: `deposits`, Columns: `name`, Join: `@2_keys`, On: `deposits.key_id = keys.id`})
+	require.EqualError(t, err, `table 2_keys can not be joined with 1_deposits`)
+	_, _, err = sc.PrepareQuery(&Query{Table: `deposits`, Join: `keys`, On: `deposits.key_id = keys.id`})
+	require.EqualError(t, err, `columns of the joined tables must be specified`)
+	_, _, err = sc.PrepareQuery(&Query{Table: `deposits`, Sum: `members.amount`})
+	require.EqualError(t, err, `table members is not used in the query`)
+	_, _, err = sc.PrepareQuery(&Query{Table: `deposits`, Sum: `amount`, Order: `amount; drop`})
+	require.EqualError(t, err, `order amount; drop is not valid`)
+}
+
+func TestColumnType(t *testing.T) {
+	cases := map[string]string{
+		`bool`:          `boolean NOT NULL DEFAULT false`,
+		`int_array`:     `bigint[] NOT NULL DEFAULT '{}'`,
+		`bytes`:         `bytea CHECK (octet_length("data") <= 1048576)`,
+		`bytes(64)`:     `bytea CHECK (octet_length("data") <= 64)`,
+		`decimal(20,8)`: `decimal(20, 8) NOT NULL DEFAULT '0'`,
+	}
+	for colType, sqlType := range cases {
+		ret, err := columnType(`data`, colType)
+		require.NoError(t, err)
+		require.Equal(t, sqlType, ret)
+	}
+	for _, colType := range []string{`bytes(0)`, `bytes(2000000)`, `bytes(10,2)`, `decimal`, `decimal(10,20)`, `decimal(-1)`} {
+		_, err := columnType(`data`, colType)
+		require.Error(t, err, colType)
+	}
+
+	require.NoError(t, checkColumnTypeParam(`CreateColumn`, 2, `decimal(20,8)`))
+	require.NoError(t, checkColumnTypeParam(`CreateColumn`, 1, `boolean`))
+	require.EqualError(t, checkColumnTypeParam(`ChangeColumnType`, 2, `boolean`), `Type 'boolean' of columns is not supported`)
+
+	arr, err := arrayValue([]interface{}{int64(1), `a"b\c`, nil})
+	require.NoError(t, err)
+	require.Equal(t, `{"1","a\"b\\c",NULL}`, arr)
+}
+
+func TestParseColumns(t *testing.T) {
+	cols, indexes, err := parseColumns(`[{"name":"Name","type":"varchar","unique":true},
+		{"name":"amount","type":"money","index":"1"},{"name":"data","type":"json"}]`)
+	require.NoError(t, err)
+	require.Len(t, cols, 3)
+	require.Equal(t, []*tableIndex{{Columns: []string{`name`}, Unique: true},
+		{Columns: []string{`amount`}}}, indexes)
+
+	cols, indexes, err = parseColumns(`{"columns":[{"name":"key_id","type":"number"},{"name":"kind","type":"varchar"}],
+		"indexes":[{"columns":"key_id, kind","unique":true},{"columns":["kind"]}]}`)
+	require.NoError(t, err)
+	require.Len(t, cols, 2)
+	require.Equal(t, []*tableIndex{{Columns: []string{`key_id`, `kind`}, Unique: true},
+		{Columns: []string{`kind`}}}, indexes)
+	require.Equal(t, `1_deposits_key_id_kind`, indexes[0].name(`1_deposits`))
+
+	_, _, err = parseColumns(`{"columns":[],"indexes":[{"columns":""}]}`)
+	require.EqualError(t, err, `columns of index are undefined`)
+
+	colType, index := splitColumnType(`amount`, `money unique`)
+	require.Equal(t, `money`, colType)
+	require.Equal(t, &tableIndex{Columns: []string{`amount`}, Unique: true}, index)
+	colType, index = splitColumnType(`amount`, `decimal(20,8)`)
+	require.Equal(t, `decimal(20,8)`, colType)
+	require.Nil(t, index)
+}
+
+func TestCheckConverter(t *testing.T) {
+	columns := map[string]string{`amount`: `true`, `name`: `true`}
+	for _, conv := range []string{`amount::numeric / 100`, `round(amount * 1.5, 2)`,
+		`coalesce(upper(name), 'none')`, `amount::decimal(20, 8)`, `trim(name) || 'it''s'`,
+		`amount <> 0 and name is not null`} {
+		require.NoError(t, checkConverter(conv, columns), conv)
+	}
+	require.EqualError(t, checkConverter(`pg_sleep(10)`, columns), `function pg_sleep can not be used in converter`)
+	require.EqualError(t, checkConverter(`amount; drop table keys`, columns), `converter amount; drop table keys is not valid`)
+	require.EqualError(t, checkConverter(`"pg_sleep"(10)`, columns), `converter "pg_sleep"(10) is not valid`)
+	require.EqualError(t, checkConverter(`(select amount from "1_keys")`, columns), `select can not be used in converter`)
+	require.EqualError(t, checkConverter(`current_timestamp`, columns), `current_timestamp can not be used in converter`)
+	require.EqualError(t, checkConverter(`'now'::date`, columns), `'now' can not be used in converter`)
+	require.EqualError(t, checkConverter(`name::timestamp`, columns), `type timestamp can not be used in converter`)
+	require.EqualError(t, checkConverter(`balance * 2`, columns), `balance can not be used in converter`)
+	require.Error(t, checkConverter(`amount -- comment`, columns))
+	require.Error(t, checkConverter(`$1`, columns))
+	require.Error(t, checkConverter(`'open`, columns))
+
+	require.NoError(t, checkNewName(`amount`, `total`))
+	require.EqualError(t, checkNewName(`id`, `key`), `column id can not be changed`)
+	require.Error(t, checkNewName(`amount`, `to"tal`))
+}
+
+func TestSponsorContract(t *testing.T) {
+	require.False(t, sponsorContract(``, `@1TokensSend`))
+	require.True(t, sponsorContract(`@1NewToken,@1TokensSend`, `@1TokensSend`))
+	require.False(t, sponsorContract(`@1NewToken`, `@1TokensSend`))
+	require.False(t, sponsorContract(`@1NewToken`, `@2NewToken`))
+
+	smartTx := tx.SmartContract{RequestID: `1`, Header: tx.Header{Type: 130, Time: 1530000000, KeyID: 2, EcosystemID: 1}}
+	require.Equal(t, `1,130,1530000000,2,1,0,,,0`, smartTx.ForSign())
+	smartTx.Sponsor = 3
+	require.Equal(t, `1,130,1530000000,2,1,0,,,0,3`, smartTx.ForSign())
+}
+
+func TestSystemContracts(t *testing.T) {
+	for _, item := range systemContracts {
+		list, err := script.ContractsList(item.Value)
+		require.NoError(t, err, item.Name)
+		require.Equal(t, []string{item.Name}, list)
+		require.Equal(t, item.Value, getSystemContract(item.Name).Value)
+	}
+	require.Nil(t, getSystemContract(`NewContract`))
+	list, err := script.ContractsList(ImportSystemContractsSource)
+	require.NoError(t, err)
+	require.Equal(t, []string{nImportSystemContracts}, list)
+}
+
+func TestOptimizeVM(t *testing.T) {
+	// the byte-code of the contracts which are executed by the consensus is not optimized
+	require.False(t, GetVM(false, 0).Optimize)
+}
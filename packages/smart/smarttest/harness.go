@@ -0,0 +1,287 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package smarttest runs the contracts without the database and the network. The contracts are
+// compiled into the smart virtual machine and they are called by SmartContract.CallContract with
+// the in-memory storage of the tables. The signatures, the access rights of the tables and the
+// payment of the fuel are not checked. The database functions which are not covered by
+// smart.Storage, such as CreateTable, are not supported.
+package smarttest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/GenesisKernel/go-genesis/packages/conf/syspar"
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/crypto"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/smart"
+	"github.com/GenesisKernel/go-genesis/packages/utils"
+	"github.com/GenesisKernel/go-genesis/packages/utils/tx"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// Harness is the environment of the contract tests. The contracts are compiled into the smart
+// virtual machine of the process so the harnesses share the compiled contracts.
+type Harness struct {
+	Storage   *MemStorage
+	Ecosystem int64 // the ecosystem of the compiled and called contracts
+	KeyID     int64 // the key which calls the contracts
+	Sponsor   int64 // the key which pays the fuel instead of KeyID
+	NodeID    int64 // the key of the node which generates the block and gets the fuel
+	Time      int64 // the time of the transactions
+	DryRun    bool  // the contracts are run as the dry run of the API
+
+	loaded   map[int64]bool
+	privates map[int64]string // the private keys of NewKey
+	txCount  int64
+}
+
+// Result is the result of the contract call
+type Result struct {
+	Result  string   // the value of $result
+	Fuel    int64    // the used fuel
+	Changes []Change // the changes of the tables including the payment of the fuel
+}
+
+// DefaultFuelRate is the fuel rate of the first ecosystem which is set by New
+const DefaultFuelRate = `1`
+
+// New returns the harness with the empty storage for the first ecosystem. The fuel rate is
+// set to DefaultFuelRate, it can be changed by SetSysParams.
+func New() *Harness {
+	h := &Harness{Storage: NewMemStorage(), Ecosystem: 1, KeyID: 1, loaded: make(map[int64]bool),
+		privates: make(map[int64]string)}
+	if err := syspar.SetParams(map[string]string{syspar.FuelRate: `[["1","` + DefaultFuelRate + `"]]`}); err != nil {
+		log.WithFields(log.Fields{"type": consts.ParameterExceeded, "error": err}).Error("setting fuel rate")
+	}
+	return h
+}
+
+// Compile compiles the source of the contracts in the ecosystem of the harness
+func (h *Harness) Compile(src string) error {
+	if !h.loaded[h.Ecosystem] {
+		if err := smart.LoadSysFuncs(smart.GetVM(false, 0), int(h.Ecosystem)); err != nil {
+			return err
+		}
+		h.loaded[h.Ecosystem] = true
+	}
+	return smart.Compile(src, &script.OwnerInfo{StateID: uint32(h.Ecosystem)})
+}
+
+// AddEcosystem adds the ecosystem
+func (h *Harness) AddEcosystem(id int64, name string) {
+	h.Storage.AddRow(`system_states`, map[string]string{`id`: converter.Int64ToStr(id), `name`: name})
+}
+
+// AddKey adds the key with the amount of tokens into the ecosystem of the harness or changes
+// the amount of the existing key. The key doesn't have the public key so its calls are not signed.
+func (h *Harness) AddKey(id int64, amount string) {
+	h.setRow(model.KeyTableName(h.Ecosystem), `id`, converter.Int64ToStr(id), map[string]string{`amount`: amount})
+}
+
+// NewKey generates the key pair and adds the key with the amount of tokens into the ecosystem
+// of the harness. The calls of the key and the sponsoring by the key are signed by the private key.
+func (h *Harness) NewKey(amount string) (int64, error) {
+	private, public, err := crypto.GenHexKeys()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("generating keys")
+		return 0, err
+	}
+	pub, err := hex.DecodeString(public)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ConversionError, "error": err}).Error("decoding public key")
+		return 0, err
+	}
+	id := crypto.Address(pub)
+	h.setRow(model.KeyTableName(h.Ecosystem), `id`, converter.Int64ToStr(id),
+		map[string]string{`amount`: amount, `pub`: string(pub)})
+	h.privates[id] = private
+	return id, nil
+}
+
+// SetEcosysParam sets the parameter of the ecosystem of the harness
+func (h *Harness) SetEcosysParam(name, value string) {
+	h.setRow(converter.Int64ToStr(h.Ecosystem)+`_parameters`, `name`, name, map[string]string{`value`: value})
+}
+
+// setRow changes the values of the row with the key or adds the new row
+func (h *Harness) setRow(table, field, key string, values map[string]string) {
+	row, _ := h.Storage.get(table, []string{field}, []string{key})
+	if row == nil {
+		row = map[string]string{field: key}
+		for name, val := range values {
+			row[name] = val
+		}
+		h.Storage.AddRow(table, row)
+		return
+	}
+	for name, val := range values {
+		row[name] = val
+	}
+}
+
+// sign returns the signature of the data by the key created by NewKey
+func (h *Harness) sign(id int64, data string) ([]byte, error) {
+	sign, err := crypto.Sign(h.privates[id], data)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err, "key_id": id}).Error("signing data")
+	}
+	return sign, err
+}
+
+// SetSysParams sets the system parameters
+func (h *Harness) SetSysParams(params map[string]string) error {
+	return syspar.SetParams(params)
+}
+
+// txValue converts the value of the parameter to the type of the field of the contract data
+func txValue(field *script.FieldInfo, v interface{}) (interface{}, error) {
+	if v == nil {
+		if field.Type.String() == script.Decimal {
+			return decimal.New(0, 0), nil
+		}
+		if field.Type.String() == `[]interface {}` {
+			return []interface{}{}, nil
+		}
+		return reflect.New(field.Type).Elem().Interface(), nil
+	}
+	if val, ok := v.(int); ok {
+		v = int64(val)
+	}
+	switch field.Type.String() {
+	case `int64`:
+		return converter.ValueToInt(v)
+	case `uint64`:
+		val, err := converter.ValueToInt(v)
+		return uint64(val), err
+	case `float64`:
+		return smart.Float(v), nil
+	case script.Decimal:
+		return script.ValueToDecimal(v)
+	case `string`:
+		return fmt.Sprint(v), nil
+	case `[]uint8`:
+		switch val := v.(type) {
+		case []byte:
+			// the parser keeps the files as the bytes
+			if field.ContainsTag(script.TagFile) {
+				return val, nil
+			}
+			return hex.EncodeToString(val), nil
+		case string:
+			return val, nil
+		}
+	case `[]interface {}`:
+		if val, ok := v.([]interface{}); ok {
+			return val, nil
+		}
+	}
+	return nil, fmt.Errorf(`wrong value of %s parameter`, field.Name)
+}
+
+// Call calls the contract with the parameters. It returns the error of the contract
+// if the contract has failed.
+func (h *Harness) Call(name string, params map[string]interface{}) (*Result, error) {
+	contract := smart.VMGetContract(smart.GetVM(false, 0), name, uint32(h.Ecosystem))
+	if contract == nil {
+		log.WithFields(log.Fields{"type": consts.NotFound, "contract_name": name}).Error("unknown contract")
+		return nil, fmt.Errorf(`unknown contract %s`, name)
+	}
+	data := make(map[string]interface{})
+	if fields := contract.Block.Info.(*script.ContractInfo).Tx; fields != nil {
+		for _, field := range *fields {
+			val, err := txValue(field, params[field.Name])
+			if err != nil {
+				return nil, err
+			}
+			data[field.Name] = val
+			if field.ContainsTag(script.TagFile) {
+				mimeType, _ := params[field.Name+`MimeType`].(string)
+				data[field.Name+`MimeType`] = mimeType
+			}
+		}
+	}
+	for key := range params {
+		if _, ok := data[key]; !ok {
+			return nil, fmt.Errorf(`unknown parameter %s of %s contract`, key, contract.Name)
+		}
+	}
+	h.txCount++
+	hash, err := crypto.Hash([]byte(fmt.Sprintf(`%d,%s`, h.txCount, contract.Name)))
+	if err != nil {
+		return nil, err
+	}
+	sc := smart.SmartContract{
+		FullAccess: true,
+		BlockData:  &utils.BlockData{BlockID: h.txCount, Time: h.Time, EcosystemID: h.Ecosystem, KeyID: h.NodeID},
+		TxSmart: tx.SmartContract{Header: tx.Header{Type: int(contract.Block.Info.(*script.ContractInfo).ID),
+			Time: h.Time, EcosystemID: h.Ecosystem, KeyID: h.KeyID}, Sponsor: h.Sponsor},
+		TxData:     data,
+		TxContract: contract,
+		TxHash:     hash,
+		Storage:    h.Storage,
+		DryRun:     h.DryRun,
+	}
+	if err = h.signTx(&sc); err != nil {
+		return nil, err
+	}
+	// the changes of the failed contract are discarded the same as the database transaction
+	tables := h.Storage.snapshot()
+	h.Storage.ResetChanges()
+	result, err := sc.CallContract(smart.CallInit | smart.CallCondition | smart.CallAction)
+	if err != nil {
+		h.Storage.tables = tables
+		h.Storage.ResetChanges()
+		return nil, err
+	}
+	changes := make([]Change, 0, len(h.Storage.Changes()))
+	for _, item := range h.Storage.Changes() {
+		// the records of the versions of the called contracts are not the changes of the contract
+		if item.Table != model.TxContractTableName {
+			changes = append(changes, item)
+		}
+	}
+	return &Result{Result: result, Fuel: sc.TxFuel, Changes: changes}, nil
+}
+
+// signTx signs the transaction by the private keys of the caller and the sponsor. The signature
+// isn't checked if the caller has not been created by NewKey.
+func (h *Harness) signTx(sc *smart.SmartContract) error {
+	if len(h.privates[h.KeyID]) == 0 {
+		sc.SkipSign = true
+		return nil
+	}
+	forSign := sc.TxSmart.ForSign()
+	sc.TxData[`forsign`] = forSign
+	sign, err := h.sign(h.KeyID, forSign)
+	if err != nil {
+		return err
+	}
+	sc.TxSmart.BinSignatures = converter.EncodeLengthPlusData(sign)
+	if len(h.privates[h.Sponsor]) > 0 {
+		if sc.TxSmart.SponsorSignature, err = h.sign(h.Sponsor, forSign); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,1082 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smarttest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/crypto"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/smart"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCall is the call of the contract in the table-driven tests. The call fails
+// if err is not empty and err is the part of the error message.
+type testCall struct {
+	name     string
+	key      int64 // the calling key, 0 is the key 1
+	sponsor  int64 // the key which pays the fuel, 0 is no sponsor
+	contract string
+	params   map[string]interface{}
+	result   string
+	changes  []Change // the changes are checked if they are not nil
+	err      string
+}
+
+func runCalls(t *testing.T, h *Harness, calls []testCall) {
+	for _, item := range calls {
+		h.KeyID = item.key
+		if h.KeyID == 0 {
+			h.KeyID = 1
+		}
+		h.Sponsor = item.sponsor
+		ret, err := h.Call(item.contract, item.params)
+		if len(item.err) > 0 {
+			require.Error(t, err, item.name)
+			require.Contains(t, err.Error(), item.err, item.name)
+			continue
+		}
+		require.NoError(t, err, item.name)
+		require.Equal(t, item.result, ret.Result, item.name)
+		if item.changes != nil {
+			require.Equal(t, item.changes, ret.Changes, item.name)
+		}
+	}
+}
+
+// txHash returns the hash of the transaction which is made by the harness for the call
+func txHash(t *testing.T, count int64, contract string) string {
+	hash, err := crypto.Hash([]byte(fmt.Sprintf(`%d,%s`, count, contract)))
+	require.NoError(t, err)
+	return hex.EncodeToString(hash)
+}
+
+func TestHarness(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	h.Storage.AddRow(`1_deposits`, map[string]string{`name`: `initial`, `amount`: `5`})
+	require.NoError(t, h.Compile(`contract TestDeposit {
+		data {
+			Name string
+			Amount money
+		}
+		conditions {
+			if $Amount <= 0 {
+				error "wrong amount"
+			}
+		}
+		action {
+			DBUpdate("keys", $key_id, "-amount", $Amount)
+			$result = DBInsert("deposits", "name,amount", $Name, $Amount)
+		}
+	}
+	contract TestDepositFailed {
+		action {
+			DBUpdate("keys", $key_id, "-amount", 10)
+			DBInsert("deposits", "name,amount", "failed", 10)
+			error "failed deposit"
+		}
+	}
+	contract TestDeposits {
+		data {
+			Amount int
+		}
+		action {
+			var list array
+			var first, key map
+			list = DBFind("deposits").Where("amount >= $", $Amount).Order("id desc")
+			first = list[0]
+			key = DBRow("keys").Columns("amount").WhereId($key_id)
+			$result = Sprintf("%d %s %s", Len(list), first["name"], key["amount"])
+		}
+	}
+	contract TestDepositsSum {
+		action {
+			$result = DBFind("deposits").Sum("amount")
+		}
+	}`))
+
+	runCalls(t, h, []testCall{
+		{name: `deposit`, contract: `TestDeposit`, params: map[string]interface{}{`Name`: `first`, `Amount`: 30},
+			result: `2`, changes: []Change{
+				{Table: `1_keys`, ID: `1`, Values: map[string]string{`amount`: `70`}},
+				{Table: `1_deposits`, ID: `2`, Values: map[string]string{`id`: `2`, `name`: `first`, `amount`: `30`}},
+			}},
+		{name: `conditions`, contract: `TestDeposit`, params: map[string]interface{}{`Name`: `second`, `Amount`: 0},
+			err: `{"type":"error","error":"wrong amount","trace":[{"name":"@1TestDeposit.conditions","line":8,"column":6}]}`},
+		{name: `unknown parameter`, contract: `TestDeposit`, params: map[string]interface{}{`Title`: `second`},
+			err: `unknown parameter Title of @1TestDeposit contract`},
+		{name: `unknown contract`, contract: `TestUnknown`, err: `unknown contract TestUnknown`},
+		{name: `rolled back`, contract: `TestDepositFailed`, err: `failed deposit`},
+		{name: `select`, contract: `TestDeposits`, params: map[string]interface{}{`Amount`: 5}, result: `2 first 70`,
+			changes: []Change{}},
+		{name: `select filter`, contract: `TestDeposits`, params: map[string]interface{}{`Amount`: 10}, result: `1 first 70`},
+		{name: `sum`, contract: `TestDepositsSum`, err: `Join, GroupBy and Sum are not supported by the storage`},
+	})
+	require.Len(t, h.Storage.Table(`1_deposits`), 2)
+	row, err := h.Storage.get(`1_keys`, []string{`id`}, []string{`1`})
+	require.NoError(t, err)
+	require.Equal(t, `70`, row[`amount`])
+
+	ret, err := h.Call(`TestDeposits`, map[string]interface{}{`Amount`: 5})
+	require.NoError(t, err)
+	require.True(t, ret.Fuel > 0)
+}
+
+func TestPayment(t *testing.T) {
+	h := New()
+	h.NodeID = 10
+	h.AddKey(1, `1000`)
+	h.AddKey(10, `0`)
+	require.NoError(t, h.Compile(`contract TestPay {
+		action {
+			$result = "paid"
+		}
+	}`))
+
+	ret, err := h.Call(`TestPay`, nil)
+	require.NoError(t, err)
+	require.Equal(t, `paid`, ret.Result)
+	fuel := converter.Int64ToStr(ret.Fuel)
+	require.Equal(t, []Change{
+		{Table: `1_keys`, ID: `10`, Values: map[string]string{`amount`: fuel}},
+		{Table: `1_history`, ID: `1`, Values: map[string]string{`id`: `1`, `sender_id`: `1`, `recipient_id`: `10`,
+			`amount`: fuel, `comment`: `Commission for execution of @1TestPay contract`, `block_id`: `1`,
+			`txhash`: h.Storage.Table(`1_history`)[0][`txhash`]}},
+		{Table: `1_keys`, ID: `1`, Values: map[string]string{`amount`: converter.Int64ToStr(1000 - ret.Fuel)}},
+	}, ret.Changes)
+
+	h.KeyID = 2
+	_, err = h.Call(`TestPay`, nil)
+	require.EqualError(t, err, `{"type":"panic","error":"current balance is not enough"}`)
+
+	h.KeyID, err = h.NewKey(`100`)
+	require.NoError(t, err)
+	_, err = h.Call(`TestPay`, nil)
+	require.NoError(t, err)
+	other, err := h.NewKey(`100`)
+	require.NoError(t, err)
+	h.privates[h.KeyID] = h.privates[other]
+	_, err = h.Call(`TestPay`, nil)
+	require.EqualError(t, err, `{"type":"panic","error":"Incorrect sign"}`)
+}
+
+func TestDBDelete(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	h.Storage.AddRow(`1_deposits`, map[string]string{`name`: `initial`, `amount`: `5`})
+	h.Storage.AddRow(`1_deposits`, map[string]string{`name`: `second`, `amount`: `10`})
+	require.NoError(t, h.Compile(`contract TestDelete {
+		data {
+			Id int
+		}
+		action {
+			DBDelete("deposits", $Id)
+		}
+	}
+	contract TestDeleteFailed {
+		data {
+			Id int
+		}
+		action {
+			DBDelete("deposits", $Id)
+			error "failed delete"
+		}
+	}`))
+
+	runCalls(t, h, []testCall{
+		{name: `delete`, contract: `TestDelete`, params: map[string]interface{}{`Id`: 1},
+			changes: []Change{{Table: `1_deposits`, ID: `1`, Deleted: true}}},
+		{name: `deleted`, contract: `TestDelete`, params: map[string]interface{}{`Id`: 1},
+			err: smart.ErrDelNotExistRecord.Error()},
+		{name: `unknown`, contract: `TestDelete`, params: map[string]interface{}{`Id`: 3},
+			err: smart.ErrDelNotExistRecord.Error()},
+		{name: `rolled back`, contract: `TestDeleteFailed`, params: map[string]interface{}{`Id`: 2}, err: `failed delete`},
+	})
+	rows := h.Storage.Table(`1_deposits`)
+	require.Len(t, rows, 1)
+	require.Equal(t, `second`, rows[0][`name`])
+}
+
+func TestEmitEvent(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	h.Time = 1530000000
+	require.NoError(t, h.Compile(`contract TestEvent {
+		data {
+			Name string
+			Data string "optional"
+		}
+		action {
+			var pars map
+			pars["amount"] = 10
+			if Size($Data) > 0 {
+				pars["data"] = $Data
+			}
+			$result = EmitEvent($Name, pars)
+		}
+	}
+	contract TestEventFailed {
+		action {
+			var pars map
+			EmitEvent("failed", pars)
+			error "failed"
+		}
+	}`))
+	event := func(id, block int64, name, data string) Change {
+		return Change{Table: `events`, ID: converter.Int64ToStr(id), Values: map[string]string{
+			`id`: converter.Int64ToStr(id), `ecosystem`: `1`, `block_id`: converter.Int64ToStr(block),
+			`tx_hash`: txHash(t, block, `@1TestEvent`), `contract`: `@1TestEvent`, `name`: name,
+			`data`: data, `time`: `1530000000`}}
+	}
+
+	runCalls(t, h, []testCall{
+		{name: `event`, contract: `TestEvent`, params: map[string]interface{}{`Name`: `paid`}, result: `1`,
+			changes: []Change{event(1, 1, `paid`, `{"amount":10}`)}},
+		{name: `event with data`, contract: `TestEvent`, params: map[string]interface{}{`Name`: `paid.v2-ok`,
+			`Data`: `value`}, result: `2`, changes: []Change{event(2, 2, `paid.v2-ok`, `{"amount":10,"data":"value"}`)}},
+		{name: `wrong name`, contract: `TestEvent`, params: map[string]interface{}{`Name`: `wrong name`},
+			err: `invalid event name wrong name`},
+		{name: `long name`, contract: `TestEvent`, params: map[string]interface{}{`Name`: strings.Repeat(`a`, 256)},
+			err: `invalid event name`},
+		{name: `big data`, contract: `TestEvent`, params: map[string]interface{}{`Name`: `big`,
+			`Data`: strings.Repeat(`a`, 8192)}, err: `event data is larger than 8192 bytes`},
+		{name: `rolled back`, contract: `TestEventFailed`, err: `failed`},
+	})
+	require.Len(t, h.Storage.Table(`events`), 2)
+}
+
+func TestMultisig(t *testing.T) {
+	h := New()
+	h.Time = 1530000000
+	for _, id := range []int64{1, 2, 3, 4, 7} {
+		h.AddKey(id, `100`)
+	}
+	h.Storage.AddRow(`1_roles_participants`, map[string]string{`role`: `{"id": "5", "type": "1", "name": "Signers"}`,
+		`member`: `{"member_id": "7", "member_type": "1", "member_name": "signer"}`})
+	require.NoError(t, h.Compile(`contract TestMultisigPay {
+		settings {
+			multisig_signers = "1,2,3"
+			multisig_roles = "5"
+			multisig_threshold = 2
+			multisig_expiration = 100
+		}
+		data {
+			Amount int
+		}
+		action {
+			if $Amount > 50 {
+				error "too much"
+			}
+			DBInsert("payments", "key_id,amount", $key_id, $Amount)
+		}
+	}
+	contract ApproveMultisig {
+		data {
+			Id int
+		}
+		action {
+			$result = MultisigApprove($Id)
+		}
+	}
+	contract TestApprove {
+		data {
+			Id int
+		}
+		action {
+			$result = MultisigApprove($Id)
+		}
+	}
+	contract TestBypassMultisig {
+		action {
+			$multisig_approved = "@1TestMultisigPay"
+			TestMultisigPay("Amount", 5)
+		}
+	}
+	contract TestMultisigFile {
+		settings {
+			multisig_signers = "1,2"
+			multisig_threshold = 2
+		}
+		data {
+			Data bytes "file"
+			Key bytes
+		}
+		action {
+			if BytesToString($Data) != "signed data" || $DataMimeType != "text/plain" || $Key != "0a0b" {
+				error "wrong bytes"
+			}
+		}
+	}`))
+
+	runCalls(t, h, []testCall{
+		{name: `park`, contract: `TestMultisigPay`, params: map[string]interface{}{`Amount`: 10}, result: `1`},
+		{name: `bypass`, key: 2, contract: `TestBypassMultisig`,
+			err: `contract @1TestMultisigPay requires the approvals of the signers`},
+		{name: `not system contract`, key: 2, contract: `TestApprove`, params: map[string]interface{}{`Id`: 1},
+			err: `MultisigApprove can be only called from ApproveMultisig`},
+		{name: `initiator`, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 1},
+			err: `multi-signature call 1 has been approved by 1`},
+		{name: `not signer`, key: 4, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 1},
+			err: `Access denied`},
+		{name: `unknown call`, key: 2, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 10},
+			err: `multi-signature call 10 has not been found`},
+		{name: `execute`, key: 2, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 1},
+			result: `executed`},
+		{name: `executed`, key: 3, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 1},
+			err: `multi-signature call 1 is executed`},
+
+		{name: `park failing`, contract: `TestMultisigPay`, params: map[string]interface{}{`Amount`: 60}, result: `2`},
+		{name: `failed action`, key: 2, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 2},
+			err: `too much`},
+		{name: `role member`, key: 7, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 2},
+			err: `too much`},
+
+		{name: `park expiring`, contract: `TestMultisigPay`, params: map[string]interface{}{`Amount`: 20}, result: `3`},
+
+		{name: `park bytes`, contract: `TestMultisigFile`, params: map[string]interface{}{`Data`: []byte(`signed data`),
+			`DataMimeType`: `text/plain`, `Key`: []byte{10, 11}}, result: `4`},
+		{name: `bytes`, key: 2, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 4},
+			result: `executed`},
+	})
+	require.Equal(t, []map[string]string{{`id`: `1`, `key_id`: `1`, `amount`: `10`}}, h.Storage.Table(`1_payments`))
+	calls := h.Storage.Table(`multisig`)
+	require.Len(t, calls, 4)
+	require.Equal(t, `[1,2]`, calls[0][`approvals`])
+	// the failed action rolls back the approval
+	require.Equal(t, `[1]`, calls[1][`approvals`])
+	require.Equal(t, `pending`, calls[1][`status`])
+
+	h.Time += 101
+	runCalls(t, h, []testCall{
+		{name: `expired`, key: 2, contract: `ApproveMultisig`, params: map[string]interface{}{`Id`: 3},
+			err: `multi-signature call 3 is expired`},
+	})
+	require.Len(t, h.Storage.Table(`1_payments`), 1)
+}
+
+func TestTokens(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	h.AddKey(3, `100`)
+	require.NoError(t, h.Compile(`contract NewToken {
+		data {
+			Symbol string
+			Supply money
+		}
+		action {
+			$result = TokenCreate($Symbol, "Test token", 2, $Supply)
+		}
+	}
+	contract TransferToken {
+		data {
+			Recipient int
+			Amount money
+		}
+		action {
+			TokenTransfer("TST", $Recipient, $Amount)
+			$result = TokenBalance("TST", $key_id)
+		}
+	}
+	contract ApproveToken {
+		data {
+			Spender int
+			Amount money
+		}
+		action {
+			TokenApprove("TST", $Spender, $Amount)
+			$result = TokenAllowance("TST", $key_id, $Spender)
+		}
+	}
+	contract TransferTokenFrom {
+		data {
+			Owner int
+			Amount money
+		}
+		action {
+			TokenTransferFrom("TST", $Owner, $key_id, $Amount)
+			$result = TokenBalance("TST", $key_id)
+		}
+	}
+	contract TestTokenSteal {
+		data {
+			Kind string
+		}
+		action {
+			if $Kind == "transfer" {
+				TokenTransfer("TST", 3, 1)
+			}
+			if $Kind == "approve" {
+				TokenApprove("TST", 3, 1)
+			}
+			if $Kind == "from" {
+				TokenTransferFrom("TST", 1, 3, 1)
+			}
+		}
+	}`))
+
+	runCalls(t, h, []testCall{
+		{name: `create`, contract: `NewToken`, params: map[string]interface{}{`Symbol`: `TST`, `Supply`: `1000`},
+			result: `1`},
+		{name: `duplicate`, contract: `NewToken`, params: map[string]interface{}{`Symbol`: `TST`, `Supply`: `10`},
+			err: `token TST already exists`},
+		{name: `wrong symbol`, contract: `NewToken`, params: map[string]interface{}{`Symbol`: `tst`, `Supply`: `10`},
+			err: `token symbol tst is not valid`},
+
+		{name: `transfer`, contract: `TransferToken`, params: map[string]interface{}{`Recipient`: 2, `Amount`: `300`},
+			result: `700`},
+		{name: `transfer over balance`, contract: `TransferToken`,
+			params: map[string]interface{}{`Recipient`: 2, `Amount`: `701`}, err: `not enough tokens on the balance of 1`},
+		{name: `transfer to nobody`, contract: `TransferToken`, params: map[string]interface{}{`Recipient`: 0, `Amount`: `1`},
+			err: `recipient 0 is not valid`},
+		{name: `negative transfer`, contract: `TransferToken`, params: map[string]interface{}{`Recipient`: 2, `Amount`: `-1`},
+			err: `token amount -1 is not valid`},
+
+		{name: `approve`, contract: `ApproveToken`, params: map[string]interface{}{`Spender`: 3, `Amount`: `100`},
+			result: `100`},
+		{name: `approve itself`, contract: `ApproveToken`, params: map[string]interface{}{`Spender`: 1, `Amount`: `100`},
+			err: `spender 1 is not valid`},
+		{name: `transfer from`, key: 3, contract: `TransferTokenFrom`, params: map[string]interface{}{`Owner`: 1, `Amount`: `60`},
+			result: `60`},
+		{name: `allowance exceeded`, key: 3, contract: `TransferTokenFrom`,
+			params: map[string]interface{}{`Owner`: 1, `Amount`: `60`}, err: `allowance of 3 is exceeded`},
+
+		{name: `transfer not system`, contract: `TestTokenSteal`, params: map[string]interface{}{`Kind`: `transfer`},
+			err: `TokenTransfer can be only called from TransferToken`},
+		{name: `approve not system`, contract: `TestTokenSteal`, params: map[string]interface{}{`Kind`: `approve`},
+			err: `TokenApprove can be only called from ApproveToken`},
+		{name: `transfer from not system`, key: 3, contract: `TestTokenSteal`, params: map[string]interface{}{`Kind`: `from`},
+			err: `TokenTransferFrom can be only called from TransferTokenFrom`},
+	})
+
+	balances := make(map[string]string)
+	for _, row := range h.Storage.Table(`token_balances`) {
+		balances[row[`key_id`]] = row[`amount`]
+	}
+	require.Equal(t, map[string]string{`1`: `640`, `2`: `300`, `3`: `60`}, balances)
+	history := h.Storage.Table(`1_history`)
+	require.Len(t, history, 3)
+	require.Equal(t, `1`, history[2][`sender_id`])
+	require.Equal(t, `3`, history[2][`recipient_id`])
+	require.Equal(t, `60`, history[2][`amount`])
+	require.Equal(t, `1`, history[2][`token_id`])
+	// the failed transfer doesn't change the allowance
+	allowances := h.Storage.Table(`token_allowances`)
+	require.Len(t, allowances, 1)
+	require.Equal(t, `40`, allowances[0][`amount`])
+}
+
+func TestAssets(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	h.AddKey(2, `100`)
+	h.Time = 1530000000
+	h.Storage.AddRow(`1_binaries`, map[string]string{`name`: `image`, `hash`: `c4ca4238a0b923820dcc509a6f75849b`})
+	require.NoError(t, h.Compile(`contract NewAsset {
+		data {
+			Data string
+			BinaryId int
+		}
+		action {
+			var m map
+			m = JSONDecode($Data)
+			$result = AssetCreate(m, $BinaryId)
+		}
+	}
+	contract ApproveAsset {
+		data {
+			Id int
+			Spender int
+		}
+		action {
+			AssetApprove($Id, $Spender)
+		}
+	}
+	contract TransferAsset {
+		data {
+			Id int
+			Recipient int
+		}
+		action {
+			AssetTransfer($Id, $Recipient)
+			$result = AssetOwner($Id)
+		}
+	}
+	contract TestAssetSteal {
+		data {
+			Approve int
+		}
+		action {
+			if $Approve == 1 {
+				AssetApprove(1, 2)
+			} else {
+				AssetTransfer(1, 2)
+			}
+		}
+	}`))
+
+	runCalls(t, h, []testCall{
+		{name: `create`, contract: `NewAsset`, params: map[string]interface{}{`Data`: `{"title":"picture"}`, `BinaryId`: 1},
+			result: `1`},
+		{name: `unknown binary`, contract: `NewAsset`, params: map[string]interface{}{`Data`: `{}`, `BinaryId`: 2},
+			err: `binary 2 has not been found`},
+
+		{name: `transfer not owned`, key: 2, contract: `TransferAsset`, params: map[string]interface{}{`Id`: 1, `Recipient`: 2},
+			err: `Access denied`},
+		{name: `approve not owned`, key: 2, contract: `ApproveAsset`, params: map[string]interface{}{`Id`: 1, `Spender`: 2},
+			err: `Access denied`},
+		{name: `unknown asset`, contract: `TransferAsset`, params: map[string]interface{}{`Id`: 5, `Recipient`: 2},
+			err: `asset 5 has not been found`},
+		{name: `transfer to owner`, contract: `TransferAsset`, params: map[string]interface{}{`Id`: 1, `Recipient`: 1},
+			err: `recipient 1 is not valid`},
+		{name: `approve not system`, contract: `TestAssetSteal`, params: map[string]interface{}{`Approve`: 1},
+			err: `AssetApprove can be only called from ApproveAsset`},
+		{name: `transfer not system`, contract: `TestAssetSteal`, params: map[string]interface{}{`Approve`: 0},
+			err: `AssetTransfer can be only called from TransferAsset`},
+
+		{name: `approve`, contract: `ApproveAsset`, params: map[string]interface{}{`Id`: 1, `Spender`: 2}},
+		{name: `approved transfer`, key: 2, contract: `TransferAsset`, params: map[string]interface{}{`Id`: 1, `Recipient`: 3},
+			result: `3`},
+		{name: `approval revoked`, key: 2, contract: `TransferAsset`, params: map[string]interface{}{`Id`: 1, `Recipient`: 2},
+			err: `Access denied`},
+	})
+
+	asset := h.Storage.Table(`assets`)[0]
+	require.Equal(t, `3`, asset[`key_id`])
+	require.Equal(t, `0`, asset[`approved_id`])
+	require.Equal(t, `1`, asset[`creator_id`])
+	require.Equal(t, `{"title":"picture"}`, asset[`data`])
+	history := h.Storage.Table(`asset_history`)
+	require.Len(t, history, 2)
+	require.Equal(t, `1`, history[1][`sender_id`])
+	require.Equal(t, `3`, history[1][`recipient_id`])
+}
+
+func TestEscrow(t *testing.T) {
+	h := New()
+	h.Time = 1530000000
+	h.AddKey(1, `1000`)
+	h.AddKey(2, `100`)
+	require.NoError(t, h.Compile(`contract LockEscrow {
+		data {
+			Amount money
+			Condition string
+			Deadline int
+			Recipient int
+		}
+		action {
+			$result = EscrowLock("", $Recipient, $Amount, $Condition, $Deadline)
+		}
+	}
+	contract ReleaseEscrow {
+		data {
+			Id int
+		}
+		action {
+			EscrowRelease($Id)
+		}
+	}
+	contract CancelEscrow {
+		data {
+			Id int
+		}
+		action {
+			EscrowCancel($Id)
+		}
+	}
+	contract SettleEscrow {
+		data {
+			Id int
+		}
+		action {
+			$result = EscrowSettle($Id)
+		}
+	}
+	contract TestEscrowSteal {
+		data {
+			Kind int
+		}
+		action {
+			if $Kind == 1 {
+				EscrowLock("", 2, 10, "", 1600000000)
+			}
+			if $Kind == 2 {
+				EscrowRelease(3)
+			}
+			if $Kind == 3 {
+				EscrowCancel(3)
+			}
+			if $Kind == 4 {
+				EscrowSettle(2)
+			}
+		}
+	}`))
+	balance := func(id string) string {
+		row, err := h.Storage.get(`1_keys`, []string{`id`}, []string{id})
+		require.NoError(t, err)
+		return row[`amount`]
+	}
+	lock := func(amount string, condition string, deadline int64) map[string]interface{} {
+		return map[string]interface{}{`Amount`: amount, `Condition`: condition, `Deadline`: deadline, `Recipient`: 2}
+	}
+	id := func(id int64) map[string]interface{} {
+		return map[string]interface{}{`Id`: id}
+	}
+
+	runCalls(t, h, []testCall{
+		{name: `lock`, contract: `LockEscrow`, params: lock(`300`, ``, h.Time+100), result: `1`},
+		{name: `not enough funds`, contract: `LockEscrow`, params: lock(`800`, ``, h.Time+100),
+			err: `not enough funds on the balance of 1`},
+		{name: `passed deadline`, contract: `LockEscrow`, params: lock(`10`, ``, h.Time),
+			err: `deadline 1530000000 of escrow has passed`},
+		{name: `wrong condition`, contract: `LockEscrow`, params: lock(`10`, `UnknownFunc(1)`, h.Time+100),
+			err: `unknown identifier UnknownFunc`},
+		{name: `lock not system`, contract: `TestEscrowSteal`, params: map[string]interface{}{`Kind`: 1},
+			err: `EscrowLock can be only called from LockEscrow`},
+		{name: `not expired`, contract: `SettleEscrow`, params: id(1), err: `escrow 1 is not expired`},
+	})
+	require.Equal(t, `700`, balance(`1`))
+
+	h.Time += 100
+	runCalls(t, h, []testCall{
+		{name: `release by deadline`, contract: `SettleEscrow`, params: id(1), result: `released`},
+		{name: `settled`, contract: `SettleEscrow`, params: id(1), err: `escrow 1 has been released`},
+		{name: `lock with condition`, contract: `LockEscrow`, params: lock(`200`, `$key_id == 2`, h.Time+100),
+			result: `2`},
+	})
+	require.Equal(t, `400`, balance(`2`))
+
+	h.Time += 100
+	runCalls(t, h, []testCall{
+		{name: `settle not system`, contract: `TestEscrowSteal`, params: map[string]interface{}{`Kind`: 4},
+			err: `EscrowSettle can be only called from SettleEscrow`},
+		{name: `refund by deadline`, contract: `SettleEscrow`, params: id(2), result: `refunded`},
+		{name: `lock to cancel`, contract: `LockEscrow`, params: lock(`100`, ``, h.Time+100), result: `3`},
+		{name: `cancel by recipient`, key: 2, contract: `CancelEscrow`, params: id(3), err: `Access denied`},
+		{name: `release by recipient`, key: 2, contract: `ReleaseEscrow`, params: id(3), err: `Access denied`},
+		{name: `release not system`, contract: `TestEscrowSteal`, params: map[string]interface{}{`Kind`: 2},
+			err: `EscrowRelease can be only called from ReleaseEscrow`},
+		{name: `cancel not system`, contract: `TestEscrowSteal`, params: map[string]interface{}{`Kind`: 3},
+			err: `EscrowCancel can be only called from CancelEscrow`},
+		{name: `cancel`, contract: `CancelEscrow`, params: id(3)},
+		{name: `cancelled`, contract: `CancelEscrow`, params: id(3), err: `escrow 3 has been refunded`},
+		{name: `lock to release`, contract: `LockEscrow`, params: lock(`50`, `$key_id == 2`, h.Time+100), result: `4`},
+		{name: `release by condition`, key: 2, contract: `ReleaseEscrow`, params: id(4)},
+	})
+	require.Equal(t, `650`, balance(`1`))
+	require.Equal(t, `450`, balance(`2`))
+	require.Len(t, h.Storage.Table(`1_history`), 8)
+}
+
+func TestContractVersions(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	require.NoError(t, h.Compile(`contract NewContract {
+		data {
+			Name string
+			Value string
+		}
+		action {
+			$result = CreateContract($Name, $Value, "true", 0, 1, 1)
+		}
+	}
+	contract EditContract {
+		data {
+			Id int
+			Value string
+			Failed string "optional"
+		}
+		action {
+			UpdateContract($Id, $Value, "", "", 0, "0", "1")
+			if Size($Failed) > 0 {
+				var par map
+				error "failed " + CallContract($Failed, par)
+			}
+		}
+	}
+	contract TestCallVersion {
+		data {
+			Name string
+		}
+		action {
+			var par map
+			$result = CallContract($Name, par)
+		}
+	}`))
+	source := func(ret string) string {
+		return `contract TestVersioned {
+			action {
+				$result = "` + ret + `"
+			}
+		}`
+	}
+	call := func(name string) map[string]interface{} {
+		return map[string]interface{}{`Name`: name}
+	}
+	edit := func(value string) map[string]interface{} {
+		return map[string]interface{}{`Id`: 1, `Value`: source(value)}
+	}
+
+	runCalls(t, h, []testCall{
+		{name: `create`, contract: `NewContract`, params: map[string]interface{}{`Name`: `TestVersioned`,
+			`Value`: source(`one`)}, result: `1`},
+		{name: `second version`, contract: `EditContract`, params: edit(`two`)},
+		{name: `third version`, contract: `EditContract`, params: edit(`three`)},
+		{name: `wrong source`, contract: `EditContract`, params: map[string]interface{}{`Id`: 1, `Value`: `contract {`},
+			err: `must be the name`},
+		{name: `unknown contract`, contract: `EditContract`, params: map[string]interface{}{`Id`: 2, `Value`: source(`two`)},
+			err: `Contract has not been found`},
+	})
+	row, err := h.Storage.get(`1_contracts`, []string{`id`}, []string{`1`})
+	require.NoError(t, err)
+	require.Equal(t, `3`, row[`version`])
+	require.Len(t, h.Storage.Table(`contract_versions`), 3)
+
+	// the pinned versions don't change the contracts of the virtual machine
+	children := len(smart.GetVM(false, 0).Children)
+	runCalls(t, h, []testCall{
+		{name: `active`, contract: `TestCallVersion`, params: call(`TestVersioned`), result: `three`},
+		{name: `first`, contract: `TestCallVersion`, params: call(`@1TestVersioned#v1`), result: `one`},
+		{name: `second`, contract: `TestCallVersion`, params: call(`TestVersioned#v2`), result: `two`},
+		{name: `second again`, contract: `TestCallVersion`, params: call(`TestVersioned#v2`), result: `two`},
+		{name: `third`, contract: `TestCallVersion`, params: call(`@1TestVersioned#v3`), result: `three`},
+		{name: `unknown version`, contract: `TestCallVersion`, params: call(`@1TestVersioned#v4`),
+			err: `unknown contract @1TestVersioned#v4`},
+	})
+	require.Equal(t, children, len(smart.GetVM(false, 0).Children))
+	_, ok := smart.GetVM(false, 0).Objects[`@1TestVersioned#v2`]
+	require.False(t, ok)
+
+	txs := h.Storage.Table(`tx_contracts`)
+	require.NotEmpty(t, txs)
+	require.Equal(t, `@1TestCallVersion`, txs[len(txs)-1][`contract`])
+
+	// the version of the failed transaction is rolled back so the cached one isn't used
+	runCalls(t, h, []testCall{
+		{name: `failed version`, contract: `EditContract`, params: map[string]interface{}{`Id`: 1,
+			`Value`: source(`lost`), `Failed`: `@1TestVersioned#v4`}, err: `failed lost`},
+		{name: `fourth version`, contract: `EditContract`, params: edit(`four`)},
+		{name: `fourth`, contract: `TestCallVersion`, params: call(`@1TestVersioned#v4`), result: `four`},
+	})
+}
+
+func TestSponsor(t *testing.T) {
+	h := New()
+	h.NodeID = 10
+	h.AddKey(1, `1000`)
+	h.AddKey(5, `1000`)
+	h.AddKey(10, `0`)
+	sponsor, err := h.NewKey(`100000`)
+	require.NoError(t, err)
+	user, err := h.NewKey(`0`)
+	require.NoError(t, err)
+	forged, err := h.NewKey(`1000`)
+	require.NoError(t, err)
+	h.privates[forged] = h.privates[sponsor]
+	require.NoError(t, h.Compile(`contract SetSponsor {
+		data {
+			Budget money
+			Contracts string "optional"
+		}
+		action {
+			var list array
+			if Size($Contracts) > 0 {
+				list = Split($Contracts, ",")
+			}
+			$result = SponsorSet($Budget, list)
+		}
+	}
+	contract TestSetSponsor {
+		action {
+			var list array
+			$result = SponsorSet("100", list)
+		}
+	}
+	contract TestSponsorBudget {
+		data {
+			Key int
+		}
+		action {
+			$result = SponsorBudget($Key)
+		}
+	}
+	contract TestSponsored {
+		action {
+			$result = "sponsored"
+		}
+	}
+	contract TestNotSponsored {
+		action {
+			$result = "not sponsored"
+		}
+	}`))
+	// the contracts of one source share the owner so the active contract is compiled separately
+	require.NoError(t, h.Compile(`contract TestActiveSponsored {
+		action {
+			$result = "active"
+		}
+	}`))
+	active := smart.VMGetContract(smart.GetVM(false, 0), `TestActiveSponsored`, 1)
+	active.Block.Info.(*script.ContractInfo).Owner.Active = true
+	active.Block.Info.(*script.ContractInfo).Owner.WalletID = 1
+	sponsorRow := func() map[string]string {
+		row, err := h.Storage.get(`sponsors`, []string{`ecosystem`, `key_id`},
+			[]string{`1`, converter.Int64ToStr(sponsor)})
+		require.NoError(t, err)
+		require.NotNil(t, row)
+		return row
+	}
+	amount := func(key int64) int64 {
+		row, err := h.Storage.get(`1_keys`, []string{`id`}, []string{converter.Int64ToStr(key)})
+		require.NoError(t, err)
+		return converter.StrToInt64(row[`amount`])
+	}
+
+	runCalls(t, h, []testCall{
+		{name: `no budget`, contract: `TestSponsorBudget`, params: map[string]interface{}{`Key`: sponsor}, result: `0`},
+		{name: `set sponsor`, key: sponsor, contract: `SetSponsor`, params: map[string]interface{}{`Budget`: `50000`,
+			`Contracts`: `TestSponsored, @1TestActiveSponsored,@1SetSponsor`}, result: `1`},
+		{name: `budget`, contract: `TestSponsorBudget`, params: map[string]interface{}{`Key`: sponsor}, result: `50000`},
+		{name: `not gated`, contract: `TestSetSponsor`, err: `SponsorSet can be only called from SetSponsor`},
+		{name: `unknown contract`, key: sponsor, contract: `SetSponsor`,
+			params: map[string]interface{}{`Budget`: `100`, `Contracts`: `TestUnknown`}, err: `unknown contract @1TestUnknown`},
+		{name: `negative budget`, key: sponsor, contract: `SetSponsor`, params: map[string]interface{}{`Budget`: `-1`}, err: `token amount`},
+		{name: `no balance`, key: user, contract: `TestSponsored`, err: `current balance is not enough`},
+		{name: `sponsored`, key: user, sponsor: sponsor, contract: `TestSponsored`, result: `sponsored`},
+		{name: `not in list`, key: user, sponsor: sponsor, contract: `TestNotSponsored`, err: smart.ErrSponsorContract.Error()},
+		{name: `not a sponsor`, key: user, sponsor: 5, contract: `TestSponsored`, err: smart.ErrSponsorSign.Error()},
+		{name: `forged sign`, key: user, sponsor: forged, contract: `TestSponsored`, err: smart.ErrSponsorSign.Error()},
+		{name: `active contract`, key: user, sponsor: sponsor, contract: `TestActiveSponsored`, err: smart.ErrSponsorActive.Error()},
+	})
+	require.Len(t, h.Storage.Table(`sponsors`), 1)
+	require.Equal(t, `@1TestSponsored,@1TestActiveSponsored,@1SetSponsor`, sponsorRow()[`contracts`])
+	require.Equal(t, int64(0), amount(user))
+
+	// the fuel of the sponsored call is paid from the wallet of the sponsor and from the budget
+	before := amount(sponsor)
+	h.KeyID, h.Sponsor = user, sponsor
+	ret, err := h.Call(`TestSponsored`, nil)
+	require.NoError(t, err)
+	require.True(t, ret.Fuel > 0)
+	require.Equal(t, before-ret.Fuel, amount(sponsor))
+	require.Equal(t, int64(0), amount(user))
+	row := sponsorRow()
+	require.Equal(t, int64(50000), converter.StrToInt64(row[`budget`])+converter.StrToInt64(row[`spent`]))
+	spent := converter.StrToInt64(row[`spent`])
+	require.True(t, spent > ret.Fuel)
+
+	// the payment is limited by the budget and the empty budget stops the sponsoring
+	runCalls(t, h, []testCall{
+		{name: `small budget`, key: sponsor, contract: `SetSponsor`, params: map[string]interface{}{`Budget`: `1`,
+			`Contracts`: `TestSponsored`}, result: `1`},
+	})
+	before = amount(sponsor)
+	h.KeyID, h.Sponsor = user, sponsor
+	ret, err = h.Call(`TestSponsored`, nil)
+	require.NoError(t, err)
+	require.True(t, ret.Fuel > 1)
+	require.Equal(t, before-1, amount(sponsor))
+	row = sponsorRow()
+	require.Equal(t, `0`, row[`budget`])
+	require.Equal(t, spent+1, converter.StrToInt64(row[`spent`]))
+	require.Equal(t, `@1TestSponsored`, row[`contracts`])
+
+	runCalls(t, h, []testCall{
+		{name: `empty budget`, key: user, sponsor: sponsor, contract: `TestSponsored`, err: smart.ErrSponsorBudget.Error()},
+		{name: `spent budget`, contract: `TestSponsorBudget`, params: map[string]interface{}{`Key`: sponsor}, result: `0`},
+		{name: `empty list`, key: sponsor, contract: `SetSponsor`, params: map[string]interface{}{`Budget`: `50000`}, result: `1`},
+		{name: `nothing sponsored`, key: user, sponsor: sponsor, contract: `TestSponsored`, err: smart.ErrSponsorContract.Error()},
+	})
+}
+
+func TestTryMemory(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	require.NoError(t, h.Compile(`contract ActivateContract {
+		data {
+			Mode int
+		}
+		action {
+			if $Mode == 1 {
+				try {
+					Activate(0, 0)
+				} catch err {
+					$result = err["error"]
+				}
+			} else {
+				try {
+					$result = "try "
+				} catch err {
+				}
+				Activate(0, 0)
+				$result = $result + "activated"
+			}
+		}
+	}
+	contract NewEcosystem {
+		action {
+			try {
+				CreateEcosystem($key_id, "test")
+			} catch err {
+				$result = err["error"]
+			}
+		}
+	}
+	contract TestTrySysParam {
+		action {
+			try {
+				DBUpdateSysParam("gap_between_blocks", "3", "")
+			} catch err {
+				$result = err["error"]
+			}
+		}
+	}`))
+
+	runCalls(t, h, []testCall{
+		{name: `in try`, contract: `ActivateContract`, params: map[string]interface{}{`Mode`: 1},
+			result: `Activate can't be called in the try block`},
+		{name: `after try`, contract: `ActivateContract`, params: map[string]interface{}{`Mode`: 2}, result: `try activated`},
+		{name: `ecosystem`, contract: `NewEcosystem`, result: `CreateEcosystem can't be called in the try block`},
+		{name: `system parameter`, contract: `TestTrySysParam`, result: `DBUpdateSysParam can't be called in the try block`},
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	target := `contract TestDryRunTarget {
+		action {
+			$result = "%s"
+		}
+	}`
+	require.NoError(t, h.Compile(fmt.Sprintf(target, `old`)))
+	h.Storage.AddRow(`1_contracts`, map[string]string{`name`: `TestDryRunTarget`,
+		`value`: fmt.Sprintf(target, `old`), `version`: `0`})
+	require.NoError(t, h.Compile(`contract EditContract {
+		data {
+			Id int
+			Value string
+		}
+		action {
+			UpdateContract($Id, $Value, "", "", 0, "0", "0")
+		}
+	}`))
+	edit := map[string]interface{}{`Id`: 1, `Value`: fmt.Sprintf(target, `new`)}
+
+	h.DryRun = true
+	runCalls(t, h, []testCall{
+		{name: `dry run`, contract: `EditContract`, params: edit, err: `FlushContract can't be called in the dry run`},
+		{name: `not changed`, contract: `TestDryRunTarget`, result: `old`},
+	})
+	h.DryRun = false
+	runCalls(t, h, []testCall{
+		{name: `edit`, contract: `EditContract`, params: edit},
+		{name: `changed`, contract: `TestDryRunTarget`, result: `new`},
+	})
+}
+
+func TestSystemContracts(t *testing.T) {
+	h := New()
+	h.AddKey(1, `100`)
+	require.NoError(t, h.Compile(`contract ImportSystemContracts {
+		data {
+			Names string
+		}
+		action {
+			$result = CreateSystemContracts(Split($Names, ","))
+		}
+	}
+	contract TestSystemContracts {
+		action {
+			$result = CreateSystemContracts(Split("EditTableName", ","))
+		}
+	}`))
+	names := func(value string) map[string]interface{} {
+		return map[string]interface{}{`Names`: value}
+	}
+
+	runCalls(t, h, []testCall{
+		{name: `import`, contract: `ImportSystemContracts`, params: names(`EditColumnName, DelColumn`), result: `2`},
+		{name: `exists`, contract: `ImportSystemContracts`, params: names(`EditTableName,DelColumn`),
+			err: `contract DelColumn exists`},
+		{name: `repeated`, contract: `ImportSystemContracts`, params: names(`EditTableName,EditTableName`),
+			err: `contract EditTableName exists`},
+		{name: `unknown`, contract: `ImportSystemContracts`, params: names(`NewContract`),
+			err: `unknown system contract NewContract`},
+		{name: `not gated`, contract: `TestSystemContracts`,
+			err: `CreateSystemContracts can be only called from ImportSystemContracts`},
+	})
+	rows := h.Storage.Table(`1_contracts`)
+	require.Len(t, rows, 2)
+	require.Equal(t, `EditColumnName`, rows[0][`name`])
+	require.Equal(t, `DelColumn`, rows[1][`name`])
+	require.NotNil(t, smart.VMGetContract(smart.GetVM(false, 0), `DelColumn`, 1))
+	require.Nil(t, smart.VMGetContract(smart.GetVM(false, 0), `EditTableName`, 1))
+}
+
+func TestSpec(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `smarttest`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, `spec.sim`), []byte(`contract TestSpecPay {
+		data {
+			Recipient int
+			Amount money
+		}
+		action {
+			if EcosysParam("limit") < $Amount {
+				error "limit"
+			}
+			DBUpdate("keys", $key_id, "-amount", $Amount)
+			DBUpdate("keys", $Recipient, "+amount", $Amount)
+		}
+	}`), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, `spec.json`), []byte(`{
+		"sources": ["spec.sim"],
+		"fixtures": {"keys": {"1": "100", "2": "0"}, "parameters": {"limit": "50"}},
+		"tests": [
+			{"contract": "TestSpecPay", "params": {"Recipient": 2, "Amount": "40"}, "changes": [
+				{"table": "1_keys", "id": "1", "values": {"amount": "60"}},
+				{"table": "1_keys", "id": "2", "values": {"amount": "40"}}
+			]},
+			{"contract": "TestSpecPay", "params": {"Recipient": 2, "Amount": "60"}, "error": "limit"}
+		]
+	}`), 0600))
+
+	spec, err := LoadSpec(filepath.Join(dir, `spec.json`))
+	require.NoError(t, err)
+	h, err := spec.Harness()
+	require.NoError(t, err)
+	require.NoError(t, spec.Tests[0].Check(h))
+	require.NoError(t, spec.Tests[1].Check(h))
+	spec.Tests[1].Error = `wrong limit`
+	require.EqualError(t, spec.Tests[1].Check(h), `error wrong limit is expected but got `+
+		`{"type":"error","error":"limit","trace":[{"name":"@1TestSpecPay.action","line":8,"column":6}]}`)
+	row, err := h.Storage.get(`1_keys`, []string{`id`}, []string{`1`})
+	require.NoError(t, err)
+	require.Equal(t, `60`, row[`amount`])
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smarttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Fixtures are the initial data of the storage
+type Fixtures struct {
+	Ecosystems map[string]string              `json:"ecosystems"` // id -> name
+	Keys       map[string]string              `json:"keys"`       // id -> amount
+	Parameters map[string]string              `json:"parameters"` // the ecosystem parameters
+	SysParams  map[string]string              `json:"sys_params"`
+	Tables     map[string][]map[string]string `json:"tables"`
+}
+
+// Case is the call of the contract with the expected results. Fuel and Changes are checked
+// if they are specified.
+type Case struct {
+	Name     string                 `json:"name"`
+	Contract string                 `json:"contract"`
+	Params   map[string]interface{} `json:"params"`
+	KeyID    int64                  `json:"key_id"`
+	Result   string                 `json:"result"`
+	Error    string                 `json:"error"` // the text of the error of the contract
+	Fuel     int64                  `json:"fuel"`
+	Changes  *[]Change              `json:"changes"`
+}
+
+// Spec is the file of the contract tests. Sources are the files of the contracts relative
+// to the spec file.
+type Spec struct {
+	Ecosystem int64    `json:"ecosystem"`
+	KeyID     int64    `json:"key_id"`
+	Sources   []string `json:"sources"`
+	Fixtures  Fixtures `json:"fixtures"`
+	Tests     []Case   `json:"tests"`
+
+	dir string
+}
+
+// LoadSpec reads the spec from the json file
+func LoadSpec(filename string) (*Spec, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": filename}).Error("reading spec file")
+		return nil, err
+	}
+	spec := Spec{Ecosystem: 1, KeyID: 1, dir: filepath.Dir(filename)}
+	if err = json.Unmarshal(data, &spec); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "path": filename}).Error("unmarshalling spec")
+		return nil, fmt.Errorf(`%s: %v`, filename, err)
+	}
+	return &spec, nil
+}
+
+// Harness returns the harness with the compiled sources and the fixtures of the spec
+func (spec *Spec) Harness() (*Harness, error) {
+	h := New()
+	h.Ecosystem = spec.Ecosystem
+	h.KeyID = spec.KeyID
+	fix := spec.Fixtures
+	for id, name := range fix.Ecosystems {
+		h.AddEcosystem(converter.StrToInt64(id), name)
+	}
+	for id, amount := range fix.Keys {
+		h.AddKey(converter.StrToInt64(id), amount)
+	}
+	for name, value := range fix.Parameters {
+		h.SetEcosysParam(name, value)
+	}
+	if len(fix.SysParams) > 0 {
+		if err := h.SetSysParams(fix.SysParams); err != nil {
+			return nil, err
+		}
+	}
+	for table, rows := range fix.Tables {
+		for _, row := range rows {
+			h.Storage.AddRow(table, row)
+		}
+	}
+	for _, src := range spec.Sources {
+		data, err := ioutil.ReadFile(filepath.Join(spec.dir, src))
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err, "path": src}).Error("reading contract source")
+			return nil, err
+		}
+		if err = h.Compile(string(data)); err != nil {
+			return nil, fmt.Errorf(`%s: %v`, src, err)
+		}
+	}
+	return h, nil
+}
+
+// Check calls the contract of the case and compares the results with the expected ones
+func (c *Case) Check(h *Harness) error {
+	keyID := h.KeyID
+	if c.KeyID != 0 {
+		h.KeyID = c.KeyID
+		defer func() { h.KeyID = keyID }()
+	}
+	ret, err := h.Call(c.Contract, c.Params)
+	if err != nil {
+		if len(c.Error) == 0 {
+			return fmt.Errorf(`unexpected error %v`, err)
+		}
+		msg := err.Error()
+		var contractErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal([]byte(msg), &contractErr) == nil && len(contractErr.Error) > 0 {
+			msg = contractErr.Error
+		}
+		if msg != c.Error {
+			return fmt.Errorf(`error %v is expected but got %v`, c.Error, err)
+		}
+		return nil
+	}
+	if len(c.Error) > 0 {
+		return fmt.Errorf(`error %s is expected`, c.Error)
+	}
+	if ret.Result != c.Result {
+		return fmt.Errorf(`result %s is expected but got %s`, c.Result, ret.Result)
+	}
+	if c.Fuel != 0 && ret.Fuel != c.Fuel {
+		return fmt.Errorf(`fuel %d is expected but got %d`, c.Fuel, ret.Fuel)
+	}
+	if c.Changes != nil && !(len(*c.Changes) == 0 && len(ret.Changes) == 0) &&
+		!reflect.DeepEqual(*c.Changes, ret.Changes) {
+		out, _ := json.Marshal(ret.Changes)
+		return fmt.Errorf(`the changes are different %s`, out)
+	}
+	return nil
+}
@@ -0,0 +1,427 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smarttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/smart"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// Change is the change of the row which has been made by the contract
+type Change struct {
+	Table   string            `json:"table"`
+	ID      string            `json:"id"`
+	Values  map[string]string `json:"values"`            // the new values of the changed columns
+	Deleted bool              `json:"deleted,omitempty"` // the row has been deleted
+}
+
+// MemStorage is the in-memory storage of the tables which implements smart.Storage.
+// It supports the simple where conditions like "name = ? and amount > 10".
+type MemStorage struct {
+	tables  map[string][]map[string]string
+	changes []Change
+}
+
+var (
+	reAnd  = regexp.MustCompile(`(?i)\s+and\s+`)
+	reCond = regexp.MustCompile(`^\s*\(?\s*([\w]+)\s*(=|!=|<>|<=|>=|<|>)\s*(\?|'(?:[^']|'')*'|-?\d+)\s*\)?\s*$`)
+)
+
+// NewMemStorage returns the empty storage
+func NewMemStorage() *MemStorage {
+	return &MemStorage{tables: make(map[string][]map[string]string)}
+}
+
+// AddRow adds the row of the fixture to the table. It is not included in the changes.
+// It returns the id of the row.
+func (s *MemStorage) AddRow(table string, row map[string]string) string {
+	table = strings.ToLower(table)
+	item := make(map[string]string, len(row)+1)
+	for key, val := range row {
+		item[strings.ToLower(key)] = val
+	}
+	if len(item[`id`]) == 0 {
+		item[`id`] = s.nextID(table)
+	}
+	s.tables[table] = append(s.tables[table], item)
+	return item[`id`]
+}
+
+// Table returns the rows of the table
+func (s *MemStorage) Table(table string) []map[string]string {
+	return s.tables[strings.ToLower(table)]
+}
+
+// Changes returns the changes of the rows since the last call of ResetChanges
+func (s *MemStorage) Changes() []Change {
+	return s.changes
+}
+
+// ResetChanges clears the list of the changes
+func (s *MemStorage) ResetChanges() {
+	s.changes = nil
+}
+
+// snapshot returns the copy of the tables
+func (s *MemStorage) snapshot() map[string][]map[string]string {
+	ret := make(map[string][]map[string]string, len(s.tables))
+	for name, rows := range s.tables {
+		list := make([]map[string]string, len(rows))
+		for i, row := range rows {
+			list[i] = make(map[string]string, len(row))
+			for key, val := range row {
+				list[i][key] = val
+			}
+		}
+		ret[name] = list
+	}
+	return ret
+}
+
+func (s *MemStorage) nextID(table string) string {
+	var max int64
+	for _, row := range s.tables[table] {
+		if id := converter.StrToInt64(row[`id`]); id > max {
+			max = id
+		}
+	}
+	return converter.Int64ToStr(max + 1)
+}
+
+func (s *MemStorage) find(table string, match func(map[string]string) bool) map[string]string {
+	for _, row := range s.tables[table] {
+		if match(row) {
+			return row
+		}
+	}
+	return nil
+}
+
+// Get returns the row which matches the where fields
+func (s *MemStorage) Get(sc *smart.SmartContract, table string, whereFields, whereValues []string) (map[string]string, error) {
+	return s.get(table, whereFields, whereValues)
+}
+
+func (s *MemStorage) get(table string, whereFields, whereValues []string) (map[string]string, error) {
+	if len(whereFields) != len(whereValues) {
+		return nil, fmt.Errorf(`wrong where values`)
+	}
+	return s.find(strings.ToLower(table), func(row map[string]string) bool {
+		for i, field := range whereFields {
+			if row[field] != whereValues[i] {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+// setValue assigns the value to the field of the row. The field can be like +amount, -amount,
+// timestamp date or doc->key.
+func setValue(row map[string]string, field, value string) (string, error) {
+	if value == `NULL` {
+		value = ``
+	}
+	switch {
+	case strings.HasPrefix(field, `+`) || strings.HasPrefix(field, `-`):
+		name := field[1:]
+		cur := decimal.New(0, 0)
+		if len(row[name]) > 0 {
+			var err error
+			if cur, err = decimal.NewFromString(row[name]); err != nil {
+				return name, err
+			}
+		}
+		val, err := decimal.NewFromString(value)
+		if err != nil {
+			return name, err
+		}
+		if field[0] == '+' {
+			cur = cur.Add(val)
+		} else {
+			cur = cur.Sub(val)
+		}
+		row[name] = cur.String()
+		return name, nil
+	case strings.HasPrefix(field, `timestamp `):
+		field = field[len(`timestamp `):]
+	case strings.Contains(field, `->`):
+		colfield := strings.SplitN(field, `->`, 2)
+		doc := make(map[string]interface{})
+		if len(row[colfield[0]]) > 0 {
+			if err := json.Unmarshal([]byte(row[colfield[0]]), &doc); err != nil {
+				return colfield[0], err
+			}
+		}
+		doc[colfield[1]] = value
+		out, err := json.Marshal(doc)
+		if err != nil {
+			return colfield[0], err
+		}
+		row[colfield[0]] = string(out)
+		return colfield[0], nil
+	}
+	row[field] = value
+	return field, nil
+}
+
+// Update updates the row which matches the where fields or inserts the new row. The cost is not calculated.
+func (s *MemStorage) Update(sc *smart.SmartContract, fields, values []string, table string, whereFields,
+	whereValues []string, generalRollback, exists bool) (int64, string, error) {
+	var (
+		row map[string]string
+		err error
+	)
+	if whereFields != nil && whereValues != nil {
+		if row, err = s.get(table, whereFields, whereValues); err != nil {
+			return 0, ``, err
+		}
+	}
+	if row != nil {
+		return 0, row[`id`], s.update(table, row[`id`], fields, values)
+	}
+	if exists {
+		log.WithFields(log.Fields{"type": consts.NotFound, "table": table}).Error("updating for not existing record")
+		return 0, ``, smart.ErrUpdNotExistRecord
+	}
+	if whereFields != nil && whereValues != nil {
+		fields = append(append([]string{}, fields...), whereFields...)
+		values = append(append([]string{}, values...), whereValues...)
+	}
+	id, err := s.insert(table, fields, values)
+	return 0, id, err
+}
+
+func (s *MemStorage) insert(table string, fields, values []string) (string, error) {
+	table = strings.ToLower(table)
+	row := make(map[string]string)
+	for i, field := range fields {
+		if _, err := setValue(row, strings.ToLower(field), values[i]); err != nil {
+			log.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "field": field}).Error("inserting row")
+			return ``, err
+		}
+	}
+	if len(row[`id`]) == 0 {
+		row[`id`] = s.nextID(table)
+	} else if s.find(table, func(item map[string]string) bool { return item[`id`] == row[`id`] }) != nil {
+		return ``, fmt.Errorf(`duplicate id %s in %s`, row[`id`], table)
+	}
+	s.tables[table] = append(s.tables[table], row)
+	changed := make(map[string]string, len(row))
+	for key, val := range row {
+		changed[key] = val
+	}
+	s.changes = append(s.changes, Change{Table: table, ID: row[`id`], Values: changed})
+	return row[`id`], nil
+}
+
+func (s *MemStorage) update(table, id string, fields, values []string) error {
+	table = strings.ToLower(table)
+	row := s.find(table, func(item map[string]string) bool { return item[`id`] == id })
+	if row == nil {
+		return fmt.Errorf(`item %s has not been found in %s`, id, table)
+	}
+	changed := make(map[string]string)
+	for i, field := range fields {
+		prev := make(map[string]string, len(row))
+		for key, val := range row {
+			prev[key] = val
+		}
+		name, err := setValue(row, strings.ToLower(field), values[i])
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "field": field}).Error("updating row")
+			return err
+		}
+		if _, ok := changed[name]; ok || prev[name] != row[name] {
+			changed[name] = row[name]
+		}
+	}
+	// the update which doesn't change the values, for example, the zero payment, is not the change
+	if len(changed) > 0 {
+		s.changes = append(s.changes, Change{Table: table, ID: id, Values: changed})
+	}
+	return nil
+}
+
+// Delete deletes the row with the specified id. The cost is not calculated.
+func (s *MemStorage) Delete(sc *smart.SmartContract, table, id string, generalRollback bool) (int64, error) {
+	table = strings.ToLower(table)
+	for i, item := range s.tables[table] {
+		if item[`id`] == id {
+			s.tables[table] = append(s.tables[table][:i], s.tables[table][i+1:]...)
+			s.changes = append(s.changes, Change{Table: table, ID: id, Deleted: true})
+			return 0, nil
+		}
+	}
+	log.WithFields(log.Fields{"type": consts.NotFound, "table": table, "id": id}).Error("deleting not existing record")
+	return 0, smart.ErrDelNotExistRecord
+}
+
+// compare compares two values as numbers if it is possible
+func compare(left, right string) int {
+	if lnum, err := decimal.NewFromString(left); err == nil {
+		if rnum, err := decimal.NewFromString(right); err == nil {
+			return lnum.Cmp(rnum)
+		}
+	}
+	return strings.Compare(left, right)
+}
+
+type condition struct {
+	column string
+	oper   string
+	value  string
+}
+
+func parseWhere(where string, params []interface{}) ([]condition, error) {
+	var conds []condition
+	if len(strings.TrimSpace(where)) == 0 {
+		return conds, nil
+	}
+	for _, item := range reAnd.Split(strings.TrimSpace(where), -1) {
+		match := reCond.FindStringSubmatch(item)
+		if match == nil {
+			log.WithFields(log.Fields{"type": consts.ParseError, "condition": item}).Error("unsupported condition")
+			return nil, fmt.Errorf(`unsupported condition %s`, item)
+		}
+		cond := condition{column: strings.ToLower(match[1]), oper: match[2], value: match[3]}
+		switch {
+		case cond.value == `?`:
+			if len(params) == 0 {
+				return nil, fmt.Errorf(`there is not value of %s`, item)
+			}
+			cond.value = fmt.Sprint(params[0])
+			params = params[1:]
+		case strings.HasPrefix(cond.value, `'`):
+			cond.value = strings.Replace(cond.value[1:len(cond.value)-1], `''`, `'`, -1)
+		}
+		conds = append(conds, cond)
+	}
+	return conds, nil
+}
+
+func (cond condition) match(row map[string]string) bool {
+	cmp := compare(row[cond.column], cond.value)
+	switch cond.oper {
+	case `=`:
+		return cmp == 0
+	case `!=`, `<>`:
+		return cmp != 0
+	case `<`:
+		return cmp < 0
+	case `<=`:
+		return cmp <= 0
+	case `>`:
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// Select returns the rows which match the where condition
+func (s *MemStorage) Select(sc *smart.SmartContract, table, columns, where string, params []interface{}, order string,
+	offset, limit int64) ([]interface{}, error) {
+	conds, err := parseWhere(where, params)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]map[string]string, 0)
+	for _, row := range s.tables[strings.ToLower(table)] {
+		matched := true
+		for _, cond := range conds {
+			matched = matched && cond.match(row)
+		}
+		if matched {
+			list = append(list, row)
+		}
+	}
+	orders := strings.Split(order, `,`)
+	sort.SliceStable(list, func(i, j int) bool {
+		for _, item := range orders {
+			fields := strings.Fields(strings.ToLower(item))
+			if len(fields) == 0 {
+				continue
+			}
+			if cmp := compare(list[i][fields[0]], list[j][fields[0]]); cmp != 0 {
+				return (cmp < 0) != (len(fields) > 1 && fields[1] == `desc`)
+			}
+		}
+		return false
+	})
+	if offset >= int64(len(list)) {
+		return []interface{}{}, nil
+	}
+	list = list[offset:]
+	if limit > 0 && limit < int64(len(list)) {
+		list = list[:limit]
+	}
+	ret := make([]interface{}, len(list))
+	for i, row := range list {
+		item := make(map[string]string)
+		for _, col := range strings.Split(columns, `,`) {
+			if col = strings.TrimSpace(col); col == `*` {
+				for key, val := range row {
+					item[key] = val
+				}
+			} else {
+				item[col] = row[col]
+			}
+		}
+		ret[i] = item
+	}
+	return ret, nil
+}
+
+// SelectQuery returns the error because the joins, the grouping and the sums are not supported
+func (s *MemStorage) SelectQuery(sc *smart.SmartContract, query *smart.Query, params []interface{}) (int64, []interface{}, error) {
+	return 0, nil, fmt.Errorf(`Join, GroupBy and Sum are not supported by the storage`)
+}
+
+// NumIndexes returns 0 because the storage doesn't have the indexes
+func (s *MemStorage) NumIndexes(sc *smart.SmartContract, table string) (int, error) {
+	return 0, nil
+}
+
+// HasRole returns true if roles_participants of the ecosystem has the member of the role
+func (s *MemStorage) HasRole(sc *smart.SmartContract, ecosystem, keyID, role int64) (bool, error) {
+	member := s.find(fmt.Sprintf(`%d_roles_participants`, ecosystem), func(row map[string]string) bool {
+		return jsonField(row[`role`], `id`) == converter.Int64ToStr(role) &&
+			jsonField(row[`member`], `member_id`) == converter.Int64ToStr(keyID)
+	})
+	return member != nil, nil
+}
+
+// jsonField returns the value of the field of the json object as the string.
+// The numbers are kept as they are written.
+func jsonField(data, field string) string {
+	var value map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if decoder.Decode(&value) != nil || value[field] == nil {
+		return ``
+	}
+	return fmt.Sprint(value[field])
+}
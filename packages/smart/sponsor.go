@@ -0,0 +1,161 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+	"github.com/GenesisKernel/go-genesis/packages/utils"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// The sponsor pays the fuel of the transactions of other keys. The sponsor co-signs the transaction and
+// the fuel is paid from the key of the sponsor in the token ecosystem of the transaction. The sponsor
+// limits the spent amount with the budget and the sponsored contracts with the allow-list of the sponsors
+// table, the empty list allows no contract. The allow-list keeps the names with the ecosystems so only
+// the contracts of these ecosystems are sponsored. The paid fuel is deducted from the budget and it is
+// added to the spent amount. The sponsors are stored by the token ecosystem so the budget
+// is counted in the tokens of this ecosystem. The sponsor sets the budget by the transaction with
+// the same token ecosystem as the sponsored transactions.
+
+// The errors of the sponsored transactions
+var (
+	ErrSponsorActive   = errors.New(`Active contract cannot be sponsored`)
+	ErrSponsorBudget   = errors.New(`sponsor budget is not enough`)
+	ErrSponsorContract = errors.New(`Contract is not sponsored`)
+	ErrSponsorSign     = errors.New(`incorrect sponsor sign`)
+)
+
+type sponsor struct {
+	id     string
+	budget decimal.Decimal
+}
+
+func (sc *SmartContract) getSponsor(ecosystem, keyID int64) (map[string]string, error) {
+	return sc.getRow(model.SponsorTableName, []string{`ecosystem`, `key_id`},
+		[]string{converter.Int64ToStr(ecosystem), converter.Int64ToStr(keyID)})
+}
+
+// tokenEcosystem returns the token ecosystem of the transaction, the first ecosystem is the default
+func (sc *SmartContract) tokenEcosystem() int64 {
+	if sc.TxSmart.TokenEcosystem == 0 {
+		return 1
+	}
+	return sc.TxSmart.TokenEcosystem
+}
+
+// sponsorContract checks whether the contract is in the allow-list of the sponsor
+func sponsorContract(contracts, name string) bool {
+	if len(contracts) == 0 {
+		return false
+	}
+	for _, item := range strings.Split(contracts, `,`) {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSponsor checks the signature and the allow-list of the sponsor of the transaction.
+// The public key is the key of the sponsor in the token ecosystem.
+func (sc *SmartContract) checkSponsor(ecosystem int64, publicKey []byte) (*sponsor, error) {
+	logger := sc.GetLogger()
+	if !sc.SkipSign {
+		if len(publicKey) == 0 || len(sc.TxSmart.SponsorSignature) == 0 {
+			logger.WithFields(log.Fields{"type": consts.EmptyObject, "sponsor": sc.TxSmart.Sponsor}).Error("empty sponsor public key or signature")
+			return nil, ErrSponsorSign
+		}
+		ok, err := utils.CheckSign([][]byte{publicKey}, sc.TxData[`forsign`].(string), sc.TxSmart.SponsorSignature, true)
+		if err != nil || !ok {
+			logger.WithFields(log.Fields{"type": consts.InvalidObject, "sponsor": sc.TxSmart.Sponsor, "error": err}).Error("incorrect sponsor sign")
+			return nil, ErrSponsorSign
+		}
+	}
+	row, err := sc.getSponsor(ecosystem, sc.TxSmart.Sponsor)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil || !sponsorContract(row[`contracts`], sc.TxContract.Name) {
+		logger.WithFields(log.Fields{"type": consts.AccessDenied, "sponsor": sc.TxSmart.Sponsor, "contract_name": sc.TxContract.Name}).Error("contract is not sponsored")
+		return nil, ErrSponsorContract
+	}
+	budget, err := decimal.NewFromString(row[`budget`])
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "value": row[`budget`]}).Error("converting sponsor budget from string to decimal")
+		return nil, err
+	}
+	return &sponsor{id: row[`id`], budget: budget}, nil
+}
+
+// SponsorSet sets the budget in the token ecosystem of the transaction and the allow-list of
+// the contracts which the current key sponsors. The names of the contracts without the ecosystem
+// belong to the ecosystem of the transaction. The empty list or the zero budget stops the sponsoring. It returns the id of the sponsor.
+func SponsorSet(sc *SmartContract, budget interface{}, contracts []interface{}) (qcost int64, ret int64, err error) {
+	if !accessContracts(sc, `SetSponsor`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("SponsorSet can be only called from @1SetSponsor")
+		return 0, 0, fmt.Errorf(`SponsorSet can be only called from SetSponsor`)
+	}
+	value, err := tokenAmount(budget)
+	if err != nil {
+		return 0, 0, err
+	}
+	names := make([]string, 0, len(contracts))
+	for _, item := range contracts {
+		name := script.StateName(uint32(sc.TxSmart.EcosystemID), strings.TrimSpace(fmt.Sprint(item)))
+		if VMGetContract(sc.VM, name, uint32(sc.TxSmart.EcosystemID)) == nil {
+			return 0, 0, fmt.Errorf(`unknown contract %s`, name)
+		}
+		names = append(names, name)
+	}
+	ecosystem := sc.tokenEcosystem()
+	keyID := sc.currentKeyID()
+	row, err := sc.getSponsor(ecosystem, keyID)
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := []string{`budget`, `contracts`}
+	values := []interface{}{value.String(), strings.Join(names, `,`)}
+	if row != nil {
+		qcost, _, err = sc.selectiveLoggingAndUpd(fields, values, model.SponsorTableName, []string{`id`},
+			[]string{row[`id`]}, !sc.VDE && sc.Rollback, true)
+		return qcost, converter.StrToInt64(row[`id`]), err
+	}
+	qcost, lastID, err := sc.selectiveLoggingAndUpd(append(fields, `ecosystem`, `key_id`),
+		append(values, ecosystem, keyID), model.SponsorTableName, nil, nil, !sc.VDE && sc.Rollback, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	return qcost, converter.StrToInt64(lastID), nil
+}
+
+// SponsorBudget returns the budget of the sponsor in the token ecosystem of the transaction
+func SponsorBudget(sc *SmartContract, keyID int64) (decimal.Decimal, error) {
+	row, err := sc.getSponsor(sc.tokenEcosystem(), keyID)
+	if err != nil || row == nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromString(row[`budget`])
+}
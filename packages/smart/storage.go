@@ -0,0 +1,159 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Storage keeps the tables of the contracts. The contract works with the database if
+// SmartContract.Storage is not defined. The other storage is set when SmartContract is created,
+// for example, the test harness keeps the tables in memory. The values are passed as strings
+// the same as they are written into the database.
+type Storage interface {
+	// Get returns the row which matches the where fields or nil if there is not such row
+	Get(sc *SmartContract, table string, whereFields, whereValues []string) (map[string]string, error)
+	// Update updates the row which matches the where fields or inserts the new row if there is
+	// not such row and exists is false. The fields with + and - prefixes are increased and decreased
+	// by the values. It returns the cost and the id of the row.
+	Update(sc *SmartContract, fields, values []string, table string, whereFields, whereValues []string,
+		generalRollback, exists bool) (int64, string, error)
+	// Delete deletes the row with the specified id and returns the cost
+	Delete(sc *SmartContract, table, id string, generalRollback bool) (int64, error)
+	// Select returns the rows which match the where condition with ? placeholders
+	Select(sc *SmartContract, table, columns, where string, params []interface{}, order string,
+		offset, limit int64) ([]interface{}, error)
+	// SelectQuery returns the rows of the query with the joined table, the grouping and the sums
+	SelectQuery(sc *SmartContract, query *Query, params []interface{}) (int64, []interface{}, error)
+	// NumIndexes returns the count of the indexes of the table
+	NumIndexes(sc *SmartContract, table string) (int, error)
+	// HasRole returns true if the key is the member of the role of the ecosystem
+	HasRole(sc *SmartContract, ecosystem, keyID, role int64) (bool, error)
+}
+
+// dbStorage is the storage of the tables in the database
+type dbStorage struct{}
+
+func (sc *SmartContract) storage() Storage {
+	if sc.Storage == nil {
+		return dbStorage{}
+	}
+	return sc.Storage
+}
+
+// Get returns the row of the table which matches the where fields
+func (dbStorage) Get(sc *SmartContract, table string, whereFields, whereValues []string) (map[string]string, error) {
+	conds := make([]string, len(whereFields))
+	params := make([]interface{}, len(whereValues))
+	for i, field := range whereFields {
+		conds[i] = field + ` = ?`
+		params[i] = whereValues[i]
+	}
+	row, err := model.GetOneRowTransaction(sc.DbTransaction, `select * from "`+table+`" where `+
+		strings.Join(conds, ` and `), params...).String()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting row")
+		return nil, err
+	}
+	if len(row) == 0 {
+		return nil, nil
+	}
+	return row, nil
+}
+
+// Select returns the rows of the table
+func (dbStorage) Select(sc *SmartContract, tblname, columns, where string, params []interface{}, order string,
+	offset, limit int64) ([]interface{}, error) {
+	columns = PrepareColumns(columns)
+
+	rows, err := model.GetDB(sc.DbTransaction).Table(tblname).Select(columns).Where(where, params...).Order(order).
+		Offset(offset).Limit(limit).Rows()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting rows from table")
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting rows columns")
+		return nil, err
+	}
+	values := make([][]byte, len(cols))
+	scanArgs := make([]interface{}, len(values))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	result := make([]interface{}, 0, 50)
+	for rows.Next() {
+		err = rows.Scan(scanArgs...)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("scanning next row")
+			return nil, err
+		}
+		row := make(map[string]string)
+		for i, col := range values {
+			var value string
+			if col != nil {
+				value = string(col)
+			}
+			row[cols[i]] = value
+		}
+		result = append(result, reflect.ValueOf(row).Interface())
+	}
+	return result, nil
+}
+
+// NumIndexes returns the count of the indexes of the table
+func (dbStorage) NumIndexes(sc *SmartContract, table string) (int, error) {
+	ind, err := model.NumIndexes(table)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("num indexes")
+	}
+	return ind, err
+}
+
+// HasRole returns true if the key is the member of the role of the ecosystem
+func (dbStorage) HasRole(sc *SmartContract, ecosystem, keyID, role int64) (bool, error) {
+	ok, err := model.MemberHasRole(sc.DbTransaction, ecosystem, keyID, role)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("checking role of member")
+	}
+	return ok, err
+}
+
+// getRow returns the row of the table which matches the where fields or nil if there is not such row
+func (sc *SmartContract) getRow(table string, whereFields, whereValues []string) (map[string]string, error) {
+	return sc.storage().Get(sc, table, whereFields, whereValues)
+}
+
+// getKey returns the key of the ecosystem or nil if there is not such key
+func (sc *SmartContract) getKey(ecosystem, id int64) (*model.Key, error) {
+	row, err := sc.getRow(model.KeyTableName(ecosystem), []string{`id`}, []string{converter.Int64ToStr(id)})
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return &model.Key{ID: id, PublicKey: []byte(row[`pub`]), Amount: row[`amount`],
+		Deleted: converter.StrToInt64(row[`deleted`]), Blocked: converter.StrToInt64(row[`blocked`])}, nil
+}
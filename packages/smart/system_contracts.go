@@ -0,0 +1,292 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The system contracts are the contracts of the first ecosystem which have been added after the release.
+// They are created by the transaction of ImportSystemContracts contract so they get the same identifiers
+// on all nodes and they are rolled back with the block. The founder creates ImportSystemContracts by
+// NewContract with ImportSystemContractsSource. The old blocks are replayed with the sources of the list
+// so the sources must not be changed, the new contracts are appended to the list.
+const nImportSystemContracts = "ImportSystemContracts"
+
+// ImportSystemContractsSource is the source of the contract which creates the system contracts
+const ImportSystemContractsSource = `contract ImportSystemContracts {
+    data {
+        Names string
+    }
+    conditions {
+        ContractConditions("MainCondition")
+    }
+    action {
+        $result = CreateSystemContracts(Split($Names, ","))
+    }
+    func rollback() {
+        RollbackSystemContracts(Split($Names, ","))
+    }
+}`
+
+type systemContract struct {
+	Name  string
+	Value string
+}
+
+var systemContracts = []systemContract{
+	{`EditColumnName`, `contract EditColumnName {
+    data {
+        TableName string
+        Name string
+        NewName string
+    }
+    action {
+        RenameColumn($TableName, $Name, $NewName)
+    }
+}`},
+	{`DelColumn`, `contract DelColumn {
+    data {
+        TableName string
+        Name string
+    }
+    action {
+        DropColumn($TableName, $Name)
+    }
+}`},
+	{`EditColumnType`, `contract EditColumnType {
+    data {
+        TableName string
+        Name string
+        Type string
+        Converter string "optional"
+    }
+    action {
+        ChangeColumnType($TableName, $Name, $Type, $Converter)
+    }
+}`},
+	{`EditTableName`, `contract EditTableName {
+    data {
+        Name string
+        NewName string
+    }
+    action {
+        RenameTable($Name, $NewName)
+    }
+}`},
+	{`ApproveMultisig`, `contract ApproveMultisig {
+    data {
+        Id int
+    }
+    action {
+        $result = MultisigApprove($Id)
+    }
+}`},
+	{`NewToken`, `contract NewToken {
+    data {
+        Symbol string
+        Name string
+        Decimals int
+        Supply money
+    }
+    action {
+        $result = TokenCreate($Symbol, $Name, $Decimals, $Supply)
+    }
+}`},
+	{`TransferToken`, `contract TransferToken {
+    data {
+        Symbol string
+        Recipient string
+        Amount money
+    }
+    action {
+        TokenTransfer($Symbol, AddressToId($Recipient), $Amount)
+    }
+}`},
+	{`ApproveToken`, `contract ApproveToken {
+    data {
+        Symbol string
+        Spender string
+        Amount money
+    }
+    action {
+        TokenApprove($Symbol, AddressToId($Spender), $Amount)
+    }
+}`},
+	{`TransferTokenFrom`, `contract TransferTokenFrom {
+    data {
+        Symbol string
+        Owner string
+        Recipient string
+        Amount money
+    }
+    action {
+        TokenTransferFrom($Symbol, AddressToId($Owner), AddressToId($Recipient), $Amount)
+    }
+}`},
+	{`NewAsset`, `contract NewAsset {
+    data {
+        Data string "optional"
+        BinaryId int "optional"
+    }
+    action {
+        var metadata map
+        if Size($Data) > 0 {
+            metadata = JSONDecode($Data)
+        }
+        $result = AssetCreate(metadata, $BinaryId)
+    }
+}`},
+	{`TransferAsset`, `contract TransferAsset {
+    data {
+        Id int
+        Recipient string
+    }
+    action {
+        AssetTransfer($Id, AddressToId($Recipient))
+    }
+}`},
+	{`ApproveAsset`, `contract ApproveAsset {
+    data {
+        Id int
+        Spender string "optional"
+    }
+    action {
+        AssetApprove($Id, AddressToId($Spender))
+    }
+}`},
+	{`LockEscrow`, `contract LockEscrow {
+    data {
+        Symbol string "optional"
+        Recipient string
+        Amount money
+        Condition string "optional"
+        Deadline int
+    }
+    action {
+        $result = EscrowLock($Symbol, AddressToId($Recipient), $Amount, $Condition, $Deadline)
+    }
+}`},
+	{`ReleaseEscrow`, `contract ReleaseEscrow {
+    data {
+        Id int
+    }
+    action {
+        EscrowRelease($Id)
+    }
+}`},
+	{`CancelEscrow`, `contract CancelEscrow {
+    data {
+        Id int
+    }
+    action {
+        EscrowCancel($Id)
+    }
+}`},
+	{`SettleEscrow`, `contract SettleEscrow {
+    data {
+        Id int
+    }
+    action {
+        $result = EscrowSettle($Id)
+    }
+}`},
+	{`SetSponsor`, `contract SetSponsor {
+    data {
+        Budget money
+        Contracts string "optional"
+    }
+    action {
+        var list array
+        if Size($Contracts) > 0 {
+            list = Split($Contracts, ",")
+        }
+        $result = SponsorSet($Budget, list)
+    }
+}`},
+}
+
+func getSystemContract(name string) *systemContract {
+	for i, item := range systemContracts {
+		if item.Name == name {
+			return &systemContracts[i]
+		}
+	}
+	return nil
+}
+
+func (sc *SmartContract) checkSystemContracts(funcName string) error {
+	if !accessContracts(sc, nImportSystemContracts) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error(funcName + " can be only called from @1ImportSystemContracts")
+		return fmt.Errorf(`%s can be only called from ImportSystemContracts`, funcName)
+	}
+	if sc.VDE || sc.TxSmart.EcosystemID != 1 {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "ecosystem": sc.TxSmart.EcosystemID}).Error("system contracts of other ecosystem")
+		return fmt.Errorf(`system contracts can be only created in the first ecosystem`)
+	}
+	return nil
+}
+
+// CreateSystemContracts creates the listed system contracts. The contracts must not exist, they get
+// the key of the transaction as the wallet. It returns the count of the created contracts.
+func CreateSystemContracts(sc *SmartContract, names []interface{}) (int64, error) {
+	if err := sc.checkSystemContracts(`CreateSystemContracts`); err != nil {
+		return 0, err
+	}
+	list := make([]*systemContract, 0, len(names))
+	added := make(map[string]bool)
+	for _, item := range names {
+		name := strings.TrimSpace(fmt.Sprint(item))
+		contract := getSystemContract(name)
+		if contract == nil {
+			log.WithFields(log.Fields{"type": consts.NotFound, "contract_name": name}).Error("unknown system contract")
+			return 0, fmt.Errorf(`unknown system contract %s`, name)
+		}
+		if added[name] || VMGetContract(sc.VM, name, 1) != nil {
+			log.WithFields(log.Fields{"type": consts.DuplicateObject, "contract_name": name}).Error("system contract exists")
+			return 0, fmt.Errorf(`contract %s exists`, name)
+		}
+		added[name] = true
+		list = append(list, contract)
+	}
+	for _, item := range list {
+		if _, err := createContract(sc, item.Name, item.Value, `ContractConditions("MainCondition")`,
+			sc.TxSmart.KeyID, 1, 1); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(list)), nil
+}
+
+// RollbackSystemContracts removes the system contracts of the rolled back transaction from the virtual machine
+func RollbackSystemContracts(sc *SmartContract, names []interface{}) error {
+	if err := sc.checkSystemContracts(`RollbackSystemContracts`); err != nil {
+		return err
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		name := strings.TrimSpace(fmt.Sprint(names[i]))
+		rollbackContract(sc, name)
+		dropContractVersion(sc.VM, script.StateName(1, name), 1)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// The tokens of the ecosystem are kept apart from the amount of the keys table which is used
+// for the fuel payment. The amounts are the integers of the minimal units of the token and every
+// movement of the token is written to the history table of the ecosystem with its token_id.
+const maxTokenDecimals = 18
+
+var tokenSymbol = regexp.MustCompile(`^[A-Z][A-Z0-9]{0,31}$`)
+
+// currentKeyID returns the key on whose behalf the contract acts. It is the key of the transaction
+// or the initiator of the approved multi-signature call.
+func (sc *SmartContract) currentKeyID() int64 {
+	if sc.TxContract != nil && sc.TxContract.Extend != nil {
+		if keyID, ok := (*sc.TxContract.Extend)[`key_id`].(int64); ok {
+			return keyID
+		}
+	}
+	return sc.TxSmart.KeyID
+}
+
+func (sc *SmartContract) getToken(symbol string) (int64, error) {
+	row, err := sc.getRow(model.TokenTableName, []string{`ecosystem`, `symbol`},
+		[]string{converter.Int64ToStr(sc.TxSmart.EcosystemID), symbol})
+	if err != nil {
+		return 0, err
+	}
+	if row == nil {
+		log.WithFields(log.Fields{"type": consts.NotFound, "symbol": symbol}).Error("token has not been found")
+		return 0, fmt.Errorf(`token %s has not been found`, symbol)
+	}
+	return converter.StrToInt64(row[`id`]), nil
+}
+
+// tokenAmount converts the value to the amount of the token. The amount can't be negative.
+func tokenAmount(v interface{}) (amount decimal.Decimal, err error) {
+	switch val := v.(type) {
+	case decimal.Decimal:
+		amount = val
+	case int64:
+		amount = decimal.New(val, 0)
+	case float64:
+		amount = decimal.NewFromFloat(val)
+	case string:
+		if amount, err = decimal.NewFromString(val); err != nil {
+			log.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "value": val}).Error("converting token amount")
+			return amount, fmt.Errorf(`token amount %s is not valid`, val)
+		}
+	default:
+		return amount, fmt.Errorf(`token amount %v is not valid`, v)
+	}
+	if amount.Sign() < 0 || !amount.Equal(amount.Floor()) {
+		return amount, fmt.Errorf(`token amount %s is not valid`, amount)
+	}
+	return amount, nil
+}
+
+func (sc *SmartContract) getTokenAmount(table string, whereFields, whereValues []string) (decimal.Decimal, error) {
+	row, err := sc.getRow(table, whereFields, whereValues)
+	if err != nil || row == nil {
+		return decimal.Zero, err
+	}
+	amount, err := decimal.NewFromString(row[`amount`])
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.ConversionError, "error": err, "value": row[`amount`]}).Error("converting token amount")
+	}
+	return amount, err
+}
+
+func (sc *SmartContract) tokenBalance(tokenID, keyID int64) (decimal.Decimal, error) {
+	return sc.getTokenAmount(model.TokenBalanceTableName, []string{`token_id`, `key_id`},
+		[]string{converter.Int64ToStr(tokenID), converter.Int64ToStr(keyID)})
+}
+
+func (sc *SmartContract) tokenAllowance(tokenID, owner, spender int64) (decimal.Decimal, error) {
+	return sc.getTokenAmount(model.TokenAllowanceTableName, []string{`token_id`, `owner_id`, `spender_id`},
+		[]string{converter.Int64ToStr(tokenID), converter.Int64ToStr(owner), converter.Int64ToStr(spender)})
+}
+
+func (sc *SmartContract) setTokenBalance(tokenID, keyID int64, amount decimal.Decimal) (int64, error) {
+	cost, _, err := sc.selectiveLoggingAndUpd([]string{`token_id`, `key_id`, `amount`},
+		[]interface{}{tokenID, keyID, amount.String()}, model.TokenBalanceTableName, []string{`token_id`, `key_id`},
+		[]string{converter.Int64ToStr(tokenID), converter.Int64ToStr(keyID)}, !sc.VDE && sc.Rollback, false)
+	return cost, err
+}
+
+func (sc *SmartContract) setTokenAllowance(tokenID, owner, spender int64, amount decimal.Decimal) (int64, error) {
+	cost, _, err := sc.selectiveLoggingAndUpd([]string{`token_id`, `owner_id`, `spender_id`, `amount`},
+		[]interface{}{tokenID, owner, spender, amount.String()}, model.TokenAllowanceTableName,
+		[]string{`token_id`, `owner_id`, `spender_id`},
+		[]string{converter.Int64ToStr(tokenID), converter.Int64ToStr(owner), converter.Int64ToStr(spender)},
+		!sc.VDE && sc.Rollback, false)
+	return cost, err
+}
+
+// moveTokens moves the amount of the token from the sender to the recipient and writes the history.
+// The tokens are issued if the sender is zero.
+func (sc *SmartContract) moveTokens(tokenID, sender, recipient int64, amount decimal.Decimal) (int64, error) {
+	var qcost int64
+	if recipient == 0 || recipient == sender {
+		return 0, fmt.Errorf(`recipient %d is not valid`, recipient)
+	}
+	if amount.Sign() <= 0 {
+		return 0, fmt.Errorf(`token amount must be greater than zero`)
+	}
+	if sender != 0 {
+		balance, err := sc.tokenBalance(tokenID, sender)
+		if err != nil {
+			return 0, err
+		}
+		if balance.LessThan(amount) {
+			log.WithFields(log.Fields{"type": consts.NoFunds, "token_id": tokenID, "key_id": sender}).Error("not enough tokens")
+			return 0, fmt.Errorf(`not enough tokens on the balance of %d`, sender)
+		}
+		if qcost, err = sc.setTokenBalance(tokenID, sender, balance.Sub(amount)); err != nil {
+			return 0, err
+		}
+	}
+	balance, err := sc.tokenBalance(tokenID, recipient)
+	if err != nil {
+		return 0, err
+	}
+	cost, err := sc.setTokenBalance(tokenID, recipient, balance.Add(amount))
+	if err != nil {
+		return 0, err
+	}
+	qcost += cost
+	cost, _, err = sc.selectiveLoggingAndUpd([]string{`sender_id`, `recipient_id`, `amount`, `comment`,
+		`block_id`, `txhash`, `token_id`}, []interface{}{sender, recipient, amount.String(), ``, sc.blockID(),
+		sc.TxHash, tokenID}, getDefTableName(sc, `history`), nil, nil, !sc.VDE && sc.Rollback, false)
+	return qcost + cost, err
+}
+
+// TokenCreate creates the token of the ecosystem. The supply of the token is issued to the current key.
+func TokenCreate(sc *SmartContract, symbol, name string, decimals int64, supply interface{}) (qcost int64, ret int64, err error) {
+	if !tokenSymbol.MatchString(symbol) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "symbol": symbol}).Error("invalid token symbol")
+		return 0, 0, fmt.Errorf(`token symbol %s is not valid`, symbol)
+	}
+	if decimals < 0 || decimals > maxTokenDecimals {
+		return 0, 0, fmt.Errorf(`decimals of token must be from 0 to %d`, maxTokenDecimals)
+	}
+	amount, err := tokenAmount(supply)
+	if err != nil {
+		return 0, 0, err
+	}
+	row, err := sc.getRow(model.TokenTableName, []string{`ecosystem`, `symbol`},
+		[]string{converter.Int64ToStr(sc.TxSmart.EcosystemID), symbol})
+	if err != nil {
+		return 0, 0, err
+	}
+	if row != nil {
+		return 0, 0, fmt.Errorf(`token %s already exists`, symbol)
+	}
+	keyID := sc.currentKeyID()
+	qcost, lastID, err := sc.selectiveLoggingAndUpd([]string{`ecosystem`, `symbol`, `name`, `decimals`, `supply`,
+		`key_id`, `block_id`}, []interface{}{sc.TxSmart.EcosystemID, symbol, name, decimals, amount.String(),
+		keyID, sc.blockID()}, model.TokenTableName, nil, nil, !sc.VDE && sc.Rollback, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	ret = converter.StrToInt64(lastID)
+	if amount.Sign() > 0 {
+		cost, err := sc.moveTokens(ret, 0, keyID, amount)
+		if err != nil {
+			return 0, 0, err
+		}
+		qcost += cost
+	}
+	return qcost, ret, nil
+}
+
+// TokenBalance returns the amount of the token of the key
+func TokenBalance(sc *SmartContract, symbol string, keyID int64) (decimal.Decimal, error) {
+	tokenID, err := sc.getToken(symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return sc.tokenBalance(tokenID, keyID)
+}
+
+// TokenTransfer transfers the amount of the token from the current key to the recipient
+func TokenTransfer(sc *SmartContract, symbol string, recipient int64, amount interface{}) (int64, error) {
+	if !accessContracts(sc, `TransferToken`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("TokenTransfer can be only called from @1TransferToken")
+		return 0, fmt.Errorf(`TokenTransfer can be only called from TransferToken`)
+	}
+	tokenID, err := sc.getToken(symbol)
+	if err != nil {
+		return 0, err
+	}
+	value, err := tokenAmount(amount)
+	if err != nil {
+		return 0, err
+	}
+	return sc.moveTokens(tokenID, sc.currentKeyID(), recipient, value)
+}
+
+// TokenApprove allows the spender to transfer the amount of the token of the current key.
+// The zero amount revokes the allowance.
+func TokenApprove(sc *SmartContract, symbol string, spender int64, amount interface{}) (int64, error) {
+	if !accessContracts(sc, `ApproveToken`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("TokenApprove can be only called from @1ApproveToken")
+		return 0, fmt.Errorf(`TokenApprove can be only called from ApproveToken`)
+	}
+	tokenID, err := sc.getToken(symbol)
+	if err != nil {
+		return 0, err
+	}
+	value, err := tokenAmount(amount)
+	if err != nil {
+		return 0, err
+	}
+	owner := sc.currentKeyID()
+	if spender == 0 || spender == owner {
+		return 0, fmt.Errorf(`spender %d is not valid`, spender)
+	}
+	return sc.setTokenAllowance(tokenID, owner, spender, value)
+}
+
+// TokenAllowance returns the amount of the token of the owner which the spender can transfer
+func TokenAllowance(sc *SmartContract, symbol string, owner, spender int64) (decimal.Decimal, error) {
+	tokenID, err := sc.getToken(symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return sc.tokenAllowance(tokenID, owner, spender)
+}
+
+// TokenTransferFrom transfers the amount of the token of the owner to the recipient within
+// the allowance of the current key
+func TokenTransferFrom(sc *SmartContract, symbol string, owner, recipient int64, amount interface{}) (int64, error) {
+	if !accessContracts(sc, `TransferTokenFrom`) {
+		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("TokenTransferFrom can be only called from @1TransferTokenFrom")
+		return 0, fmt.Errorf(`TokenTransferFrom can be only called from TransferTokenFrom`)
+	}
+	tokenID, err := sc.getToken(symbol)
+	if err != nil {
+		return 0, err
+	}
+	value, err := tokenAmount(amount)
+	if err != nil {
+		return 0, err
+	}
+	spender := sc.currentKeyID()
+	allowance, err := sc.tokenAllowance(tokenID, owner, spender)
+	if err != nil {
+		return 0, err
+	}
+	if allowance.LessThan(value) {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "owner": owner, "spender": spender}).Error("allowance is exceeded")
+		return 0, fmt.Errorf(`allowance of %d is exceeded`, spender)
+	}
+	qcost, err := sc.setTokenAllowance(tokenID, owner, spender, allowance.Sub(value))
+	if err != nil {
+		return 0, err
+	}
+	cost, err := sc.moveTokens(tokenID, owner, recipient, value)
+	return qcost + cost, err
+}
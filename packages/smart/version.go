@@ -0,0 +1,176 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package smart
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/GenesisKernel/go-genesis/packages/consts"
+	"github.com/GenesisKernel/go-genesis/packages/converter"
+	"github.com/GenesisKernel/go-genesis/packages/model"
+	"github.com/GenesisKernel/go-genesis/packages/script"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The sources of the contracts are kept in contract_versions table. The version is added when the
+// contract is created or its source is updated and the version column of the contracts table points
+// to the active version. Any version can be called by the pinned name like @1Name#v3, the pinned
+// version is compiled on the first call and it is kept in the cache of the versions. The cache is
+// apart from the objects of the virtual machine so the identifiers of the contracts don't depend
+// on the calls of the versions. The cached version is checked by the hash of the source of the row
+// because the row can be added by the transaction which is rolled back later.
+
+var versionRegexp = regexp.MustCompile(`^@(\d+)(\w[_\w\d]*)#v(\d+)$`)
+
+type contractVersion struct {
+	hash       string // the hash of the source of the version
+	contractID int64
+	obj        *script.ObjInfo
+}
+
+// contractVersions are the compiled pinned versions of the contracts of the virtual machines
+var contractVersions = struct {
+	sync.RWMutex
+	objects map[*script.VM]map[string]*contractVersion
+}{objects: make(map[*script.VM]map[string]*contractVersion)}
+
+// getContractVersion returns the cached version if it has been compiled from the source with the hash
+func getContractVersion(vm *script.VM, name, hash string, contractID int64) *script.ObjInfo {
+	contractVersions.RLock()
+	defer contractVersions.RUnlock()
+	version := contractVersions.objects[vm][name]
+	if version == nil || version.hash != hash || version.contractID != contractID {
+		return nil
+	}
+	return version.obj
+}
+
+func setContractVersion(vm *script.VM, name string, version *contractVersion) {
+	contractVersions.Lock()
+	defer contractVersions.Unlock()
+	if contractVersions.objects[vm] == nil {
+		contractVersions.objects[vm] = make(map[string]*contractVersion)
+	}
+	contractVersions.objects[vm][name] = version
+}
+
+// ContractVersionName returns the pinned name of the version of the contract
+func ContractVersionName(name string, version int64) string {
+	return fmt.Sprintf(`%s#v%d`, name, version)
+}
+
+func sourceHash(value string) string {
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
+
+func (sc *SmartContract) addContractVersion(id int64, name, value string, version, blockID int64, txHash string) error {
+	_, _, err := sc.selectiveLoggingAndUpd([]string{`ecosystem`, `contract_id`, `name`, `version`, `value`,
+		`hash`, `block_id`, `tx_hash`}, []interface{}{sc.TxSmart.EcosystemID, id, name, version, value,
+		sourceHash(value), blockID, txHash}, model.ContractVersionTableName, nil, nil, !sc.VDE && sc.Rollback, false)
+	return err
+}
+
+// newContractVersion adds the version with the new source of the contract. The current source of
+// the unversioned contract is kept as the first version. It returns the added version.
+func (sc *SmartContract) newContractVersion(id int64, value string) (int64, error) {
+	row, err := sc.getRow(getDefTableName(sc, `contracts`), []string{`id`}, []string{converter.Int64ToStr(id)})
+	if err != nil {
+		return 0, err
+	}
+	if row == nil {
+		log.WithFields(log.Fields{"type": consts.NotFound, "contract_id": id}).Error("getting contract")
+		return 0, errContractNotFound
+	}
+	version := converter.StrToInt64(row[`version`])
+	if version == 0 {
+		version = 1
+		if err = sc.addContractVersion(id, row[`name`], row[`value`], version, 0, ``); err != nil {
+			return 0, err
+		}
+	}
+	version++
+	if err = sc.addContractVersion(id, row[`name`], value, version, sc.blockID(), hex.EncodeToString(sc.TxHash)); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// LoadContractVersion returns the compiled pinned version of the contract. The version is compiled
+// on the first call or if the source of the row differs from the cached one. It returns nil if
+// the version has not been found.
+func (sc *SmartContract) LoadContractVersion(name string) (*script.ObjInfo, error) {
+	ret := versionRegexp.FindStringSubmatch(name)
+	if len(ret) != 4 {
+		log.WithFields(log.Fields{"type": consts.ContractError, "contract_name": name}).Error("wrong pinned name of contract")
+		return nil, fmt.Errorf(`wrong version of contract %s`, name)
+	}
+	row, err := sc.getRow(model.ContractVersionTableName, []string{`ecosystem`, `name`, `version`}, ret[1:])
+	if err != nil || row == nil {
+		return nil, err
+	}
+	contractID := converter.StrToInt64(row[`contract_id`])
+	if obj := getContractVersion(sc.VM, name, row[`hash`], contractID); obj != nil {
+		return obj, nil
+	}
+	state := uint32(converter.StrToInt64(ret[1]))
+	owner := script.OwnerInfo{StateID: state, TableID: contractID, Version: converter.StrToInt64(ret[3])}
+	cur := VMGetContract(sc.VM, ret[2], state)
+	if cur != nil {
+		info := cur.Block.Info.(*script.ContractInfo).Owner
+		owner.Active, owner.WalletID, owner.TokenID = info.Active, info.WalletID, info.TokenID
+	}
+	root, err := VMCompileBlock(sc.VM, row[`value`], &owner)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.VMError, "contract_name": name, "error": err}).Error("compiling contract version")
+		return nil, err
+	}
+	obj, ok := root.Objects[script.StateName(state, ret[2])]
+	if len(root.Children) != 1 || !ok {
+		log.WithFields(log.Fields{"type": consts.VMError, "contract_name": name}).Error("contract version doesn't contain the contract")
+		return nil, fmt.Errorf(`wrong version of contract %s`, name)
+	}
+	// the version is not flushed into the virtual machine, it gets the identifier of the active contract
+	block := obj.Value.(*script.Block)
+	block.Parent = &sc.VM.Block
+	if cur != nil {
+		block.Info.(*script.ContractInfo).ID = cur.Block.Info.(*script.ContractInfo).ID
+	}
+	setContractVersion(sc.VM, name, &contractVersion{hash: row[`hash`], contractID: contractID, obj: obj})
+	return obj, nil
+}
+
+// dropContractVersion removes the compiled pinned version of the contract from the cache
+func dropContractVersion(vm *script.VM, name string, version int64) {
+	contractVersions.Lock()
+	defer contractVersions.Unlock()
+	delete(contractVersions.objects[vm], ContractVersionName(name, version))
+}
+
+// saveTxContract keeps the contract and its version which have been executed by the transaction
+func (sc *SmartContract) saveTxContract() error {
+	info := sc.TxContract.Block.Info.(*script.ContractInfo)
+	_, _, err := sc.selectiveLoggingAndUpd([]string{`tx_hash`, `ecosystem`, `contract`, `version`, `block_id`},
+		[]interface{}{hex.EncodeToString(sc.TxHash), sc.TxSmart.EcosystemID, info.Name, info.Owner.Version,
+			sc.blockID()}, model.TxContractTableName, nil, nil, !sc.VDE && sc.Rollback, false)
+	return err
+}
@@ -12,6 +12,10 @@ const (
 	columnTypeText     = "text"
 	columnTypeLongText = "long_text"
 	columnTypeBlob     = "blob"
+	columnTypeBool     = "bool"
+	columnTypeDate     = "date"
+	columnTypeUUID     = "uuid"
+	columnTypeArray    = "array"
 
 	substringLength = 32
 )
@@ -20,6 +24,25 @@ func dbfindExpressionBlob(column string) string {
 	return fmt.Sprintf(`md5(%s) "%[1]s"`, column)
 }
 
+func dbfindExpressionArray(column string) string {
+	return fmt.Sprintf(`array_to_json(%s) "%[1]s"`, column)
+}
+
+// sourceColumnTypes are the types of the columns of the Data source which are passed to the client
+var sourceColumnTypes = map[string]bool{columnTypeText: true, columnTypeBool: true, columnTypeDate: true,
+	columnTypeUUID: true, columnTypeArray: true}
+
+// boolValue returns the value of the boolean column in the same form as the bool values of the contracts
+func boolValue(val string) string {
+	switch val {
+	case `t`:
+		return `true`
+	case `f`:
+		return `false`
+	}
+	return val
+}
+
 func dbfindExpressionLongText(column string) string {
 	return fmt.Sprintf(`json_build_array(
 		substr(%s, 1, %d),
@@ -134,6 +134,7 @@ func init() {
 	}}
 	tails[`data`] = forTails{map[string]tailInfo{
 		`Custom`: {tplFunc{customTag, customTagFull, `custom`, `Column,Body`}, false},
+		`Types`:  {tplFunc{tailTag, defaultTailFull, `types`, `Types`}, false},
 	}}
 	tails[`dbfind`] = forTails{map[string]tailInfo{
 		`Columns`:   {tplFunc{tailTag, defaultTailFull, `columns`, `Columns`}, false},
@@ -147,6 +148,9 @@ func init() {
 		`Custom`:    {tplFunc{customTag, customTagFull, `custom`, `Column,Body`}, false},
 		`Vars`:      {tplFunc{tailTag, defaultTailFull, `vars`, `Prefix`}, false},
 		`Cutoff`:    {tplFunc{tailTag, defaultTailFull, `cutoff`, `Cutoff`}, false},
+		`Join`:      {tplFunc{tailTag, defaultTailFull, `join`, `Join,On`}, false},
+		`GroupBy`:   {tplFunc{tailTag, defaultTailFull, `groupby`, `GroupBy`}, false},
+		`Sum`:       {tplFunc{tailTag, defaultTailFull, `sum`, `Sum`}, false},
 	}}
 	tails[`p`] = forTails{map[string]tailInfo{
 		`Style`: {tplFunc{tailTag, defaultTailFull, `style`, `Style`}, false},
@@ -433,8 +437,15 @@ func dataTag(par parFunc) string {
 	data := make([][]string, 0)
 	cols := strings.Split((*par.Pars)[`Columns`], `,`)
 	types := make([]string, len(cols))
+	var colTypes []string
+	if par.Node.Attr[`types`] != nil {
+		colTypes = strings.Split(par.Node.Attr[`types`].(string), `,`)
+	}
 	for i := 0; i < len(types); i++ {
-		types[i] = `text`
+		types[i] = columnTypeText
+		if i < len(colTypes) && sourceColumnTypes[strings.TrimSpace(colTypes[i])] {
+			types[i] = strings.TrimSpace(colTypes[i])
+		}
 	}
 
 	list, err := csv.NewReader(strings.NewReader((*par.Pars)[`Data`])).ReadAll()
@@ -521,6 +532,9 @@ func dbfindTag(par parFunc) string {
 		return ``
 	}
 	defaultTail(par, `dbfind`)
+	if par.Node.Attr[`join`] != nil || par.Node.Attr[`groupby`] != nil || par.Node.Attr[`sum`] != nil {
+		return dbfindQuery(par)
+	}
 	prefix := ``
 	where := ``
 	order := ``
@@ -614,6 +628,15 @@ func dbfindTag(par parFunc) string {
 				queryColumns[i] = dbfindExpressionLongText(col)
 			}
 			break
+		case "boolean":
+			extendedColumns[col] = columnTypeBool
+		case "date":
+			extendedColumns[col] = columnTypeDate
+		case "uuid":
+			extendedColumns[col] = columnTypeUUID
+		case "ARRAY":
+			extendedColumns[col] = columnTypeArray
+			queryColumns[i] = dbfindExpressionArray(col)
 		}
 	}
 	fields = strings.Join(queryColumns, ", ")
@@ -692,6 +715,9 @@ func dbfindTag(par parFunc) string {
 						return err.Error()
 					}
 					break
+				case columnTypeBool:
+					ival = boolValue(ival)
+					item[icol] = ival
 				}
 			} else {
 				root := node{}
@@ -752,6 +778,65 @@ func dbfindTag(par parFunc) string {
 	return ``
 }
 
+// dbfindQuery is DBFind with the joined table, the grouping or the sums. The values of the columns
+// are returned as the text.
+func dbfindQuery(par parFunc) string {
+	attr := func(name string) string {
+		if par.Node.Attr[name] == nil {
+			return ``
+		}
+		return macro(par.Node.Attr[name].(string), par.Workspace.Vars)
+	}
+	q := &smart.Query{
+		Table:   strings.Trim(converter.EscapeName(macro((*par.Pars)[`Name`], par.Workspace.Vars)), `"`),
+		Columns: attr(`columns`),
+		Join:    attr(`join`),
+		On:      attr(`on`),
+		Where:   attr(`where`),
+		WhereID: converter.StrToInt64(attr(`whereid`)),
+		GroupBy: attr(`groupby`),
+		Sum:     attr(`sum`),
+		Order:   attr(`order`),
+		Offset:  converter.StrToInt64(attr(`offset`)),
+		Limit:   converter.StrToInt64(attr(`limit`)),
+	}
+	if par.Node.Attr[`ecosystem`] != nil {
+		q.Ecosystem = converter.StrToInt64(attr(`ecosystem`))
+	} else {
+		q.Ecosystem = converter.StrToInt64((*par.Workspace.Vars)[`ecosystem_id`])
+	}
+	query, columnNames, err := par.Workspace.SmartContract.PrepareQuery(q)
+	if err != nil {
+		return err.Error()
+	}
+	list, err := model.GetAllTransaction(nil, query, -1)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting query of DBFind from db")
+		return err.Error()
+	}
+	types := make([]string, len(columnNames))
+	for i := range types {
+		types[i] = columnTypeText
+	}
+	data := make([][]string, 0, len(list))
+	for _, item := range list {
+		row := make([]string, len(columnNames))
+		for i, col := range columnNames {
+			if item[col] != `NULL` {
+				row[i] = item[col]
+			}
+		}
+		data = append(data, row)
+	}
+	setAllAttr(par)
+	par.Node.Attr[`columns`] = &columnNames
+	par.Node.Attr[`types`] = &types
+	par.Node.Attr[`data`] = &data
+	newSource(par)
+	par.Owner.Children = append(par.Owner.Children, par.Node)
+	return ``
+}
+
 func compositeTag(par parFunc) string {
 	setAllAttr(par)
 	if len((*par.Pars)[`Name`]) == 0 {
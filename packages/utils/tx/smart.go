@@ -11,10 +11,20 @@ type SmartContract struct {
 	PayOver        string
 	SignedBy       int64
 	Data           []byte
+
+	// Sponsor is the key which pays the fuel instead of the key of the transaction,
+	// SponsorSignature is the signature of the same data by the sponsor
+	Sponsor          int64
+	SponsorSignature []byte
 }
 
 // ForSign is converting SmartContract to string
 func (s SmartContract) ForSign() string {
-	return fmt.Sprintf("%s,%d,%d,%d,%d,%d,%s,%s,%d", s.RequestID, s.Type, s.Time, s.KeyID, s.EcosystemID,
+	ret := fmt.Sprintf("%s,%d,%d,%d,%d,%d,%s,%s,%d", s.RequestID, s.Type, s.Time, s.KeyID, s.EcosystemID,
 		s.TokenEcosystem, s.MaxSum, s.PayOver, s.SignedBy)
+	// the sponsor is added only if it is specified so the data of the other transactions is not changed
+	if s.Sponsor != 0 {
+		ret += fmt.Sprintf(",%d", s.Sponsor)
+	}
+	return ret
 }